@@ -0,0 +1,139 @@
+package ldp
+
+import (
+	"errors"
+	"io"
+	"ldpserver/rdf"
+	"ldpserver/util"
+	"log"
+)
+
+var MembershipResourceNotFoundError = errors.New("The membershipResource must be an existing resource on this server")
+var DuplicateContainerPredicateError = errors.New("The membershipResource, hasMemberRelation, isMemberOfRelation, and insertedContentRelation can only be indicated once")
+var MembershipCycleError = errors.New("The membershipResource creates a cycle between containers")
+
+// ReadRdfSource reads the triples (in the given media type) for the
+// RDF Source at the given path, resolving relative IRIs against its
+// URI. The graph can then be validated and saved (see NewRdfNode.)
+func ReadRdfSource(settings Settings, reader io.Reader, contentType string, path string) (rdf.RdfGraph, error) {
+	node := newNode(settings, path)
+	return rdf.ReadGraph(reader, contentType, node.uri)
+}
+
+// ValidateRdfSource validates the graph for a new RDF Source
+// without saving it.
+func ValidateRdfSource(settings Settings, graph rdf.RdfGraph, path string) error {
+	node := newNode(settings, path)
+	return validateContainerConfig(settings, node.subject, graph)
+}
+
+// Validates the configuration of Direct and Indirect Containers:
+//   - each of the membership predicates can only be used once
+//   - the membershipResource must exist on this server (or be
+//     the container itself)
+//   - following the membershipResource of each container must
+//     not lead back to the container.
+func validateContainerConfig(settings Settings, subject rdf.Term, graph rdf.RdfGraph) error {
+	predicates := []string{rdf.LdpMembershipResource, rdf.LdpHasMemberRelation,
+		rdf.LdpIsMemberOfRelation, rdf.LdpInsertedContentRelationUri}
+	for _, predicate := range predicates {
+		if len(graph.GetObjects(subject, rdf.NewIri(predicate))) > 1 {
+			return DuplicateContainerPredicateError
+		}
+	}
+
+	membershipResource, _, isDirectContainer := graph.GetDirectContainerInfo()
+	if !isDirectContainer || membershipResource == subject {
+		return nil
+	}
+
+	target, err := getMembershipResource(settings, membershipResource)
+	if err != nil {
+		return err
+	}
+
+	visited := map[rdf.Term]bool{subject: true}
+	for target.isDirectContainer || target.isIndirectContainer {
+		visited[target.subject] = true
+		next := target.membershipResource
+		if next == target.subject {
+			// Containers can be their own membershipResource
+			break
+		}
+		if visited[next] {
+			return MembershipCycleError
+		}
+		target, err = getMembershipResource(settings, next)
+		if err != nil {
+			// Not our problem (we already validated ours)
+			break
+		}
+	}
+	return nil
+}
+
+func getMembershipResource(settings Settings, membershipResource rdf.Term) (Node, error) {
+	uri := membershipResource.Value()
+	if uri != settings.rootUri && !util.IsSubUri(settings.rootUri, uri) {
+		return Node{}, MembershipResourceNotFoundError
+	}
+
+	node, err := GetHead(settings, util.PathFromUri(settings.rootUri, uri))
+	if err == NodeNotFoundError || err == NodeDeletedError {
+		return Node{}, MembershipResourceNotFoundError
+	}
+	return node, err
+}
+
+func (node Node) isMembershipChanged(other Node) bool {
+	return node.isDirectContainer != other.isDirectContainer ||
+		node.isIndirectContainer != other.isIndirectContainer ||
+		node.membershipResource != other.membershipResource ||
+		node.hasMemberRelation != other.hasMemberRelation ||
+		node.isMemberOfRelation != other.isMemberOfRelation ||
+		node.insertedContentRelation != other.insertedContentRelation
+}
+
+// Recomputes the membership triples of the existing children after
+// the membership configuration of the container has changed. The
+// old node is the container as it was before the change.
+func (node *Node) updateMembers(old Node) error {
+	if !node.isMembershipChanged(old) {
+		return nil
+	}
+
+	// Remove the old membership of all children before adding the
+	// new one since children can share membership triples.
+	children := []Node{}
+	for _, childPath := range node.ChildrenPaths() {
+		child, err := getNode(node.settings, childPath)
+		if err == NodeNotFoundError || err == NodeDeletedError {
+			log.Printf("Skipping missing child %s of %s", childPath, node.uri)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		err = old.RemoveMember(child)
+		if err != nil {
+			return err
+		}
+		children = append(children, child)
+	}
+
+	for _, child := range children {
+		// Reload the child since removing the old membership
+		// might have changed its graph (isMemberOfRelation)
+		child, err := getNode(node.settings, child.Path())
+		if err != nil {
+			return err
+		}
+
+		err = node.addMember(&child)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
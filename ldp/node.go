@@ -1,93 +1,150 @@
 package ldp
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"github.com/hectorcorrea/rdf"
 	"io"
-	"ldpserver/textstore"
+	"ldpserver/fileio"
+	"ldpserver/rdf"
+	"ldpserver/storage"
 	"ldpserver/util"
 	"log"
+	"net/http"
+	"sort"
 	"strings"
 	"time"
 )
 
 var NodeNotFoundError = errors.New("Node not found")
+var NodeDeletedError = errors.New("Node has been deleted")
+var ContainerNotEmptyError = errors.New("Container is not empty")
 var DuplicateNodeError = errors.New("Node already exists")
 var EtagMissingError = errors.New("Missing Etag")
 var EtagMismatchError = errors.New("Etag mismatch")
 var ServerManagedPropertyError = errors.New("Attempted to update server managed property")
+var NodeModifiedError = errors.New("Node has been modified")
 
-const etagPredicate = "<" + rdf.ServerETagUri + ">"
-const rdfTypePredicate = "<" + rdf.RdfTypeUri + ">"
-const contentTypePredicate = "<" + rdf.ServerContentTypeUri + ">"
+// The maximum number of nodes to search for inbound references
+const maxInboundReferenceSearch = 1000
 
-type PreferTriples struct {
-	Containment      bool
-	Membership       bool
-	MinimalContainer bool
-}
+var etagPredicate = rdf.NewIri(rdf.ServerETagUri)
+var lastModifiedPredicate = rdf.NewIri(rdf.ServerLastModifiedUri)
+var digestPredicate = rdf.NewIri(rdf.ServerDigestUri)
+var rdfTypePredicate = rdf.NewIri(rdf.RdfTypeUri)
+var contentTypePredicate = rdf.NewIri(rdf.ServerContentTypeUri)
+var containsPredicate = rdf.NewIri(rdf.LdpContainsUri)
+var insertedMemberPredicate = rdf.NewIri(rdf.ServerInsertedMemberUri)
 
 type Node struct {
 	isRdf      bool
-	uri        string // http://localhost/node1
-	subject    string // <http://localhost/node1>
+	uri        string   // http://localhost/node1
+	subject    rdf.Term // <http://localhost/node1>
 	headers    map[string][]string
 	graph      rdf.RdfGraph
-	graphExtra rdf.RdfGraph // triples from included resources (see PreferTriples)
-	binary     string       // should be []byte or reader
+	graphExtra rdf.RdfGraph // triples from other resources (see PreferTriples)
 
 	settings Settings
 	rootUri  string // http://localhost/
-	store    textstore.Store
-
-	isBasicContainer   bool
-	isDirectContainer  bool
-	membershipResource string
-	hasMemberRelation  string
-	// TODO isMemberOfRelation string
-}
-
-func (node Node) AddChild(child Node) error {
-	triple := rdf.NewTriple(node.subject, "<"+rdf.LdpContainsUri+">", child.subject)
-	err := node.store.AppendToMetaFile(triple.StringLn())
+	store    storage.Store
+
+	isBasicContainer        bool
+	isDirectContainer       bool
+	isIndirectContainer     bool
+	membershipResource      rdf.Term
+	hasMemberRelation       rdf.Term
+	isMemberOfRelation      rdf.Term // only for Direct Containers
+	insertedContentRelation rdf.Term // only for Indirect Containers
+}
+
+// AddChild adds the containment triple for the child and, for Direct
+// and Indirect Containers, the membership triples. The child is
+// updated if the container has an isMemberOfRelation.
+func (node *Node) AddChild(child *Node) error {
+	node.appendTriple(containsPredicate, child.subject)
+	err := node.saveMeta()
 	if err != nil {
 		return err
 	}
+	return node.addMember(child)
+}
 
+// Adds the membership triples for a child of a Direct
+// or Indirect Container. This is a no-op for other nodes.
+func (node Node) addMember(child *Node) error {
 	if node.isDirectContainer {
 		return node.addDirectContainerChild(child)
+	} else if node.isIndirectContainer {
+		return node.addIndirectContainerChild(child)
 	}
 	return nil
 }
 
+// ChildrenPaths returns the paths of the nodes that this node
+// contains (i.e. the objects of its ldp:contains triples.)
+func (node Node) ChildrenPaths() []string {
+	paths := []string{}
+	for _, triple := range node.graph.Match(rdf.Term{}, containsPredicate, rdf.Term{}) {
+		uri := triple.Object().Value()
+		paths = append(paths, util.PathFromUri(node.rootUri, uri))
+	}
+	return paths
+}
+
+// ContentPref returns the triples of an RDF source (and the triples
+// from other resources fetched by GetNode) according to the given
+// preferences.
 func (node Node) ContentPref(pref PreferTriples) string {
-	if node.isRdf {
-		var triples rdf.RdfGraph
-		if pref.MinimalContainer {
-			// All but ldpContains
-			for _, triple := range node.graph {
-				if !triple.Is("<" + rdf.LdpContainsUri + ">") {
-					triples = append(triples, triple)
-				}
-			}
-		} else {
-			triples = node.graph
+	if !node.isRdf {
+		return node.Content()
+	}
+	return node.GraphPref(pref).String()
+}
+
+// GraphPref returns the graph that ContentPref serializes.
+func (node Node) GraphPref(pref PreferTriples) rdf.RdfGraph {
+	var triples rdf.RdfGraph
+	for _, triple := range node.graph.Triples() {
+		if triple.Is(rdf.LdpContainsUri) && !pref.includeContainment() {
+			continue
 		}
-		triplesStr := triples.String()
-		if node.graphExtra != nil {
-			triplesStr += "\n" + node.graphExtra.String()
+		if isServerManagedTriple(triple) && !pref.includeServerManaged() {
+			continue
 		}
-		return triplesStr
+		triples.AppendTriple(triple)
 	}
-	return node.binary
+	triples.Append(node.graphExtra)
+	return triples
 }
 
+// Content returns the triples of an RDF source or the binary
+// content of a non-RDF source. Use Binary() instead for
+// non-RDF sources that could be large.
 func (node Node) Content() string {
 	if node.isRdf {
 		return node.graph.String()
 	}
-	return node.binary
+	reader, err := node.Binary()
+	if err != nil {
+		log.Printf("Error reading binary for %s. %s", node.uri, err)
+		return ""
+	}
+	defer reader.Close()
+	content, err := fileio.ReaderToString(reader)
+	if err != nil {
+		log.Printf("Error reading binary for %s. %s", node.uri, err)
+	}
+	return content
+}
+
+// Binary returns a reader to the content of a non-RDF source.
+// The caller is responsible for closing it.
+func (node Node) Binary() (io.ReadSeekCloser, error) {
+	if node.isRdf {
+		return nil, errors.New("RDF sources have no binary content")
+	}
+	return node.store.ReadDataFile()
 }
 
 func (node Node) Metadata() string {
@@ -102,7 +159,7 @@ func (node Node) contentType() string {
 	if !found {
 		return "application/binary"
 	}
-	return util.RemoveQuotes(triple.Object())
+	return triple.Object().Value()
 }
 
 func (node Node) DebugString() string {
@@ -111,7 +168,7 @@ func (node Node) DebugString() string {
 	}
 
 	triples := ""
-	for i, triple := range node.graph {
+	for i, triple := range node.graph.Triples() {
 		triples += fmt.Sprintf("%d %s\n", i, triple)
 	}
 	debugString := fmt.Sprintf("RDF: %s\n %s", node.uri, triples)
@@ -119,14 +176,66 @@ func (node Node) DebugString() string {
 }
 
 func (node *Node) Etag() string {
-	etag, etagFound := node.graph.GetObject(node.subject, "<"+rdf.ServerETagUri+">")
+	etag, etagFound := node.graph.GetObject(node.subject, etagPredicate)
 	if !etagFound {
 		panic(fmt.Sprintf("No etag found for node %s", node.uri))
 	}
-	return etag
+	return "\"" + etag.Value() + "\""
+}
+
+// RepresentationEtag is the ETag of the representation returned
+// by GetNode. This is a weak ETag (different from Etag()) when
+// the representation varies with the Prefer header.
+func (node Node) RepresentationEtag() string {
+	if etag, ok := node.headers["Etag"]; ok && len(etag) > 0 {
+		return etag[0]
+	}
+	return node.Etag()
+}
+
+// SerializationEtag returns the ETag of the representation of the
+// node in the given media type (and JSON-LD profile.) The Turtle and
+// other serializations are semantically equivalent but not
+// byte-for-byte identical so only Turtle gets the strong ETag.
+func (node Node) SerializationEtag(contentType, profile string) string {
+	etag := node.RepresentationEtag()
+	if contentType == rdf.TurtleContentType {
+		return etag
+	}
+	return weakEtag(etag, contentType, profile)
+}
+
+// LastModified returns the zero time for nodes saved before
+// we started tracking this value.
+func (node Node) LastModified() time.Time {
+	value, found := node.graph.GetObject(node.subject, lastModifiedPredicate)
+	if !found {
+		return time.Time{}
+	}
+	modified, err := time.Parse(time.RFC3339Nano, value.Value())
+	if err != nil {
+		log.Printf("Invalid last modified date (%s) for %s", value, node.uri)
+		return time.Time{}
+	}
+	return modified
 }
 
-func (node Node) HasTriple(predicate, object string) bool {
+// Returns NodeModifiedError if the node has been modified after the
+// given time (i.e. an If-Unmodified-Since precondition.) A zero time
+// means there is no precondition. HTTP dates only have a resolution
+// of one second.
+func (node Node) CheckUnmodifiedSince(since time.Time) error {
+	modified := node.LastModified()
+	if since.IsZero() || modified.IsZero() {
+		return nil
+	}
+	if modified.Truncate(time.Second).After(since) {
+		return NodeModifiedError
+	}
+	return nil
+}
+
+func (node Node) HasTriple(predicate, object rdf.Term) bool {
 	return node.graph.HasTriple(node.subject, predicate, object)
 }
 
@@ -142,6 +251,10 @@ func (node Node) IsDirectContainer() bool {
 	return node.isDirectContainer
 }
 
+func (node Node) IsIndirectContainer() bool {
+	return node.isIndirectContainer
+}
+
 func (node Node) IsRdf() bool {
 	return node.isRdf
 }
@@ -150,12 +263,24 @@ func (node Node) Uri() string {
 	return node.uri
 }
 
-func (node *Node) Patch(triples string) error {
+// Patch changes the triples of the node. The patch can be a patch
+// document (e.g. SPARQL Update, see rdf.UpdateParserFor) or triples
+// in any of the RDF media types that we parse, which are added to
+// the node.
+func (node *Node) Patch(triples string, contentType string) error {
 	if !node.isRdf {
 		return errors.New("Cannot PATCH non-RDF Source")
 	}
 
-	userGraph, err := rdf.StringToGraph(triples, node.subject)
+	if parser, ok := rdf.UpdateParserFor(contentType); ok {
+		updates, err := parser(triples, node.uri)
+		if err != nil {
+			return err
+		}
+		return node.update(updates)
+	}
+
+	userGraph, err := rdf.ParseGraph(triples, contentType, node.uri)
 	if err != nil {
 		return err
 	}
@@ -164,10 +289,40 @@ func (node *Node) Patch(triples string) error {
 		return ServerManagedPropertyError
 	}
 
-	// This is pretty useless as-is since it does not allow to update
-	// a triple. It always adds triples.
-	node.graph.Append(userGraph)
-	return node.save(node.graph, nil)
+	graph := rdf.NewGraph(node.graph.Triples()...)
+	graph.Append(userGraph)
+	return node.savePatched(graph)
+}
+
+// Applies the updates to the graph of the node. Either all of them
+// are saved or (if any of them deletes or inserts server-managed
+// triples, or its conditions are not met) none of them.
+func (node *Node) update(updates []rdf.Update) error {
+	graph, changes, err := rdf.ApplyUpdates(node.graph, updates)
+	if err != nil {
+		return err
+	}
+	if hasServerManagedProperties(changes, node.subject) {
+		return ServerManagedPropertyError
+	}
+	return node.savePatched(graph)
+}
+
+// Saves the graph that results from a patch. As with ReplaceRdfNode
+// the configuration of Direct and Indirect Containers is validated
+// and the membership triples are updated if it changed.
+func (node *Node) savePatched(graph rdf.RdfGraph) error {
+	err := validateContainerConfig(node.settings, node.subject, graph)
+	if err != nil {
+		return err
+	}
+
+	old := *node
+	err = node.save(graph, nil)
+	if err != nil {
+		return err
+	}
+	return node.updateMembers(old)
 }
 
 func (node Node) Path() string {
@@ -178,77 +333,161 @@ func (node Node) String() string {
 	return node.uri
 }
 
-func (node *Node) appendTriple(predicate, object string) {
-	node.graph.AppendTripleStr(node.subject, predicate, object)
+func (node *Node) appendTriple(predicate, object rdf.Term) {
+	node.graph.AppendTriple(rdf.NewTriple(node.subject, predicate, object))
 }
 
+// Updates the ETag and the last modified date of the node.
+// The ETag is calculated from the triples in the graph so
+// this must be called after all other changes to the graph.
 func (node *Node) setETag() {
-	node.graph.SetObject(node.subject, etagPredicate, calculateEtag())
+	modified := time.Now().UTC().Format(time.RFC3339Nano)
+	node.graph.SetObject(node.subject, lastModifiedPredicate, rdf.NewLiteral(modified))
+	node.graph.SetObject(node.subject, etagPredicate, rdf.NewLiteral(calculateEtag(node.graph)))
 }
 
 func (node *Node) Delete() error {
 	return node.store.Delete()
 }
 
-func (node *Node) RemoveContainsUri(uri string) error {
-	predicate := "<" + rdf.LdpContainsUri + ">"
-	object := uri
-	deleted := node.graph.DeleteTriple(node.subject, predicate, object)
+// RemoveChild undoes what AddChild does: it removes the containment
+// triple and, for Direct Containers, the membership triple from the
+// membershipResource.
+func (node *Node) RemoveChild(child Node) error {
+	err := node.RemoveContainsUri(child.subject)
+	if err != nil {
+		return err
+	}
+	return node.RemoveMember(child)
+}
+
+// RemoveMember removes the membership triples that were added to the
+// membershipResource when the child was added to this container.
+// This is a no-op for containers that are not Direct or Indirect
+// Containers.
+func (node Node) RemoveMember(child Node) error {
+	if node.isDirectContainer {
+		return node.removeDirectContainerChild(child)
+	} else if node.isIndirectContainer {
+		return node.removeIndirectContainerChild(child)
+	}
+	return nil
+}
+
+func (node *Node) RemoveContainsUri(uri rdf.Term) error {
+	deleted := node.graph.DeleteTriple(node.subject, containsPredicate, uri)
 	if !deleted {
 		return errors.New("Failed to deleted the containment triple")
 	}
-	return node.save(node.graph, nil)
+	return node.saveMeta()
 }
 
 func getNode(settings Settings, path string) (Node, error) {
 	return GetNode(settings, path, PreferTriples{})
 }
 
+// GetNode fetches the node and, depending on the preferences, the
+// triples from other resources that must be included in its
+// representation (see ContentPref.)
 func GetNode(settings Settings, path string, pref PreferTriples) (Node, error) {
 	node := newNode(settings, path)
-	err := node.loadNode(true)
+	err := node.loadMeta()
+	if err != nil || !node.isRdf || pref == (PreferTriples{}) {
+		return node, err
+	}
 
-	if pref.Membership && node.IsDirectContainer() {
+	if pref.includeMembership() && (node.IsDirectContainer() || node.IsIndirectContainer()) {
 		// Fetch the triples from the membershipResource
 		log.Printf("Fetching membershipResource's graph: %s", node.membershipResourcePath())
 		memberNode, err := getNode(settings, node.membershipResourcePath())
 		if err != nil {
 			return node, err
 		}
-		node.graphExtra = memberNode.graph
-		// TODO: set node.headers["Preference-Applied"] = "return=representation"
+		node.graphExtra.Append(memberNode.graph)
 	}
 
-	return node, err
+	applied := pref.appliedTo(node)
+	if pref.includeInboundReferences() {
+		inbound, complete, err := node.inboundReferences()
+		if err != nil {
+			return node, err
+		}
+		if complete {
+			node.graphExtra.Append(inbound)
+		} else {
+			log.Printf("Inbound references to %s not included, more than %d nodes to search", node.uri, maxInboundReferenceSearch)
+			applied.InboundReferences = false
+		}
+	}
+
+	// Preferences that were not applied leave the representation
+	// (and therefore its ETag) as it is without them.
+	if applied != (PreferTriples{}) {
+		node.headers["Preference-Applied"] = []string{applied.Applied()}
+		node.headers["Etag"] = []string{weakEtag(node.Etag(), node.graphExtra.String(), applied)}
+	}
+	return node, nil
+}
+
+// Finds the triples in other nodes that reference this node. There
+// is no index of references so we walk the containment tree from
+// the root node. To keep requests from walking a large repository
+// the search gives up (and returns false) after visiting
+// maxInboundReferenceSearch nodes.
+func (node Node) inboundReferences() (rdf.RdfGraph, bool, error) {
+	var inbound rdf.RdfGraph
+	pending := []string{"/"}
+	for visited := 0; len(pending) > 0; visited++ {
+		if visited == maxInboundReferenceSearch {
+			return rdf.RdfGraph{}, false, nil
+		}
+
+		path := pending[0]
+		pending = pending[1:]
+		other, err := getNode(node.settings, path)
+		if err == NodeNotFoundError || err == NodeDeletedError {
+			continue
+		}
+		if err != nil {
+			return rdf.RdfGraph{}, false, err
+		}
+
+		pending = append(pending, other.ChildrenPaths()...)
+		if other.uri == node.uri {
+			continue
+		}
+		for _, triple := range other.graph.Match(rdf.Term{}, rdf.Term{}, node.subject) {
+			inbound.AppendTriple(triple)
+		}
+	}
+	return inbound, true, nil
 }
 
 func GetHead(settings Settings, path string) (Node, error) {
 	node := newNode(settings, path)
-	err := node.loadNode(false)
+	err := node.loadMeta()
 	return node, err
 }
 
-func NewRdfNode(settings Settings, triples string, path string) (Node, error) {
+// NewRdfNode saves the graph (read with ReadRdfSource and validated
+// with ValidateRdfSource) as a new RDF Source.
+func NewRdfNode(settings Settings, graph rdf.RdfGraph, path string) (Node, error) {
 	node := newNode(settings, path)
 	node.isRdf = true
-	graph, err := rdf.StringToGraph(triples, node.subject)
-	if err != nil {
-		return Node{}, err
-	}
 	return node, node.save(graph, nil)
 }
 
 func NewNonRdfNode(settings Settings, reader io.ReadCloser, path, triples string) (Node, error) {
 	node := newNode(settings, path)
 	node.isRdf = false
-	graph, err := rdf.StringToGraph(triples, node.subject)
+	graph, err := rdf.StringToGraph(triples, node.uri)
 	if err != nil {
 		return Node{}, err
 	}
 	return node, node.save(graph, reader)
 }
 
-func ReplaceNonRdfNode(settings Settings, reader io.ReadCloser, path, etag, triples string) (Node, error) {
+func ReplaceNonRdfNode(settings Settings, reader io.ReadCloser, path, etag, triples string, since time.Time) (Node, error) {
 	node, err := GetHead(settings, path)
 	if err != nil {
 		return Node{}, err
@@ -258,6 +497,10 @@ func ReplaceNonRdfNode(settings Settings, reader io.ReadCloser, path, etag, trip
 		return Node{}, errors.New("Cannot replace RDF source with a Non-RDF source")
 	}
 
+	if err = node.CheckUnmodifiedSince(since); err != nil {
+		return Node{}, err
+	}
+
 	if etag == "" {
 		return Node{}, EtagMissingError
 	}
@@ -269,7 +512,7 @@ func ReplaceNonRdfNode(settings Settings, reader io.ReadCloser, path, etag, trip
 
 	var graph rdf.RdfGraph
 	if triples != "" {
-		graph, err = rdf.StringToGraph(triples, node.subject)
+		graph, err = rdf.StringToGraph(triples, node.uri)
 		if err != nil {
 			return Node{}, err
 		}
@@ -277,7 +520,7 @@ func ReplaceNonRdfNode(settings Settings, reader io.ReadCloser, path, etag, trip
 	return node, node.save(graph, reader)
 }
 
-func ReplaceRdfNode(settings Settings, triples string, path string, etag string) (Node, error) {
+func ReplaceRdfNode(settings Settings, graph rdf.RdfGraph, path string, etag string, since time.Time) (Node, error) {
 	node, err := getNode(settings, path)
 	if err != nil {
 		return Node{}, err
@@ -287,6 +530,10 @@ func ReplaceRdfNode(settings Settings, triples string, path string, etag string)
 		return Node{}, errors.New("Cannot replace non-RDF source with an RDF source")
 	}
 
+	if err = node.CheckUnmodifiedSince(since); err != nil {
+		return Node{}, err
+	}
+
 	if etag == "" {
 		return Node{}, EtagMissingError
 	}
@@ -296,32 +543,175 @@ func ReplaceRdfNode(settings Settings, triples string, path string, etag string)
 		return Node{}, EtagMismatchError
 	}
 
-	graph, err := rdf.StringToGraph(triples, node.subject)
+	// The rdf:type triples that the node already has (e.g. in the
+	// representation from a GET) can be sent back since they don't
+	// change its interaction model.
+	sent := rdf.NewGraph(graph.Triples()...)
+	for _, triple := range node.graph.Match(node.subject, rdfTypePredicate, rdf.Term{}) {
+		sent.RemoveTriple(triple)
+	}
+	if hasServerManagedProperties(sent, node.subject) {
+		return Node{}, ServerManagedPropertyError
+	}
+
+	err = validateContainerConfig(settings, node.subject, graph)
 	if err != nil {
 		return Node{}, err
 	}
 
-	if hasServerManagedProperties(graph, node.subject) {
-		return Node{}, ServerManagedPropertyError
+	// Containment triples (and the members recorded for children
+	// of Indirect Containers) are managed by the server and must
+	// be preserved.
+	for _, predicate := range []rdf.Term{containsPredicate, insertedMemberPredicate} {
+		for _, triple := range node.graph.Match(rdf.Term{}, predicate, rdf.Term{}) {
+			graph.AppendTriple(triple)
+		}
 	}
 
-	return node, node.save(graph, nil)
+	old := node
+	err = node.save(graph, nil)
+	if err != nil {
+		return Node{}, err
+	}
+	return node, node.updateMembers(old)
+}
+
+func (node Node) addDirectContainerChild(child *Node) error {
+	if !node.isMemberOfRelation.IsZero() {
+		// The membership triple goes on the child's own graph
+		child.appendTriple(node.isMemberOfRelation, node.membershipResource)
+		err := child.saveMeta()
+		if err != nil {
+			log.Printf("Error adding %s to %s. %s", node.isMemberOfRelation, child.uri, err)
+			return err
+		}
+	}
+
+	if node.hasMemberRelation.IsZero() {
+		return nil
+	}
+	return node.addMembers(*child, []rdf.Term{child.subject})
+}
+
+func (node Node) removeDirectContainerChild(child Node) error {
+	if !node.isMemberOfRelation.IsZero() {
+		// Reload the child since it might have been deleted
+		// (in which case there is nothing to update)
+		current, err := getNode(node.settings, child.Path())
+		if err != nil && err != NodeNotFoundError && err != NodeDeletedError {
+			return err
+		}
+		if err == nil && current.graph.DeleteTriple(current.subject, node.isMemberOfRelation, node.membershipResource) {
+			err = current.saveMeta()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if node.hasMemberRelation.IsZero() {
+		return nil
+	}
+	return node.removeMembers(child, []rdf.Term{child.subject})
+}
+
+// For Indirect Containers the members are not the children themselves
+// but the objects of the insertedContentRelation in the child's graph.
+// The members are recorded in the child's graph so that the same
+// membership triples are removed even if the child is updated later.
+func (node Node) addIndirectContainerChild(child *Node) error {
+	members := child.graph.GetObjects(child.subject, node.insertedContentRelation)
+	if len(members) == 0 {
+		log.Printf("No %s found in %s, no membership triples added.", node.insertedContentRelation, child.uri)
+		return nil
+	}
+
+	for _, member := range members {
+		child.appendTriple(insertedMemberPredicate, member)
+	}
+	err := child.saveMeta()
+	if err != nil {
+		log.Printf("Error recording the members of %s. %s", child.uri, err)
+		return err
+	}
+	return node.addMembers(*child, members)
+}
+
+// Removes the membership triples that were added for the child
+// unless another child of the container still produces them.
+func (node Node) removeIndirectContainerChild(child Node) error {
+	members := child.graph.GetObjects(child.subject, insertedMemberPredicate)
+
+	// Reload the child since it might have been deleted
+	// (in which case there is nothing to update)
+	current, err := getNode(node.settings, child.Path())
+	if err != nil && err != NodeNotFoundError && err != NodeDeletedError {
+		return err
+	}
+	if err == nil {
+		deleted := false
+		for _, member := range members {
+			if current.graph.DeleteTriple(current.subject, insertedMemberPredicate, member) {
+				deleted = true
+			}
+		}
+		if deleted {
+			if err = current.saveMeta(); err != nil {
+				return err
+			}
+		}
+	}
+
+	inUse, err := node.insertedMembers(child)
+	if err != nil {
+		return err
+	}
+	unused := []rdf.Term{}
+	for _, member := range members {
+		if !inUse[member] {
+			unused = append(unused, member)
+		}
+	}
+	return node.removeMembers(child, unused)
 }
 
-func (node Node) addDirectContainerChild(child Node) error {
-	// TODO: account for isMemberOfRelation
-	targetUri := util.RemoveAngleBrackets(node.membershipResource)
-	targetPath := util.PathFromUri(node.rootUri, targetUri)
+// Returns the members recorded for the children of an Indirect
+// Container other than the given one.
+func (node Node) insertedMembers(except Node) (map[rdf.Term]bool, error) {
+	members := map[rdf.Term]bool{}
+	for _, childPath := range node.ChildrenPaths() {
+		if childPath == except.Path() {
+			continue
+		}
+		child, err := GetHead(node.settings, childPath)
+		if err == NodeNotFoundError || err == NodeDeletedError {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, member := range child.graph.GetObjects(child.subject, insertedMemberPredicate) {
+			members[member] = true
+		}
+	}
+	return members, nil
+}
 
+// Adds the membership triples for the given members
+// to the membershipResource of the container.
+func (node Node) addMembers(child Node, members []rdf.Term) error {
+	targetPath := node.membershipResourcePath()
 	targetNode, err := getNode(node.settings, targetPath)
 	if err != nil {
 		log.Printf("Could not find target node %s.", targetPath)
 		return err
 	}
 
-	tripleForTarget := rdf.NewTriple("<"+targetNode.uri+">", node.hasMemberRelation, "<"+child.uri+">")
+	for _, member := range members {
+		targetNode.appendTriple(node.hasMemberRelation, member)
+	}
 
-	err = targetNode.store.AppendToMetaFile(tripleForTarget.StringLn())
+	err = targetNode.saveMeta()
 	if err != nil {
 		log.Printf("Error appending child %s to %s. %s", child.uri, targetNode.uri, err)
 		return err
@@ -329,27 +719,42 @@ func (node Node) addDirectContainerChild(child Node) error {
 	return nil
 }
 
-func (node *Node) loadNode(isIncludeBody bool) error {
-	err := node.loadMeta()
+// Removes the membership triples for the given members
+// from the membershipResource of the container.
+func (node Node) removeMembers(child Node, members []rdf.Term) error {
+	targetPath := node.membershipResourcePath()
+	targetNode, err := getNode(node.settings, targetPath)
+	if err == NodeNotFoundError || err == NodeDeletedError {
+		// Nothing to update
+		log.Printf("Membership resource %s not found.", targetPath)
+		return nil
+	}
 	if err != nil {
 		return err
 	}
 
-	if node.isRdf || isIncludeBody == false {
+	deleted := false
+	for _, member := range members {
+		if targetNode.graph.DeleteTriple(targetNode.subject, node.hasMemberRelation, member) {
+			deleted = true
+		}
+	}
+	if !deleted {
 		return nil
 	}
 
-	return node.loadBinary()
-}
-
-func (node *Node) loadBinary() error {
-	var err error
-	node.binary, err = node.store.ReadDataFile()
+	err = targetNode.saveMeta()
+	if err != nil {
+		log.Printf("Error removing child %s from %s. %s", child.uri, targetNode.uri, err)
+	}
 	return err
 }
 
 func (node *Node) loadMeta() error {
 	if !node.store.Exists() {
+		if node.store.IsDeleted() {
+			return NodeDeletedError
+		}
 		return NodeNotFoundError
 	}
 
@@ -358,9 +763,10 @@ func (node *Node) loadMeta() error {
 		return err
 	}
 
-	node.graph, err = rdf.StringToGraph(meta, node.subject)
+	node.graph, err = rdf.StringToGraph(meta, node.uri)
 	if err != nil {
-		return err
+		// Not a rdf.ParseError since it's not the client's fault
+		return fmt.Errorf("Invalid metadata for %s. %s", node.uri, err)
 	}
 
 	if node.graph.IsRdfSource(node.subject) {
@@ -376,47 +782,55 @@ func (node *Node) loadMeta() error {
 func (node *Node) save(graph rdf.RdfGraph, reader io.ReadCloser) error {
 	node.graph = graph
 
-	if node.graph.IsDirectContainer() {
-		// TODO: we might need a different triple for DCs using isMemberOfRelation
-		node.appendTriple("<"+rdf.LdpInsertedContentRelationUri+">", "<"+rdf.LdpMemberSubjectUri+">")
+	insertedContentRelation := rdf.NewIri(rdf.LdpInsertedContentRelationUri)
+	if node.graph.IsDirectContainer() && !node.graph.HasPredicate(node.subject, insertedContentRelation) {
+		// Direct Containers that don't indicate otherwise
+		// use the child itself as the member
+		node.appendTriple(insertedContentRelation, rdf.NewIri(rdf.LdpMemberSubjectUri))
 	}
 
-	node.setETag()
-	node.appendTriple(rdfTypePredicate, "<"+rdf.LdpResourceUri+">")
+	node.appendTriple(rdfTypePredicate, rdf.NewIri(rdf.LdpResourceUri))
 	if node.isRdf {
-		node.appendTriple(rdfTypePredicate, "<"+rdf.LdpRdfSourceUri+">")
-		node.appendTriple(rdfTypePredicate, "<"+rdf.LdpContainerUri+">")
-		node.appendTriple(rdfTypePredicate, "<"+rdf.LdpBasicContainerUri+">")
-		node.setAsRdf()
+		node.appendTriple(rdfTypePredicate, rdf.NewIri(rdf.LdpRdfSourceUri))
+		node.appendTriple(rdfTypePredicate, rdf.NewIri(rdf.LdpContainerUri))
+		node.appendTriple(rdfTypePredicate, rdf.NewIri(rdf.LdpBasicContainerUri))
 	} else {
-		node.appendTriple(rdfTypePredicate, "<"+rdf.LdpNonRdfSourceUri+">")
-		node.setAsNonRdf()
+		node.appendTriple(rdfTypePredicate, rdf.NewIri(rdf.LdpNonRdfSourceUri))
+		// Write the binary first so that its digest
+		// is accounted for in the ETag.
+		digest, err := node.writeBinary(reader)
+		if err != nil {
+			return err
+		}
+		node.graph.SetObject(node.subject, digestPredicate, rdf.NewLiteral(digest))
 	}
-	return node.writeToDisk(reader)
-}
 
-func (node *Node) writeToDisk(reader io.ReadCloser) error {
-	// Write the RDF metadata
-	err := node.store.SaveMetaFile(node.graph.String())
-	if err != nil {
-		return err
-	}
+	return node.saveMeta()
+}
 
+// Writes the graph of the node to the store. Every change to
+// a node's graph (whether requested by the client or done by
+// the server, like adding containment or membership triples)
+// must be saved through this method so that the node gets a
+// new ETag and last modified date.
+func (node *Node) saveMeta() error {
+	node.setETag()
 	if node.isRdf {
-		return nil
+		node.setAsRdf()
+	} else {
+		node.setAsNonRdf()
 	}
+	return node.store.SaveMetaFile(node.graph.String())
+}
 
-	// Write the binary...
-	err = node.store.SaveDataFile(reader)
+// Saves the binary and returns its SHA-256 digest.
+func (node *Node) writeBinary(reader io.Reader) (string, error) {
+	hash := sha256.New()
+	err := node.store.SaveDataFile(io.TeeReader(reader, hash))
 	if err != nil {
-		return err
+		return "", err
 	}
-
-	// ...update the copy in memory (this would get
-	// tricky when we switch "node.binary" to a
-	// reader.
-	node.binary, err = node.store.ReadDataFile()
-	return err
+	return "sha256-" + hex.EncodeToString(hash.Sum(nil)), nil
 }
 
 func (node *Node) setAsRdf() {
@@ -424,14 +838,14 @@ func (node *Node) setAsRdf() {
 	node.headers["Content-Type"] = []string{node.contentType()}
 
 	if node.graph.IsBasicContainer(node.subject) {
-		node.headers["Allow"] = []string{"GET, HEAD, POST, PUT, PATCH"}
+		node.headers["Allow"] = []string{"GET, HEAD, POST, PUT, PATCH" + node.allowDelete()}
 	} else {
-		node.headers["Allow"] = []string{"GET, HEAD, PUT, PATCH"}
+		node.headers["Allow"] = []string{"GET, HEAD, PUT, PATCH" + node.allowDelete()}
 	}
-	node.headers["Accept-Post"] = []string{rdf.TurtleContentType}
-	node.headers["Accept-Patch"] = []string{rdf.TurtleContentType}
-
-	node.headers["Etag"] = []string{node.Etag()}
+	node.headers["Accept-Post"] = []string{strings.Join(rdf.ParserContentTypes(), ", ")}
+	node.headers["Accept-Patch"] = []string{strings.Join(rdf.PatchContentTypes(), ", ")}
+	node.headers["Vary"] = []string{"Accept, Prefer"}
+	node.setValidatorHeaders()
 
 	links := make([]string, 0)
 	links = append(links, rdf.LdpResourceLink)
@@ -439,9 +853,14 @@ func (node *Node) setAsRdf() {
 		node.isBasicContainer = true
 		links = append(links, rdf.LdpContainerLink)
 		links = append(links, rdf.LdpBasicContainerLink)
-		// TODO: validate membershipResource is a sub-URI of rootURI
 		node.membershipResource, node.hasMemberRelation, node.isDirectContainer = node.graph.GetDirectContainerInfo()
-		if node.isDirectContainer {
+		node.isMemberOfRelation, _ = node.graph.GetIsMemberOfRelation()
+		_, _, node.insertedContentRelation, node.isIndirectContainer = node.graph.GetIndirectContainerInfo()
+		if node.isIndirectContainer {
+			// Indirect Containers are not Direct Containers
+			node.isDirectContainer = false
+			links = append(links, rdf.LdpIndirectContainerLink)
+		} else if node.isDirectContainer {
 			links = append(links, rdf.LdpDirectContainerLink)
 		}
 	}
@@ -449,42 +868,106 @@ func (node *Node) setAsRdf() {
 }
 
 func (node *Node) setAsNonRdf() {
-	// TODO Figure out a way to pass the binary as a stream
-	node.binary = ""
 	node.headers = make(map[string][]string)
 
 	describedByLink := fmt.Sprintf("<%s?metadata=yes>; rel=\"describedby\"; anchor=\"%s\"", node.uri, node.uri)
 	node.headers["Link"] = []string{describedByLink, rdf.LdpResourceLink, rdf.LdpNonRdfSourceLink}
 
-	node.headers["Allow"] = []string{"GET, HEAD, PUT"}
+	node.headers["Allow"] = []string{"GET, HEAD, PUT" + node.allowDelete()}
 	node.headers["Content-Type"] = []string{node.contentType()}
+	node.setValidatorHeaders()
+}
+
+func (node *Node) setValidatorHeaders() {
 	node.headers["Etag"] = []string{node.Etag()}
+	if modified := node.LastModified(); !modified.IsZero() {
+		node.headers["Last-Modified"] = []string{modified.Format(http.TimeFormat)}
+	}
+}
+
+func (node *Node) allowDelete() string {
+	if node.uri == node.rootUri {
+		// The root node cannot be deleted
+		return ""
+	}
+	return ", DELETE"
 }
 
 func (node *Node) membershipResourcePath() string {
-	uri := util.RemoveAngleBrackets(node.membershipResource)
+	uri := node.membershipResource.Value()
 	return strings.Replace(uri, node.settings.rootUri, "", 1)
 }
 
-func hasServerManagedProperties(graph rdf.RdfGraph, subject string) bool {
-	// TODO: What other server-managed properties should we handle?
-	properties := []string{rdf.LdpResourceUri, rdf.LdpRdfSourceUri, rdf.LdpNonRdfSourceUri,
-		rdf.LdpContainerUri, rdf.LdpBasicContainerUri, rdf.LdpDirectContainerUri, rdf.LdpContainsUri,
-		rdf.LdpConstrainedBy}
+// Returns true for the triples that the server adds to the graph
+// of every node (e.g. the ETag) that clients can omit with the
+// PreferServerManaged preference.
+func isServerManagedTriple(triple rdf.Triple) bool {
+	return triple.Is(rdf.ServerETagUri) || triple.Is(rdf.ServerLastModifiedUri) ||
+		triple.Is(rdf.ServerDigestUri) || triple.Is(rdf.ServerContentTypeUri) ||
+		triple.Is(rdf.ServerInsertedMemberUri)
+}
 
+func hasServerManagedProperties(graph rdf.RdfGraph, subject rdf.Term) bool {
+	// The ETag, last modified, digest, and content type are only
+	// written by the server (regardless of the subject.)
+	for _, triple := range graph.Triples() {
+		if isServerManagedTriple(triple) {
+			return true
+		}
+	}
+
+	// TODO: What other server-managed properties should we handle?
+	properties := []string{rdf.LdpContainsUri, rdf.LdpConstrainedBy, rdf.ServerInsertedMemberUri}
 	for _, property := range properties {
-		if graph.HasPredicate(subject, "<"+property+">") {
+		if graph.HasPredicate(subject, rdf.NewIri(property)) {
+			return true
+		}
+	}
+
+	// The interaction model (i.e. the LDP types) is set by the server
+	interactionModels := []string{rdf.LdpResourceUri, rdf.LdpRdfSourceUri, rdf.LdpNonRdfSourceUri,
+		rdf.LdpContainerUri, rdf.LdpBasicContainerUri, rdf.LdpDirectContainerUri, rdf.LdpIndirectContainerUri}
+	for _, model := range interactionModels {
+		if graph.HasTriple(subject, rdfTypePredicate, rdf.NewIri(model)) {
 			return true
 		}
 	}
 	return false
 }
 
-func calculateEtag() string {
-	// TODO: Come up with a more precise value.
-	now := time.Now().Format(time.RFC3339)
-	etag := strings.Replace(now, ":", "_", -1)
-	return "\"" + etag + "\""
+// Calculates a strong ETag (without the quotes) from the content of the graph. Triples
+// are sorted so that the ETag does not depend on their order. The
+// ETag and last modified triples are excluded since they change
+// on every save. Non-RDF sources have the digest of their binary
+// on the graph so changes to the binary change the ETag too.
+func calculateEtag(graph rdf.RdfGraph) string {
+	lines := []string{}
+	for _, triple := range graph.Triples() {
+		if triple.Is(rdf.ServerETagUri) || triple.Is(rdf.ServerLastModifiedUri) {
+			continue
+		}
+		lines = append(lines, triple.String())
+	}
+	sort.Strings(lines)
+
+	hash := sha256.New()
+	for _, line := range lines {
+		io.WriteString(hash, line+"\n")
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Calculates the weak ETag for a representation that varies
+// with the Prefer header (e.g. when it includes the triples
+// from the membershipResource or omits containment triples)
+// or with the media type.
+func weakEtag(etag string, variant ...interface{}) string {
+	hash := sha256.New()
+	fmt.Fprint(hash, etag)
+	for _, value := range variant {
+		fmt.Fprintf(hash, " %v", value)
+	}
+	return "W/\"" + hex.EncodeToString(hash.Sum(nil)) + "\""
 }
 
 func newNode(settings Settings, path string) Node {
@@ -493,10 +976,9 @@ func newNode(settings Settings, path string) Node {
 	}
 	var node Node
 	node.settings = settings
-	pathOnDisk := util.PathConcat(settings.dataPath, path)
-	node.store = textstore.NewStore(pathOnDisk)
+	node.store = settings.backend.NewStore(path)
 	node.rootUri = settings.RootUri()
 	node.uri = util.UriConcat(node.rootUri, path)
-	node.subject = "<" + node.uri + ">"
+	node.subject = rdf.NewIri(node.uri)
 	return node
 }
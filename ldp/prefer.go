@@ -0,0 +1,125 @@
+package ldp
+
+import (
+	"ldpserver/rdf"
+	"ldpserver/util"
+	"strings"
+)
+
+// PreferTriples indicates the triples that the client prefers to be
+// included or omitted in the representation of a node
+// (see https://www.w3.org/TR/ldp/#prefer-parameters)
+// The zero value represents the default representation.
+type PreferTriples struct {
+	Minimal bool // return=minimal
+
+	Containment       bool
+	Membership        bool
+	MinimalContainer  bool
+	ServerManaged     bool
+	InboundReferences bool
+
+	OmitContainment       bool
+	OmitMembership        bool
+	OmitMinimalContainer  bool
+	OmitServerManaged     bool
+	OmitInboundReferences bool
+}
+
+// PreferTriplesFromHeader returns the PreferTriples indicated in the
+// values of the Prefer header of a request, for example:
+//
+//	Prefer: return=representation; include="http://www.w3.org/ns/ldp#PreferMembership"
+//
+// Several URIs can be given (separated by spaces) in the include and
+// omit parameters. Unknown URIs are ignored.
+func PreferTriplesFromHeader(headers []string) PreferTriples {
+	var pref PreferTriples
+	ret, ok := util.ParsePrefer(headers)["return"]
+	if !ok {
+		return pref
+	}
+
+	switch strings.ToLower(ret.Value) {
+	case "minimal":
+		pref.Minimal = true
+	case "representation":
+		include, _ := ret.Param("include")
+		for _, uri := range strings.Fields(include) {
+			pref.set(uri, true)
+		}
+		omit, _ := ret.Param("omit")
+		for _, uri := range strings.Fields(omit) {
+			pref.set(uri, false)
+		}
+	}
+	return pref
+}
+
+// Applied returns the value for the Preference-Applied header
+// or an empty string if no preference was applied.
+func (pref PreferTriples) Applied() string {
+	if pref.Minimal {
+		return "return=minimal"
+	} else if pref != (PreferTriples{}) {
+		return "return=representation"
+	}
+	return ""
+}
+
+// appliedTo returns the preferences that are honoured for the node,
+// for example PreferMembership only applies to Direct and Indirect
+// Containers and return=minimal only changes the representation of
+// containers.
+func (pref PreferTriples) appliedTo(node Node) PreferTriples {
+	applied := pref
+	if !node.isBasicContainer {
+		applied.Minimal = false
+		applied.Containment, applied.OmitContainment = false, false
+		applied.MinimalContainer, applied.OmitMinimalContainer = false, false
+	}
+	if !node.isDirectContainer && !node.isIndirectContainer {
+		applied.Membership, applied.OmitMembership = false, false
+	}
+	return applied
+}
+
+func (pref *PreferTriples) set(uri string, include bool) {
+	switch uri {
+	case rdf.LdpPreferContainment:
+		pref.Containment = pref.Containment || include
+		pref.OmitContainment = pref.OmitContainment || !include
+	case rdf.LdpPreferMembership:
+		pref.Membership = pref.Membership || include
+		pref.OmitMembership = pref.OmitMembership || !include
+	case rdf.LdpPreferMinimalContainer:
+		pref.MinimalContainer = pref.MinimalContainer || include
+		pref.OmitMinimalContainer = pref.OmitMinimalContainer || !include
+	case rdf.ServerPreferServerManaged:
+		pref.ServerManaged = pref.ServerManaged || include
+		pref.OmitServerManaged = pref.OmitServerManaged || !include
+	case rdf.ServerPreferInboundReferences:
+		pref.InboundReferences = pref.InboundReferences || include
+		pref.OmitInboundReferences = pref.OmitInboundReferences || !include
+	}
+}
+
+// Omit takes precedence when the same URI is both included and
+// omitted. Containment triples are included by default unless a
+// minimal container (or response) is requested.
+func (pref PreferTriples) includeContainment() bool {
+	minimal := pref.MinimalContainer || pref.Minimal
+	return !pref.OmitContainment && (pref.Containment || !minimal)
+}
+
+func (pref PreferTriples) includeMembership() bool {
+	return pref.Membership && !pref.OmitMembership
+}
+
+func (pref PreferTriples) includeServerManaged() bool {
+	return !pref.OmitServerManaged
+}
+
+func (pref PreferTriples) includeInboundReferences() bool {
+	return pref.InboundReferences && !pref.OmitInboundReferences
+}
@@ -1,21 +1,54 @@
 package ldp
 
-import "ldpserver/util"
+import (
+	"ldpserver/memstore"
+	"ldpserver/storage"
+	"ldpserver/textstore"
+	"ldpserver/util"
+)
 
 type Settings struct {
 	dataPath string
 	rootUri  string
 	idFile   string
+	backend  storage.Backend
+	inMemory bool
 }
 
+// SettingsNew returns the settings for a server that stores
+// its nodes on disk under datapath.
 func SettingsNew(rootUri, datapath string) Settings {
+	dataPath := util.PathConcat(datapath, "/")
+	return SettingsWithBackend(rootUri, datapath, textstore.NewBackend(dataPath))
+}
+
+// SettingsWithBackend returns the settings for a server that
+// stores its nodes in the given backend. The datapath is still
+// used for the ID file.
+func SettingsWithBackend(rootUri, datapath string, backend storage.Backend) Settings {
 	var sett Settings
 	sett.rootUri = util.StripSlash(rootUri)
 	sett.dataPath = util.PathConcat(datapath, "/")
 	sett.idFile = util.PathConcat(sett.dataPath, "meta.rdf.id")
+	sett.backend = backend
 	return sett
 }
 
+// SettingsInMemory returns the settings for a server that keeps
+// everything in memory. There is no data path nor ID file in
+// this case.
+func SettingsInMemory(rootUri string) Settings {
+	var sett Settings
+	sett.rootUri = util.StripSlash(rootUri)
+	sett.backend = memstore.NewBackend()
+	sett.inMemory = true
+	return sett
+}
+
+func (settings Settings) Backend() storage.Backend {
+	return settings.backend
+}
+
 func (settings Settings) DataPath() string {
 	return settings.dataPath
 }
@@ -27,3 +60,7 @@ func (settings Settings) RootUri() string {
 func (settings Settings) IdFile() string {
 	return settings.idFile
 }
+
+func (settings Settings) IsInMemory() bool {
+	return settings.inMemory
+}
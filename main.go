@@ -15,7 +15,9 @@ func main() {
 
 	var address = flag.String("address", "localhost:9001", "Address where server will listen for connections")
 	var dataPath = flag.String("data", rootFolder, "Path where data will be saved")
+	var inMemory = flag.Bool("memory", false, "Keep data in memory only (nothing is saved to disk)")
+	var relative = flag.Bool("relative", false, "Use IRIs relative to the request URI in Turtle responses")
 	flag.Parse()
 
-	web.Start(*address, *dataPath)
+	web.Start(*address, *dataPath, *inMemory, *relative)
 }
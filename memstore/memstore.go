@@ -0,0 +1,174 @@
+// Package memstore is a storage backend that keeps every node in
+// memory. Nothing is ever written to disk which makes it handy for
+// tests and throwaway servers. All data is lost when the process ends.
+package memstore
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"ldpserver/storage"
+	"ldpserver/util"
+	"sync"
+)
+
+var NotFoundError = errors.New("Store not found")
+var NoDataError = errors.New("Store has no data")
+
+type entry struct {
+	meta    string
+	data    []byte
+	exists  bool
+	hasData bool
+	deleted bool
+}
+
+// Backend is safe for concurrent use. Copies of a Backend
+// share the same underlying data.
+type Backend struct {
+	mutex *sync.Mutex
+	nodes map[string]*entry
+}
+
+type Store struct {
+	backend Backend
+	path    string
+	err     error
+}
+
+func NewBackend() Backend {
+	return Backend{mutex: &sync.Mutex{}, nodes: make(map[string]*entry)}
+}
+
+func (backend Backend) NewStore(path string) storage.Store {
+	return Store{backend: backend, path: normalizePath(path)}
+}
+
+func (backend Backend) CreateStore(path string) storage.Store {
+	store := Store{backend: backend, path: normalizePath(path)}
+	backend.mutex.Lock()
+	defer backend.mutex.Unlock()
+	e := backend.nodes[store.path]
+	switch {
+	case e != nil && e.exists:
+		store.err = storage.AlreadyExistsError
+	case e != nil && e.deleted:
+		store.err = storage.CreateDeletedError
+	default:
+		backend.nodes[store.path] = &entry{exists: true}
+	}
+	return store
+}
+
+func (store Store) Error() error {
+	return store.err
+}
+
+func (store Store) Exists() bool {
+	store.backend.mutex.Lock()
+	defer store.backend.mutex.Unlock()
+	e := store.backend.nodes[store.path]
+	return e != nil && e.exists
+}
+
+func (store Store) IsDeleted() bool {
+	store.backend.mutex.Lock()
+	defer store.backend.mutex.Unlock()
+	e := store.backend.nodes[store.path]
+	return e != nil && e.deleted
+}
+
+func (store Store) Delete() error {
+	store.backend.mutex.Lock()
+	defer store.backend.mutex.Unlock()
+	e := store.backend.nodes[store.path]
+	if e == nil || !e.exists {
+		return NotFoundError
+	}
+	// Keep the entry around as a tombstone
+	store.backend.nodes[store.path] = &entry{deleted: true}
+	return nil
+}
+
+func (store Store) SaveMetaFile(content string) error {
+	store.backend.mutex.Lock()
+	defer store.backend.mutex.Unlock()
+	e := store.backend.nodes[store.path]
+	if e == nil {
+		e = &entry{}
+		store.backend.nodes[store.path] = e
+	}
+	e.exists = true
+	e.meta = content
+	return nil
+}
+
+func (store Store) AppendToMetaFile(content string) error {
+	store.backend.mutex.Lock()
+	defer store.backend.mutex.Unlock()
+	e := store.backend.nodes[store.path]
+	if e == nil || !e.exists {
+		return NotFoundError
+	}
+	e.meta += content
+	return nil
+}
+
+func (store Store) ReadMetaFile() (string, error) {
+	store.backend.mutex.Lock()
+	defer store.backend.mutex.Unlock()
+	e := store.backend.nodes[store.path]
+	if e == nil || !e.exists {
+		return "", NotFoundError
+	}
+	return e.meta, nil
+}
+
+func (store Store) SaveDataFile(reader io.Reader) error {
+	// Read outside of the lock so that a slow reader
+	// does not block other stores.
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+
+	store.backend.mutex.Lock()
+	defer store.backend.mutex.Unlock()
+	e := store.backend.nodes[store.path]
+	if e == nil || !e.exists {
+		return NotFoundError
+	}
+	e.data = data
+	e.hasData = true
+	return nil
+}
+
+func (store Store) ReadDataFile() (io.ReadSeekCloser, error) {
+	store.backend.mutex.Lock()
+	defer store.backend.mutex.Unlock()
+	e := store.backend.nodes[store.path]
+	if e == nil || !e.exists {
+		return nil, NotFoundError
+	}
+	if !e.hasData {
+		return nil, NoDataError
+	}
+	// SaveDataFile replaces e.data rather than modifying it so
+	// it's safe to hand out a reader to the current slice.
+	return dataReader{bytes.NewReader(e.data)}, nil
+}
+
+type dataReader struct {
+	*bytes.Reader
+}
+
+func (reader dataReader) Close() error {
+	return nil
+}
+
+// Makes sure "node1", "/node1", and "/node1/" refer to the same node
+// (the same way they would refer to the same folder on disk.)
+func normalizePath(path string) string {
+	return util.StripSlash(util.PathConcat("/", path))
+}
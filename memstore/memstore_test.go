@@ -0,0 +1,88 @@
+package memstore
+
+import (
+	"ldpserver/fileio"
+	"ldpserver/storage"
+	"ldpserver/util"
+	"sync"
+	"testing"
+)
+
+func TestMemStore(t *testing.T) {
+	backend := NewBackend()
+	store := backend.NewStore("/test")
+	if store.Exists() {
+		t.Errorf("Found an unexpected memory store")
+	}
+
+	store = backend.CreateStore("/test")
+	if store.Error() != nil || !store.Exists() {
+		t.Errorf("Error creating memory store: %s", store.Error())
+	}
+
+	reader := util.FakeReaderCloser{Text: "hello"}
+	if err := store.SaveDataFile(reader); err != nil {
+		t.Errorf("Error %s saving text to data file", err)
+	}
+
+	reader2, err := backend.NewStore("test/").ReadDataFile()
+	if err != nil {
+		t.Fatalf("Error %s reading data file", err)
+	}
+	text, err := fileio.ReaderToString(reader2)
+	if err != nil || text != "hello" {
+		t.Errorf("Unexpected text %s found when reading store. Error: %s", text, err)
+	}
+
+	store = backend.CreateStore("/test")
+	if store.Error() != storage.AlreadyExistsError {
+		t.Errorf("Failed to detect override on create")
+	}
+}
+
+func TestMemStoreDelete(t *testing.T) {
+	backend := NewBackend()
+	store := backend.CreateStore("/test")
+	store.SaveMetaFile("<a> <b> <c> .\n")
+	if err := store.Delete(); err != nil {
+		t.Errorf("Error deleting memory store: %s", err)
+	}
+
+	if store.Exists() || !store.IsDeleted() {
+		t.Errorf("Store was not marked as deleted")
+	}
+
+	if _, err := store.ReadMetaFile(); err == nil {
+		t.Errorf("Read metadata from a deleted store")
+	}
+
+	store = backend.CreateStore("/test")
+	if store.Error() != storage.CreateDeletedError {
+		t.Errorf("Failed to detect create on a deleted store: %s", store.Error())
+	}
+}
+
+func TestMemStoreConcurrentCreate(t *testing.T) {
+	backend := NewBackend()
+	var wg sync.WaitGroup
+	created := make(chan bool, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			created <- backend.CreateStore("/same").Error() == nil
+		}()
+	}
+	wg.Wait()
+	close(created)
+
+	count := 0
+	for ok := range created {
+		if ok {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Errorf("Expected one store to be created, found %d", count)
+	}
+}
@@ -1,39 +1,37 @@
 package rdf
 
 type SubjectNode struct {
-	value      string
+	value      Term
 	predicates []*PredicateNode
 }
 
 type PredicateNode struct {
-	value   string
-	objects []string
+	value   Term
+	objects []Term
 }
 
-func NewSubjectNode(value string) SubjectNode {
+func NewSubjectNode(value Term) SubjectNode {
 	return SubjectNode{value: value}
 }
 
-func NewPredicateNode(value string) PredicateNode {
+func NewPredicateNode(value Term) PredicateNode {
 	return PredicateNode{value: value}
 }
 
-func (subject *SubjectNode) AddPredicate(value string) *PredicateNode {
+func (subject *SubjectNode) AddPredicate(value Term) *PredicateNode {
 	predicate := PredicateNode{value: value}
 	subject.predicates = append(subject.predicates, &predicate)
 	return &predicate
 }
 
-func (predicate *PredicateNode) AddObject(object string) {
+func (predicate *PredicateNode) AddObject(object Term) {
 	predicate.objects = append(predicate.objects, object)
 }
 
 func (subject *SubjectNode) Render() string {
 	triples := ""
-	for _, predicate := range subject.predicates {
-		for _, object := range predicate.objects {
-			triples += subject.value + " " + predicate.value + " " + object + " .\n"
-		}
+	for _, triple := range subject.RenderTriples() {
+		triples += triple.StringLn()
 	}
 	return triples
 }
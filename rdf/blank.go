@@ -0,0 +1,54 @@
+package rdf
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync/atomic"
+)
+
+var blankScopeCount uint64
+
+// blankNodes issues the blank node labels for a document. Blank node
+// labels are only meaningful within the document that uses them so
+// the labels in the document are replaced with new ones that include
+// a random scope. This prevents collisions with the blank nodes of
+// other documents (e.g. in other nodes or in a previous PATCH.)
+type blankNodes struct {
+	scope  string
+	count  int
+	labels map[string]Term
+	keep   bool // keep the labels in the document as-is
+}
+
+func newBlankNodes() *blankNodes {
+	bytes := make([]byte, 6)
+	scope := ""
+	if _, err := rand.Read(bytes); err == nil {
+		scope = hex.EncodeToString(bytes)
+	} else {
+		scope = fmt.Sprintf("%x", atomic.AddUint64(&blankScopeCount, 1))
+	}
+	return &blankNodes{scope: scope, labels: map[string]Term{}}
+}
+
+// Returns a new anonymous blank node.
+func (blanks *blankNodes) New() Term {
+	blanks.count++
+	return NewBlankNode(fmt.Sprintf("b%s-%d", blanks.scope, blanks.count))
+}
+
+// Returns the blank node to use for a blank node label (_:xyz) in
+// the document. The same label in the document always gets the
+// same new blank node.
+func (blanks *blankNodes) Label(label string) Term {
+	if blanks.keep {
+		return NewBlankNode(label)
+	}
+	node, ok := blanks.labels[label]
+	if !ok {
+		node = blanks.New()
+		blanks.labels[label] = node
+	}
+	return node
+}
@@ -1,190 +1,356 @@
 package rdf
 
-import "log"
+import (
+	"sort"
+	"strings"
+)
 
-type RdfGraph []Triple
+// RdfGraph is a set of triples indexed by subject, predicate, and
+// object so that the triples that match a pattern (see Match) can
+// be found without scanning the whole graph. The triples keep the
+// order in which they were added.
+//
+// Like maps, copies of a graph share the same triples. The zero
+// value is an empty graph ready to use.
+type RdfGraph struct {
+	index *tripleIndex
+}
 
-func (triples RdfGraph) String() string {
-	theString := ""
-	for _, triple := range triples {
-		theString += triple.StringLn()
-	}
-	return theString
+type tripleIndex struct {
+	triples   []Triple // in the order they were added (zero Triples were deleted)
+	positions map[Triple]int
+	deleted   int
+	spo       termIndex
+	pos       termIndex
+	osp       termIndex
 }
 
-func StringToGraph(theString, rootUri string) (RdfGraph, error) {
-	var err error
+// Three levels of terms (e.g. subject, predicate, and object)
+type termIndex map[Term]map[Term]map[Term]bool
+
+// NewGraph returns a graph with the triples. Duplicate
+// triples are only added once.
+func NewGraph(triples ...Triple) RdfGraph {
 	var graph RdfGraph
-	if len(theString) > 0 {
-		parser := NewTurtleParser(theString)
-		err = parser.Parse()
-		if err == nil {
-			for _, triple := range parser.Triples() {
-				triple.ReplaceBlankUri(rootUri)
-				graph = append(graph, triple)
-			}
-		}
+	for _, triple := range triples {
+		graph.AppendTriple(triple)
 	}
-	return graph, err
+	return graph
 }
 
-func (graph RdfGraph) IsRdfSource(subject string) bool {
-	return graph.HasTriple(subject, "a", "<"+LdpRdfSourceUri+">")
+func (graph RdfGraph) String() string {
+	var text strings.Builder
+	for _, triple := range graph.Triples() {
+		text.WriteString(triple.StringLn())
+	}
+	return text.String()
 }
 
-func (graph RdfGraph) IsBasicContainer(subject string) bool {
-	return graph.HasTriple(subject, "a", "<"+LdpBasicContainerUri+">")
+// StringToGraph parses the Turtle in theString (see ParseGraph
+// for other formats.) Unlike ParseGraph, blank node labels are
+// kept as-is since this is used to read the triples that we
+// saved.
+func StringToGraph(theString, base string) (RdfGraph, error) {
+	parser := NewTurtleParserWithBase(theString, base)
+	parser.KeepBlankLabels()
+	err := parser.Parse()
+	if err != nil {
+		return RdfGraph{}, err
+	}
+	return NewGraph(parser.Triples()...), nil
 }
 
-func (graph RdfGraph) IsDirectContainer() bool {
-	_, _, isDirectContainer := graph.GetDirectContainerInfo()
-	return isDirectContainer
+// Len returns the number of triples in the graph.
+func (graph RdfGraph) Len() int {
+	if graph.index == nil {
+		return 0
+	}
+	return len(graph.index.positions)
 }
 
-func (graph RdfGraph) GetDirectContainerInfo() (string, string, bool) {
-	// TODO: validate only one instance of each these predicates is found on the graph
-	// (perhas the validation should only be when adding/updating triples)
-	membershipResource := ""
-	hasMemberRelation := ""
-	for _, triple := range graph {
-		switch triple.predicate {
-		case "<" + LdpMembershipResource + ">":
-			membershipResource = triple.object
-		case "<" + LdpHasMemberRelation + ">":
-			hasMemberRelation = triple.object
-		}
-		if membershipResource != "" && hasMemberRelation != "" {
-			return membershipResource, hasMemberRelation, true
+// Triples returns the triples in the order they were added.
+func (graph RdfGraph) Triples() []Triple {
+	triples := []Triple{}
+	if graph.index == nil {
+		return triples
+	}
+	for _, triple := range graph.index.triples {
+		if !triple.subject.IsZero() {
+			triples = append(triples, triple)
 		}
 	}
-	return "", "", false
+	return triples
 }
 
-func (graph RdfGraph) HasPredicate(subject, predicate string) bool {
-	_, found := graph.FindPredicate(subject, predicate)
-	return found
-}
+// Match returns the triples that match the subject, predicate, and
+// object in the order they were added. The zero Term matches any
+// term, e.g. Match(subject, Term{}, Term{}) returns all the triples
+// of the subject. The predicate "a" in Turtle is parsed as rdf:type
+// so Match(subject, NewIri(RdfTypeUri), Term{}) finds those triples.
+func (graph RdfGraph) Match(subject, predicate, object Term) []Triple {
+	index := graph.index
+	if index == nil {
+		return []Triple{}
+	}
+
+	var triples []Triple
+	switch {
+	case !subject.IsZero() && !predicate.IsZero() && !object.IsZero():
+		triple := NewTriple(subject, predicate, object)
+		if _, found := index.positions[triple]; found {
+			triples = append(triples, triple)
+		}
+		return triples
+	case !subject.IsZero() && !object.IsZero():
+		triples = index.osp.match(object, subject, func(o, s, p Term) Triple { return NewTriple(s, p, o) })
+	case !subject.IsZero():
+		triples = index.spo.match(subject, predicate, func(s, p, o Term) Triple { return NewTriple(s, p, o) })
+	case !predicate.IsZero():
+		triples = index.pos.match(predicate, object, func(p, o, s Term) Triple { return NewTriple(s, p, o) })
+	case !object.IsZero():
+		triples = index.osp.match(object, Term{}, func(o, s, p Term) Triple { return NewTriple(s, p, o) })
+	default:
+		return graph.Triples()
+	}
 
-func (graph *RdfGraph) FindPredicate(subject, predicate string) (*Triple, bool) {
-	return graph.findPredicate(subject, predicate, true)
+	sort.Slice(triples, func(i, j int) bool {
+		return index.positions[triples[i]] < index.positions[triples[j]]
+	})
+	return triples
 }
 
-func (graph *RdfGraph) findPredicate(subject, predicate string, recurr bool) (*Triple, bool) {
-	for i, triple := range *graph {
-		if triple.subject == subject && triple.predicate == predicate {
-			// return a reference to the original triple
-			return &(*graph)[i], true
+// Returns the triples for the first term and (unless it is the
+// zero Term) the second term in the index.
+func (index termIndex) match(first, second Term, triple func(a, b, c Term) Triple) []Triple {
+	triples := []Triple{}
+	for b, thirds := range index[first] {
+		if !second.IsZero() && b != second {
+			continue
 		}
-	}
-	if recurr {
-		// "a" is an alias for RdfType
-		// look to see if we can find it by alias
-		switch {
-		case predicate == "a":
-			return graph.findPredicate(subject, "<"+RdfTypeUri+">", false)
-		case predicate == "<"+RdfTypeUri+">":
-			return graph.findPredicate(subject, "a", false)
+		for c := range thirds {
+			triples = append(triples, triple(first, b, c))
 		}
 	}
-	return nil, false
+	return triples
 }
 
-func (graph *RdfGraph) findTriple(subject, predicate, object string, recurr bool) (*Triple, bool) {
-	for i, t := range *graph {
-		if t.subject == subject && t.predicate == predicate && t.object == object {
-			// return a reference to the original triple
-			return &(*graph)[i], true
-		}
+func (index termIndex) add(a, b, c Term) {
+	if index[a] == nil {
+		index[a] = map[Term]map[Term]bool{}
 	}
-	// "a" is an alias for RdfType
-	// look to see if we can find it by alias
-	if recurr {
-		switch {
-		case predicate == "a":
-			return graph.findTriple(subject, "<"+RdfTypeUri+">", object, false)
-		case predicate == "<"+RdfTypeUri+">":
-			return graph.findTriple(subject, "a", object, false)
-		}
+	if index[a][b] == nil {
+		index[a][b] = map[Term]bool{}
 	}
-	return nil, false
+	index[a][b][c] = true
 }
 
-func (graph *RdfGraph) DeleteTriple(subject, predicate, object string) bool {
-	var newGraph RdfGraph
-	deleted := false
-	for _, triple := range *graph {
-		// This does not handle the predicate a vs RdfType like find does. Should it?
-		if triple.subject == subject && triple.predicate == predicate && triple.object == object {
-			// don't add it to the new graph
-			deleted = true
-		} else {
-			newGraph = append(newGraph, triple)
-		}
+func (index termIndex) remove(a, b, c Term) {
+	delete(index[a][b], c)
+	if len(index[a][b]) == 0 {
+		delete(index[a], b)
 	}
-
-	if deleted {
-		*graph = newGraph
+	if len(index[a]) == 0 {
+		delete(index, a)
 	}
-	return deleted
 }
 
-func (graph *RdfGraph) appendTriple(subject, predicate, object string) bool {
-	t, found := graph.findTriple(subject, predicate, object, true)
-	if found {
-		if t.predicate == "a" && predicate != "a" {
-			t.predicate = predicate
-			log.Printf("**> replaced a with %s", predicate)
-		}
+// AppendTriple adds the triple to the graph unless it is
+// already there. Returns true if the triple was added.
+func (graph *RdfGraph) AppendTriple(t Triple) bool {
+	if graph.index == nil {
+		graph.index = &tripleIndex{positions: map[Triple]int{},
+			spo: termIndex{}, pos: termIndex{}, osp: termIndex{}}
+	}
+	index := graph.index
+	if _, found := index.positions[t]; found {
 		// nothing to do
 		return false
 	}
-
-	// Append the new triple
-	newTriple := NewTriple(subject, predicate, object)
-	*graph = append(*graph, newTriple)
+	index.positions[t] = len(index.triples)
+	index.triples = append(index.triples, t)
+	index.addTerms(t)
 	return true
 }
 
 func (graph *RdfGraph) Append(newGraph RdfGraph) {
-	for _, triple := range newGraph {
+	for _, triple := range newGraph.Triples() {
 		graph.AppendTriple(triple)
 	}
 }
 
-func (graph *RdfGraph) AppendTriple(t Triple) bool {
-	return graph.appendTriple(t.subject, t.predicate, t.object)
+// RemoveTriple removes the triple from the graph. Returns
+// true if the triple was in the graph.
+func (graph *RdfGraph) RemoveTriple(t Triple) bool {
+	index := graph.index
+	if index == nil {
+		return false
+	}
+	position, found := index.positions[t]
+	if !found {
+		return false
+	}
+	delete(index.positions, t)
+	index.triples[position] = Triple{}
+	index.removeTerms(t)
+	index.deleted++
+	if index.deleted > 32 && index.deleted > len(index.positions) {
+		index.compact()
+	}
+	return true
+}
+
+func (index *tripleIndex) addTerms(t Triple) {
+	index.spo.add(t.subject, t.predicate, t.object)
+	index.pos.add(t.predicate, t.object, t.subject)
+	index.osp.add(t.object, t.subject, t.predicate)
+}
+
+func (index *tripleIndex) removeTerms(t Triple) {
+	index.spo.remove(t.subject, t.predicate, t.object)
+	index.pos.remove(t.predicate, t.object, t.subject)
+	index.osp.remove(t.object, t.subject, t.predicate)
 }
 
-func (graph *RdfGraph) AppendTripleStr(subject, predicate, object string) bool {
-	return graph.appendTriple(subject, predicate, object)
+// Removes the deleted triples from the list of triples
+func (index *tripleIndex) compact() {
+	triples := make([]Triple, 0, len(index.positions))
+	for _, triple := range index.triples {
+		if !triple.subject.IsZero() {
+			index.positions[triple] = len(triples)
+			triples = append(triples, triple)
+		}
+	}
+	index.triples = triples
+	index.deleted = 0
+}
+
+func (graph RdfGraph) IsRdfSource(subject Term) bool {
+	return graph.HasTriple(subject, NewIri(RdfTypeUri), NewIri(LdpRdfSourceUri))
+}
+
+func (graph RdfGraph) IsBasicContainer(subject Term) bool {
+	return graph.HasTriple(subject, NewIri(RdfTypeUri), NewIri(LdpBasicContainerUri))
+}
+
+func (graph RdfGraph) IsDirectContainer() bool {
+	_, _, isDirectContainer := graph.GetDirectContainerInfo()
+	return isDirectContainer
+}
+
+// A Direct Container has a membershipResource and either a
+// hasMemberRelation or an isMemberOfRelation (or both.) The
+// hasMemberRelation returned is the zero Term for Direct Containers
+// that only have an isMemberOfRelation (see GetIsMemberOfRelation)
+func (graph RdfGraph) GetDirectContainerInfo() (Term, Term, bool) {
+	// Only one instance of each of these predicates is expected
+	// (this is validated when Direct Containers are created or updated)
+	membershipResource := graph.firstObject(LdpMembershipResource)
+	hasMemberRelation := graph.firstObject(LdpHasMemberRelation)
+	isMemberOfRelation := graph.firstObject(LdpIsMemberOfRelation)
+	if !membershipResource.IsZero() && (!hasMemberRelation.IsZero() || !isMemberOfRelation.IsZero()) {
+		return membershipResource, hasMemberRelation, true
+	}
+	return Term{}, Term{}, false
+}
+
+func (graph RdfGraph) GetIsMemberOfRelation() (Term, bool) {
+	isMemberOfRelation := graph.firstObject(LdpIsMemberOfRelation)
+	return isMemberOfRelation, !isMemberOfRelation.IsZero()
+}
+
+// Returns the object of the first triple (of any subject) with the
+// predicate or the zero Term if there is none.
+func (graph RdfGraph) firstObject(predicate string) Term {
+	triples := graph.Match(Term{}, NewIri(predicate), Term{})
+	if len(triples) == 0 {
+		return Term{}
+	}
+	return triples[0].object
+}
+
+func (graph RdfGraph) IsIndirectContainer() bool {
+	_, _, _, isIndirectContainer := graph.GetIndirectContainerInfo()
+	return isIndirectContainer
+}
+
+// An Indirect Container is like a Direct Container (i.e. it has a
+// membershipResource and a hasMemberRelation) but it also has an
+// insertedContentRelation other than ldp:MemberSubject.
+func (graph RdfGraph) GetIndirectContainerInfo() (Term, Term, Term, bool) {
+	membershipResource, hasMemberRelation, isDirectContainer := graph.GetDirectContainerInfo()
+	if !isDirectContainer || hasMemberRelation.IsZero() {
+		return Term{}, Term{}, Term{}, false
+	}
+
+	for _, triple := range graph.Match(Term{}, NewIri(LdpInsertedContentRelationUri), Term{}) {
+		if triple.object != NewIri(LdpMemberSubjectUri) {
+			return membershipResource, hasMemberRelation, triple.object, true
+		}
+	}
+	return Term{}, Term{}, Term{}, false
+}
+
+func (graph RdfGraph) HasPredicate(subject, predicate Term) bool {
+	_, found := graph.FindPredicate(subject, predicate)
+	return found
+}
+
+// FindPredicate returns the first triple for the subject/predicate.
+func (graph RdfGraph) FindPredicate(subject, predicate Term) (Triple, bool) {
+	triples := graph.Match(subject, predicate, Term{})
+	if len(triples) == 0 {
+		return Triple{}, false
+	}
+	return triples[0], true
+}
+
+func (graph *RdfGraph) DeleteTriple(subject, predicate, object Term) bool {
+	return graph.RemoveTriple(NewTriple(subject, predicate, object))
 }
 
-func (graph RdfGraph) HasTriple(subject, predicate, object string) bool {
-	_, found := graph.findTriple(subject, predicate, object, true)
+func (graph RdfGraph) HasTriple(subject, predicate, object Term) bool {
+	if graph.index == nil {
+		return false
+	}
+	_, found := graph.index.positions[NewTriple(subject, predicate, object)]
 	return found
 }
 
-func (graph RdfGraph) GetObject(subject, predicate string) (string, bool) {
+// Returns all the objects for a subject/predicate
+func (graph RdfGraph) GetObjects(subject, predicate Term) []Term {
+	objects := []Term{}
+	for _, triple := range graph.Match(subject, predicate, Term{}) {
+		objects = append(objects, triple.object)
+	}
+	return objects
+}
+
+func (graph RdfGraph) GetObject(subject, predicate Term) (Term, bool) {
 	triple, found := graph.FindPredicate(subject, predicate)
 	if found {
 		return triple.object, true
 	}
-	return "", false
+	return Term{}, false
 }
 
 // Set the object for a subject/predicate
 // This is only useful for subject/predicates that can appear only once
 // on the graph. If a subject/predicate can appear multiple times, this
 // method will find and overwrite the first instance only.
-func (graph *RdfGraph) SetObject(subject, predicate, object string) {
+func (graph *RdfGraph) SetObject(subject, predicate, object Term) {
+	newTriple := NewTriple(subject, predicate, object)
 	triple, found := graph.FindPredicate(subject, predicate)
-	if found {
-		triple.object = object
+	if !found || graph.HasTriple(subject, predicate, object) {
+		graph.AppendTriple(newTriple)
 		return
 	}
 
-	// Add a new triple to the graph with the subject/predicate/object
-	newTriple := NewTriple(subject, predicate, object)
-	newGraph := RdfGraph{newTriple}
-	graph.Append(newGraph)
+	// Replace the triple in the same position
+	index := graph.index
+	position := index.positions[triple]
+	delete(index.positions, triple)
+	index.removeTerms(triple)
+	index.triples[position] = newTriple
+	index.positions[newTriple] = position
+	index.addTerms(newTriple)
 }
@@ -2,12 +2,14 @@ package rdf
 
 import "testing"
 import "fmt"
+import "strconv"
 
 func TestGraphToString(t *testing.T) {
-	triple1 := Triple{subject: "<a>", predicate: "<b>", object: "<c>"}
-	triple2 := Triple{subject: "<x>", predicate: "<y>", object: "<z>"}
+	triple1 := NewTriple(NewIri("a"), NewIri("b"), NewIri("c"))
+	triple2 := NewTriple(NewIri("x"), NewIri("y"), NewIri("z"))
 	var graph RdfGraph
-	graph = append(graph, triple1, triple2)
+	graph.AppendTriple(triple1)
+	graph.AppendTriple(triple2)
 	str := fmt.Sprintf("%s", graph)
 	if str != "<a> <b> <c> .\n<x> <y> <z> .\n" {
 		t.Errorf("Graph to string failed: %s", str)
@@ -20,113 +22,206 @@ func TestStringToGraph(t *testing.T) {
 	triple1 := "<a> <b> <c> .\n"
 	triple2 := "<x> <y> <z> .\n"
 	graph, err = StringToGraph(triple1+triple2, "")
-	if err != nil || len(graph) != 2 {
-		t.Errorf("Unexpected number of triples found: %d %s", len(graph), err)
+	if err != nil || graph.Len() != 2 {
+		t.Errorf("Unexpected number of triples found: %d %s", graph.Len(), err)
 	}
 
 	graph, err = StringToGraph("\n"+triple1+"\n"+triple2+"\n", "")
-	if err != nil || len(graph) != 2 {
-		t.Errorf("Failed to remove empty lines %d %s", len(graph), err)
+	if err != nil || graph.Len() != 2 {
+		t.Errorf("Failed to remove empty lines %d %s", graph.Len(), err)
 	}
 }
 
 func TestAppend(t *testing.T) {
 	var graph2 RdfGraph
-	t1 := Triple{subject: "s", predicate: "p", object: "o"}
-	graph1 := RdfGraph{t1}
-	for _, x := range graph1 {
-		graph2 = append(graph2, x)
-	}
+	t1 := NewTriple(NewIri("s"), NewIri("p"), NewIri("o"))
+	graph1 := NewGraph(t1)
+	graph2.Append(graph1)
+	graph2.Append(graph1)
 
-	if len(graph2) != 1 {
+	if graph2.Len() != 1 {
 		t.Errorf("Graph not appended: [%s]", graph2)
 	}
 }
 
 func TestHasTriple(t *testing.T) {
-	triple := Triple{subject: "s", predicate: "p", object: "o"}
-	graph := RdfGraph{triple}
+	triple := NewTriple(NewIri("s"), NewIri("p"), NewIri("o"))
+	graph := NewGraph(triple)
 
-	if !graph.HasTriple("s", "p", "o") {
+	if !graph.HasTriple(NewIri("s"), NewIri("p"), NewIri("o")) {
 		t.Errorf("HasTriple test failed for graph [%s]", graph)
 	}
 
-	if graph.HasTriple("s", "x", "o") {
+	if graph.HasTriple(NewIri("s"), NewIri("x"), NewIri("o")) {
 		t.Errorf("HasTriple test failed for graph [%s]", graph)
 	}
 }
 
 func TestFindPredicate(t *testing.T) {
-	triple := Triple{subject: "s", predicate: "a", object: "something"}
-	graph := RdfGraph{triple, triple}
+	triple := NewTriple(NewIri("s"), NewIri("p"), NewIri("something"))
+	graph := NewGraph(triple, triple)
 
-	if _, found := graph.FindPredicate("s", "a"); !found {
+	if _, found := graph.FindPredicate(NewIri("s"), NewIri("p")); !found {
 		t.Errorf("FindPredicate test failed for valid triple")
 	}
 
-	if _, found := graph.FindPredicate("s", "b"); found {
+	if _, found := graph.FindPredicate(NewIri("s"), NewIri("b")); found {
 		t.Errorf("FindPredicate test failed for invalid triple")
 	}
 }
 
 func TestFindPredicateAliasA(t *testing.T) {
-	triple := Triple{subject: "s", predicate: "a", object: "something"}
-	graph := RdfGraph{triple, triple}
+	graph, _ := StringToGraph("<s> a <something> .", "")
 
-	if _, found := graph.FindPredicate("s", "<"+RdfTypeUri+">"); !found {
+	if _, found := graph.FindPredicate(NewIri("s"), NewIri(RdfTypeUri)); !found {
 		t.Errorf("FindPredicate test failed when using rdf type in fullname")
 	}
 }
 
 func TestFindPredicateAliasRdfType(t *testing.T) {
-	triple := Triple{subject: "s", predicate: "<" + RdfTypeUri + ">", object: "something"}
-	graph := RdfGraph{triple, triple}
+	graph, _ := StringToGraph("<s> a <something> .\n<s> <"+RdfTypeUri+"> <something> .", "")
 
-	if _, found := graph.FindPredicate("s", "a"); !found {
-		t.Errorf("FindPredicate test failed when using 'a' rather than rdf type fullname")
+	if graph.Len() != 1 {
+		t.Errorf("'a' and the rdf type fullname parsed differently: %s", graph)
 	}
 }
 
 func TestSetObject(t *testing.T) {
-	triple := Triple{subject: "s", predicate: "p", object: "o"}
-	graph := RdfGraph{triple}
+	triple := NewTriple(NewIri("s"), NewIri("p"), NewIri("o"))
+	graph := NewGraph(triple)
 
-	graph.SetObject("s", "p", "o2")
-	if graph.HasTriple("s", "p", "o") {
+	graph.SetObject(NewIri("s"), NewIri("p"), NewIri("o2"))
+	if graph.HasTriple(NewIri("s"), NewIri("p"), NewIri("o")) {
 		t.Errorf("SetObject found the original triple (after it was replaced)")
 	}
 
-	if !graph.HasTriple("s", "p", "o2") {
+	if !graph.HasTriple(NewIri("s"), NewIri("p"), NewIri("o2")) {
 		t.Errorf("SetObject did not find triple with new value")
 	}
 
-	graph.SetObject("s", "p2", "o3")
-	if !graph.HasTriple("s", "p2", "o3") {
+	graph.SetObject(NewIri("s"), NewIri("p2"), NewIri("o3"))
+	if !graph.HasTriple(NewIri("s"), NewIri("p2"), NewIri("o3")) {
 		t.Errorf("SetObject did not find new triple")
 	}
 
-	if !graph.HasTriple("s", "p", "o2") {
+	if !graph.HasTriple(NewIri("s"), NewIri("p"), NewIri("o2")) {
 		t.Errorf("SetObject did not triple with new value (after adding new triple)")
 	}
 }
 
 func TestDeleteTriple(t *testing.T) {
-	t1 := Triple{subject: "s1", predicate: "p1", object: "o1"}
-	t2 := Triple{subject: "s2", predicate: "p2", object: "o2"}
-	t3 := Triple{subject: "s3", predicate: "p3", object: "o3"}
-	graph := RdfGraph{t1, t2, t3}
+	t1 := NewTriple(NewIri("s1"), NewIri("p1"), NewIri("o1"))
+	t2 := NewTriple(NewIri("s2"), NewIri("p2"), NewIri("o2"))
+	t3 := NewTriple(NewIri("s3"), NewIri("p3"), NewIri("o3"))
+	graph := NewGraph(t1, t2, t3)
 
-	deleted := graph.DeleteTriple("s2", "p2", "o2")
+	deleted := graph.DeleteTriple(NewIri("s2"), NewIri("p2"), NewIri("o2"))
 	if !deleted {
 		t.Errorf("Did not delete triple from graph")
 	}
 
-	if graph.HasTriple("s2", "p2", "o2") {
+	if graph.HasTriple(NewIri("s2"), NewIri("p2"), NewIri("o2")) {
 		t.Errorf("Deleted triple found in graph")
 	}
 
-	deleted = graph.DeleteTriple("s2", "p2", "o2")
+	deleted = graph.DeleteTriple(NewIri("s2"), NewIri("p2"), NewIri("o2"))
 	if deleted {
 		t.Errorf("Delete triple deleted a non-existing triple")
 	}
 }
+
+func TestIndirectContainer(t *testing.T) {
+	dc := "<s> <" + LdpMembershipResource + "> <m> .\n" +
+		"<s> <" + LdpHasMemberRelation + "> <rel> .\n"
+	graph, _ := StringToGraph(dc, "")
+	if !graph.IsDirectContainer() || graph.IsIndirectContainer() {
+		t.Errorf("Direct container not detected")
+	}
+
+	dcSubject := dc + "<s> <" + LdpInsertedContentRelationUri + "> <" + LdpMemberSubjectUri + "> .\n"
+	graph, _ = StringToGraph(dcSubject, "")
+	if graph.IsIndirectContainer() {
+		t.Errorf("Direct container with ldp:MemberSubject detected as indirect container")
+	}
+
+	ic := dc + "<s> <" + LdpInsertedContentRelationUri + "> <topic> .\n"
+	graph, _ = StringToGraph(ic, "")
+	_, _, inserted, isIndirect := graph.GetIndirectContainerInfo()
+	if !isIndirect || inserted != NewIri("topic") {
+		t.Errorf("Indirect container not detected: %v %s", isIndirect, inserted)
+	}
+}
+
+func TestIsMemberOfRelation(t *testing.T) {
+	dc := "<s> <" + LdpMembershipResource + "> <m> .\n" +
+		"<s> <" + LdpIsMemberOfRelation + "> <rel> .\n"
+	graph, _ := StringToGraph(dc, "")
+	_, hasMemberRelation, isDirect := graph.GetDirectContainerInfo()
+	if !isDirect || !hasMemberRelation.IsZero() {
+		t.Errorf("Direct container with isMemberOfRelation not detected")
+	}
+
+	if rel, found := graph.GetIsMemberOfRelation(); !found || rel != NewIri("rel") {
+		t.Errorf("isMemberOfRelation not found: %s", rel)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	s1, s2, p1, p2, o1, o2 := NewIri("s1"), NewIri("s2"), NewIri("p1"), NewIri("p2"), NewIri("o1"), NewLiteral("o2")
+	graph := NewGraph(NewTriple(s1, p1, o1), NewTriple(s1, p2, o2), NewTriple(s2, p1, o1),
+		NewTriple(s2, p2, o1), NewTriple(s1, p1, o1))
+	if graph.Len() != 4 {
+		t.Fatalf("Duplicate triple added to the graph: %s", graph)
+	}
+
+	all := Term{}
+	tests := []struct {
+		s, p, o  Term
+		expected int
+	}{
+		{all, all, all, 4},
+		{s1, all, all, 2},
+		{s1, p1, all, 1},
+		{s1, all, o1, 1},
+		{all, p1, all, 2},
+		{all, p1, o1, 2},
+		{all, all, o1, 3},
+		{s2, p2, o1, 1},
+		{s2, p2, o2, 0},
+		{NewIri("s3"), all, all, 0},
+	}
+	for _, test := range tests {
+		if matches := graph.Match(test.s, test.p, test.o); len(matches) != test.expected {
+			t.Errorf("Unexpected matches for %s %s %s: %v", test.s, test.p, test.o, matches)
+		}
+	}
+
+	// Matches are in the order the triples were added
+	matches := graph.Match(all, all, o1)
+	if matches[0].Subject() != s1 || matches[1].Predicate() != p1 || matches[2].Predicate() != p2 {
+		t.Errorf("Matches not in order: %v", matches)
+	}
+}
+
+func TestRemoveTriple(t *testing.T) {
+	var graph RdfGraph
+	p := NewIri("p")
+	for i := 0; i < 100; i++ {
+		graph.AppendTriple(NewTriple(NewIri("s"), p, NewLiteral(strconv.Itoa(i))))
+	}
+	for i := 0; i < 100; i += 2 {
+		if !graph.RemoveTriple(NewTriple(NewIri("s"), p, NewLiteral(strconv.Itoa(i)))) {
+			t.Fatalf("Triple %d not removed", i)
+		}
+	}
+
+	objects := graph.GetObjects(NewIri("s"), p)
+	if graph.Len() != 50 || len(objects) != 50 || objects[0] != NewLiteral("1") || objects[49] != NewLiteral("99") {
+		t.Errorf("Unexpected triples after removing: %v", objects)
+	}
+
+	graph.SetObject(NewIri("s"), p, NewLiteral("first"))
+	if graph.Triples()[0].Object() != NewLiteral("first") || graph.Len() != 50 {
+		t.Errorf("SetObject did not replace the first triple in place: %s", graph.Triples()[0])
+	}
+}
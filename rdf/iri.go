@@ -0,0 +1,201 @@
+package rdf
+
+import (
+	"strings"
+)
+
+// The components of an IRI reference (RFC 3986 section 3.)
+type iriParts struct {
+	scheme       string
+	authority    string
+	path         string
+	query        string
+	fragment     string
+	hasAuthority bool
+	hasQuery     bool
+	hasFragment  bool
+}
+
+// ResolveIri resolves an IRI reference against a base IRI as
+// described in RFC 3986 section 5.2. Absolute IRIs are returned
+// as-is, as are all IRIs when there is no base.
+func ResolveIri(base, iri string) string {
+	if base == "" || iriScheme(iri) != "" {
+		return iri
+	}
+
+	b := splitIri(base)
+	r := splitIri(iri)
+	target := iriParts{scheme: b.scheme, fragment: r.fragment, hasFragment: r.hasFragment}
+	switch {
+	case r.hasAuthority:
+		target.authority, target.hasAuthority = r.authority, true
+		target.path = removeDotSegments(r.path)
+		target.query, target.hasQuery = r.query, r.hasQuery
+	case r.path == "":
+		target.authority, target.hasAuthority = b.authority, b.hasAuthority
+		target.path = b.path
+		target.query, target.hasQuery = b.query, b.hasQuery
+		if r.hasQuery {
+			target.query, target.hasQuery = r.query, true
+		}
+	default:
+		target.authority, target.hasAuthority = b.authority, b.hasAuthority
+		if strings.HasPrefix(r.path, "/") {
+			target.path = removeDotSegments(r.path)
+		} else {
+			target.path = removeDotSegments(mergePaths(b, r.path))
+		}
+		target.query, target.hasQuery = r.query, r.hasQuery
+	}
+	return target.String()
+}
+
+func (parts iriParts) String() string {
+	iri := ""
+	if parts.scheme != "" {
+		iri += parts.scheme + ":"
+	}
+	if parts.hasAuthority {
+		iri += "//" + parts.authority
+	}
+	iri += parts.path
+	if parts.hasQuery {
+		iri += "?" + parts.query
+	}
+	if parts.hasFragment {
+		iri += "#" + parts.fragment
+	}
+	return iri
+}
+
+func splitIri(iri string) iriParts {
+	parts := iriParts{}
+	if index := strings.Index(iri, "#"); index != -1 {
+		parts.fragment, parts.hasFragment = iri[index+1:], true
+		iri = iri[:index]
+	}
+	if index := strings.Index(iri, "?"); index != -1 {
+		parts.query, parts.hasQuery = iri[index+1:], true
+		iri = iri[:index]
+	}
+	if scheme := iriScheme(iri); scheme != "" {
+		parts.scheme = scheme
+		iri = iri[len(scheme)+1:]
+	}
+	if strings.HasPrefix(iri, "//") {
+		iri = iri[2:]
+		index := strings.Index(iri, "/")
+		if index == -1 {
+			index = len(iri)
+		}
+		parts.authority, parts.hasAuthority = iri[:index], true
+		iri = iri[index:]
+	}
+	parts.path = iri
+	return parts
+}
+
+// Returns the scheme of an absolute IRI or an empty string
+// for relative IRIs.
+func iriScheme(iri string) string {
+	for i, char := range iri {
+		switch {
+		case char == ':' && i > 0:
+			return iri[:i]
+		case (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z'):
+			continue
+		case i > 0 && ((char >= '0' && char <= '9') || char == '+' || char == '-' || char == '.'):
+			continue
+		}
+		return ""
+	}
+	return ""
+}
+
+// RFC 3986 section 5.2.3
+func mergePaths(base iriParts, path string) string {
+	if base.hasAuthority && base.path == "" {
+		return "/" + path
+	}
+	return base.path[:strings.LastIndex(base.path, "/")+1] + path
+}
+
+// RFC 3986 section 5.2.4
+func removeDotSegments(path string) string {
+	output := []string{}
+	pop := func() {
+		if len(output) > 0 {
+			output = output[:len(output)-1]
+		}
+	}
+
+	for path != "" {
+		switch {
+		case strings.HasPrefix(path, "../"):
+			path = path[3:]
+		case strings.HasPrefix(path, "./"):
+			path = path[2:]
+		case strings.HasPrefix(path, "/./"):
+			path = path[2:]
+		case path == "/.":
+			path = "/"
+		case strings.HasPrefix(path, "/../"):
+			path = path[3:]
+			pop()
+		case path == "/..":
+			path = "/"
+			pop()
+		case path == "." || path == "..":
+			path = ""
+		default:
+			index := strings.Index(path[1:], "/")
+			if index == -1 {
+				output = append(output, path)
+				path = ""
+			} else {
+				output = append(output, path[:index+1])
+				path = path[index+1:]
+			}
+		}
+	}
+	return strings.Join(output, "")
+}
+
+// RelativeIri returns the shortest IRI reference that resolves
+// to the IRI against the base (see ResolveIri), e.g. <child> for
+// <http://x/node/child> against <http://x/node/>. Returns the IRI
+// as-is if it cannot be made relative to the base.
+func RelativeIri(base, iri string) string {
+	if base == "" {
+		return iri
+	}
+	b := splitIri(base)
+	r := splitIri(iri)
+	if b.scheme != r.scheme || b.hasAuthority != r.hasAuthority || b.authority != r.authority {
+		return iri
+	}
+
+	// The query and the fragment
+	suffix := iriParts{query: r.query, hasQuery: r.hasQuery, fragment: r.fragment, hasFragment: r.hasFragment}.String()
+	candidates := []string{}
+	if r.path == b.path && r.query == b.query && r.hasQuery == b.hasQuery {
+		candidates = append(candidates, iriParts{fragment: r.fragment, hasFragment: r.hasFragment}.String())
+	}
+	directory := b.path[:strings.LastIndex(b.path, "/")+1]
+	if directory != "" && strings.HasPrefix(r.path, directory) {
+		relative := r.path[len(directory):]
+		if relative == "" || iriScheme(relative) != "" {
+			relative = "./" + relative
+		}
+		candidates = append(candidates, relative+suffix)
+	}
+	candidates = append(candidates, r.path+suffix)
+
+	for _, candidate := range candidates {
+		if ResolveIri(base, candidate) == iri {
+			return candidate
+		}
+	}
+	return iri
+}
@@ -0,0 +1,79 @@
+package rdf
+
+import "testing"
+
+func TestResolveIri(t *testing.T) {
+	// Examples from RFC 3986 section 5.4
+	base := "http://a/b/c/d;p?q"
+	tests := map[string]string{
+		"g:h":           "g:h",
+		"g":             "http://a/b/c/g",
+		"./g":           "http://a/b/c/g",
+		"g/":            "http://a/b/c/g/",
+		"/g":            "http://a/g",
+		"//g":           "http://g",
+		"?y":            "http://a/b/c/d;p?y",
+		"g?y":           "http://a/b/c/g?y",
+		"#s":            "http://a/b/c/d;p?q#s",
+		"g#s":           "http://a/b/c/g#s",
+		";x":            "http://a/b/c/;x",
+		"":              "http://a/b/c/d;p?q",
+		".":             "http://a/b/c/",
+		"./":            "http://a/b/c/",
+		"..":            "http://a/b/",
+		"../g":          "http://a/b/g",
+		"../..":         "http://a/",
+		"../../g":       "http://a/g",
+		"../../../g":    "http://a/g",
+		"/./g":          "http://a/g",
+		"/../g":         "http://a/g",
+		"g.":            "http://a/b/c/g.",
+		"..g":           "http://a/b/c/..g",
+		"./g/.":         "http://a/b/c/g/",
+		"g/./h":         "http://a/b/c/g/h",
+		"g/../h":        "http://a/b/c/h",
+		"g;x=1/../y":    "http://a/b/c/y",
+		"g?y/./x":       "http://a/b/c/g?y/./x",
+		"g#s/../x":      "http://a/b/c/g#s/../x",
+		"http://x/y/./": "http://x/y/./",
+	}
+
+	for iri, expected := range tests {
+		if resolved := ResolveIri(base, iri); resolved != expected {
+			t.Errorf("Error resolving <%s>. Expected <%s>, got <%s>", iri, expected, resolved)
+		}
+	}
+
+	if resolved := ResolveIri("http://a", "b"); resolved != "http://a/b" {
+		t.Errorf("Error resolving against a base without path: %s", resolved)
+	}
+
+	if resolved := ResolveIri("", "b"); resolved != "b" {
+		t.Errorf("Relative IRI changed without a base: %s", resolved)
+	}
+}
+
+func TestRelativeIri(t *testing.T) {
+	base := "http://x/a/b?q"
+	tests := map[string]string{
+		"http://x/a/b?q":      "",
+		"http://x/a/b?q#f":    "#f",
+		"http://x/a/b":        "b",
+		"http://x/a/c/d":      "c/d",
+		"http://x/a/":         "./",
+		"http://x/e":          "/e",
+		"http://x/a/x:y":      "./x:y",
+		"http://y/a/b":        "http://y/a/b",
+		"https://x/a/b":       "https://x/a/b",
+		"urn:isbn:0451450523": "urn:isbn:0451450523",
+	}
+	for iri, expected := range tests {
+		relative := RelativeIri(base, iri)
+		if relative != expected {
+			t.Errorf("Unexpected relative IRI for <%s>. Expected <%s>, got <%s>", iri, expected, relative)
+		}
+		if ResolveIri(base, relative) != iri {
+			t.Errorf("Relative IRI <%s> does not resolve to <%s>", relative, iri)
+		}
+	}
+}
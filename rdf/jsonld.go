@@ -0,0 +1,523 @@
+package rdf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+type jsonLdNode map[string]interface{}
+
+// JsonLd returns the graph serialized as JSON-LD
+// (https://www.w3.org/TR/json-ld/) in either the compacted or
+// the expanded form. The compacted form uses a context with the
+// prefixes (ldp, rdf, et cetera) used in the graph.
+func (graph RdfGraph) JsonLd(compacted bool) (string, error) {
+	var document interface{}
+	if compacted {
+		document = graph.compactedJsonLd()
+	} else {
+		document = graph.expandedJsonLd()
+	}
+	bytes, err := json.MarshalIndent(document, "", "  ")
+	return string(bytes), err
+}
+
+// Follows the "Serialize RDF as JSON-LD" algorithm without
+// native types or rdf:type as a property.
+func (graph RdfGraph) expandedJsonLd() []jsonLdNode {
+	nodes := []jsonLdNode{}
+	index := map[string]jsonLdNode{}
+	for _, triple := range graph.Triples() {
+		id := jsonLdId(triple.subject)
+		node, found := index[id]
+		if !found {
+			node = jsonLdNode{"@id": id}
+			index[id] = node
+			nodes = append(nodes, node)
+		}
+
+		predicate := triple.predicate.Value()
+		if predicate == RdfTypeUri && !triple.object.IsLiteral() {
+			types, _ := node["@type"].([]interface{})
+			node["@type"] = append(types, jsonLdId(triple.object))
+			continue
+		}
+
+		values, _ := node[predicate].([]interface{})
+		node[predicate] = append(values, jsonLdValue(triple.object))
+	}
+	return nodes
+}
+
+func (graph RdfGraph) compactedJsonLd() jsonLdNode {
+	context := map[string]string{}
+	compact := func(iri string) string {
+		for _, known := range knownPrefixes {
+			local := strings.TrimPrefix(iri, known.namespace)
+			if local != iri && local != "" && !strings.ContainsAny(local, "/#") {
+				context[known.prefix] = known.namespace
+				return known.prefix + ":" + local
+			}
+		}
+		return iri
+	}
+
+	nodes := []interface{}{}
+	for _, expanded := range graph.expandedJsonLd() {
+		node := jsonLdNode{}
+		for key, value := range expanded {
+			switch key {
+			case "@id":
+				node[key] = value
+			case "@type":
+				types := []interface{}{}
+				for _, iri := range value.([]interface{}) {
+					types = append(types, compact(iri.(string)))
+				}
+				node[key] = compactArray(types)
+			default:
+				values := []interface{}{}
+				for _, item := range value.([]interface{}) {
+					values = append(values, compactValue(item.(map[string]string), compact))
+				}
+				node[compact(key)] = compactArray(values)
+			}
+		}
+		nodes = append(nodes, node)
+	}
+
+	var document jsonLdNode
+	if len(nodes) == 1 {
+		document = nodes[0].(jsonLdNode)
+	} else {
+		document = jsonLdNode{"@graph": nodes}
+	}
+	if len(context) > 0 {
+		document["@context"] = context
+	}
+	return document
+}
+
+func compactValue(value map[string]string, compact func(string) string) interface{} {
+	if id, ok := value["@id"]; ok {
+		return map[string]string{"@id": compact(id)}
+	}
+	if len(value) == 1 {
+		// plain string
+		return value["@value"]
+	}
+	if datatype, ok := value["@type"]; ok {
+		return map[string]string{"@value": value["@value"], "@type": compact(datatype)}
+	}
+	return value
+}
+
+func compactArray(values []interface{}) interface{} {
+	if len(values) == 1 {
+		return values[0]
+	}
+	return values
+}
+
+func jsonLdId(term Term) string {
+	if term.IsBlankNode() {
+		return term.String()
+	}
+	return term.Value()
+}
+
+func jsonLdValue(term Term) map[string]string {
+	if !term.IsLiteral() {
+		return map[string]string{"@id": jsonLdId(term)}
+	}
+
+	object := map[string]string{"@value": term.Value()}
+	if term.Language() != "" {
+		object["@language"] = term.Language()
+	} else if term.Datatype() != xsdStringUri {
+		object["@type"] = term.Datatype()
+	}
+	return object
+}
+
+// A term definition from a JSON-LD context
+type jsonLdTerm struct {
+	id        string
+	typeId    string  // "@id", "@vocab", or a datatype
+	language  *string // nil if the term does not set the language
+	container string
+}
+
+type jsonLdContext struct {
+	terms    map[string]jsonLdTerm
+	vocab    string
+	language string
+	base     string
+}
+
+type jsonLdParser struct {
+	triples []Triple
+	blanks  *blankNodes
+}
+
+// ParseJsonLd parses a JSON-LD document (https://www.w3.org/TR/json-ld/)
+// Only local contexts are supported (i.e. contexts are not fetched
+// from the web.) As in the JSON-LD algorithms, properties that do
+// not expand to an absolute IRI are ignored. Relative IRIs in @id
+// (e.g. "" for the node itself) are resolved against the base, or
+// the @base of the context.
+func ParseJsonLd(text, base string) ([]Triple, error) {
+	var document interface{}
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err != nil {
+		return nil, jsonParseError(text, err)
+	}
+
+	parser := jsonLdParser{blanks: newBlankNodes()}
+	err := parser.parseTopLevel(document, jsonLdContext{terms: map[string]jsonLdTerm{}, base: base})
+	return parser.triples, err
+}
+
+// Converts JSON syntax errors to a ParseError with their position.
+func jsonParseError(text string, err error) error {
+	offset := 0
+	switch syntaxErr := err.(type) {
+	case *json.SyntaxError:
+		// the offset is after the offending character
+		offset = int(syntaxErr.Offset) - 1
+	default:
+		if err != io.ErrUnexpectedEOF {
+			return err
+		}
+		offset = len(text)
+	}
+	line, column := textPosition(text, offset)
+	return &ParseError{Message: "Invalid JSON: " + err.Error(), Line: line, Column: column}
+}
+
+func (parser *jsonLdParser) parseTopLevel(element interface{}, context jsonLdContext) error {
+	switch value := element.(type) {
+	case []interface{}:
+		for _, item := range value {
+			if err := parser.parseTopLevel(item, context); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]interface{}:
+		context, err := context.update(value["@context"])
+		if err != nil {
+			return err
+		}
+		if graph, ok := value["@graph"]; ok && !isJsonLdNode(value) {
+			return parser.parseTopLevel(graph, context)
+		}
+		_, err = parser.parseNode(value, context)
+		return err
+	}
+	return errors.New("Invalid JSON-LD document. Expected an object or an array")
+}
+
+// Returns true if the object has properties other than keywords
+// (i.e. it is not just a wrapper for a @graph)
+func isJsonLdNode(object map[string]interface{}) bool {
+	for key := range object {
+		if !strings.HasPrefix(key, "@") {
+			return true
+		}
+	}
+	return false
+}
+
+// Adds the triples for a node object and returns its subject.
+func (parser *jsonLdParser) parseNode(object map[string]interface{}, context jsonLdContext) (Term, error) {
+	context, err := context.update(object["@context"])
+	if err != nil {
+		return Term{}, err
+	}
+
+	subject := parser.blanks.New()
+	if id, ok := object["@id"].(string); ok {
+		subject = parser.iriTerm(context.expandIri(id, false))
+	}
+
+	types := object["@type"]
+	if typeId, ok := types.(string); ok {
+		types = []interface{}{typeId}
+	}
+	typeList, _ := types.([]interface{})
+	for _, typeId := range typeList {
+		if iri, ok := typeId.(string); ok {
+			parser.add(subject, NewIri(RdfTypeUri), parser.iriTerm(context.expandIri(iri, true)))
+		}
+	}
+
+	// Sort the keys so that the triples are always in the same order
+	keys := []string{}
+	for key := range object {
+		if !strings.HasPrefix(key, "@") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := object[key]
+		predicate := context.expandIri(key, true)
+		if !strings.Contains(predicate, ":") || strings.HasPrefix(predicate, "_:") {
+			// Not an absolute IRI
+			continue
+		}
+
+		term := context.terms[key]
+		objects, err := parser.parseValues(value, context, term)
+		if err != nil {
+			return Term{}, err
+		}
+		if term.container == "@list" {
+			if _, isList := value.([]interface{}); isList {
+				objects = []Term{parser.list(objects)}
+			}
+		}
+		for _, object := range objects {
+			parser.add(subject, NewIri(predicate), object)
+		}
+	}
+	return subject, nil
+}
+
+// Returns the RDF terms for the value(s) of a property.
+func (parser *jsonLdParser) parseValues(value interface{}, context jsonLdContext, term jsonLdTerm) ([]Term, error) {
+	switch value := value.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		terms := []Term{}
+		for _, item := range value {
+			itemTerms, err := parser.parseValues(item, context, term)
+			if err != nil {
+				return nil, err
+			}
+			terms = append(terms, itemTerms...)
+		}
+		return terms, nil
+	case map[string]interface{}:
+		if _, ok := value["@value"]; ok {
+			return parser.parseValueObject(value, context)
+		}
+		if list, ok := value["@list"]; ok {
+			items, err := parser.parseValues(list, context, term)
+			return []Term{parser.list(items)}, err
+		}
+		if set, ok := value["@set"]; ok {
+			return parser.parseValues(set, context, term)
+		}
+		subject, err := parser.parseNode(value, context)
+		return []Term{subject}, err
+	case string:
+		switch term.typeId {
+		case "@id":
+			return []Term{parser.iriTerm(context.expandIri(value, false))}, nil
+		case "@vocab":
+			return []Term{parser.iriTerm(context.expandIri(value, true))}, nil
+		case "":
+			language := context.language
+			if term.language != nil {
+				language = *term.language
+			}
+			return []Term{jsonLdLiteral(value, language, "")}, nil
+		}
+		return []Term{jsonLdLiteral(value, "", context.expandIri(term.typeId, true))}, nil
+	}
+
+	literal, datatype := jsonLdNativeValue(value)
+	if term.typeId != "" && term.typeId != "@id" && term.typeId != "@vocab" {
+		datatype = context.expandIri(term.typeId, true)
+	}
+	return []Term{jsonLdLiteral(literal, "", datatype)}, nil
+}
+
+func (parser *jsonLdParser) parseValueObject(object map[string]interface{}, context jsonLdContext) ([]Term, error) {
+	if object["@value"] == nil {
+		return nil, nil
+	}
+
+	value, datatype := jsonLdNativeValue(object["@value"])
+	if typeId, ok := object["@type"].(string); ok {
+		datatype = context.expandIri(typeId, true)
+	}
+	language, _ := object["@language"].(string)
+	return []Term{jsonLdLiteral(value, language, datatype)}, nil
+}
+
+// Returns the lexical form and the datatype of a JSON
+// string, number, or boolean.
+func jsonLdNativeValue(value interface{}) (string, string) {
+	switch value := value.(type) {
+	case string:
+		return value, ""
+	case bool:
+		if value {
+			return "true", xsdNamespace + "boolean"
+		}
+		return "false", xsdNamespace + "boolean"
+	case json.Number:
+		if strings.ContainsAny(value.String(), ".eE") {
+			return value.String(), xsdNamespace + "double"
+		}
+		return value.String(), xsdNamespace + "integer"
+	}
+	return fmt.Sprintf("%v", value), ""
+}
+
+func jsonLdLiteral(value, language, datatype string) Term {
+	if language != "" {
+		return NewLangLiteral(value, language)
+	}
+	if datatype != "" {
+		return NewTypedLiteral(value, datatype)
+	}
+	return NewLiteral(value)
+}
+
+// Adds the triples for an RDF collection and returns its head.
+func (parser *jsonLdParser) list(items []Term) Term {
+	head := NewIri(RdfNilUri)
+	for i := len(items) - 1; i >= 0; i-- {
+		node := parser.blanks.New()
+		parser.add(node, NewIri(RdfFirstUri), items[i])
+		parser.add(node, NewIri(RdfRestUri), head)
+		head = node
+	}
+	return head
+}
+
+func (parser *jsonLdParser) add(subject, predicate, object Term) {
+	parser.triples = append(parser.triples, NewTriple(subject, predicate, object))
+}
+
+// Returns the term for an IRI or a blank node identifier. Blank
+// node identifiers are scoped to the document (see blankNodes.)
+func (parser *jsonLdParser) iriTerm(iri string) Term {
+	if !strings.HasPrefix(iri, "_:") {
+		return NewIri(iri)
+	}
+	return parser.blanks.Label(iri)
+}
+
+// Returns a new context with the definitions in the local context
+// (an object, an array of objects, or null to reset the context.)
+func (context jsonLdContext) update(local interface{}) (jsonLdContext, error) {
+	switch local := local.(type) {
+	case nil:
+		return context, nil
+	case []interface{}:
+		var err error
+		for _, item := range local {
+			if item == nil {
+				context = jsonLdContext{terms: map[string]jsonLdTerm{}, base: context.base}
+				continue
+			}
+			if context, err = context.update(item); err != nil {
+				return context, err
+			}
+		}
+		return context, nil
+	case map[string]interface{}:
+		updated := jsonLdContext{terms: map[string]jsonLdTerm{},
+			vocab: context.vocab, language: context.language, base: context.base}
+		for key, term := range context.terms {
+			updated.terms[key] = term
+		}
+		for key, value := range local {
+			if err := updated.define(key, value); err != nil {
+				return context, err
+			}
+		}
+		return updated, nil
+	}
+	return context, errors.New("Remote JSON-LD contexts are not supported")
+}
+
+func (context *jsonLdContext) define(key string, value interface{}) error {
+	switch key {
+	case "@vocab":
+		vocab, _ := value.(string)
+		context.vocab = context.expandIri(vocab, true)
+		return nil
+	case "@language":
+		context.language, _ = value.(string)
+		return nil
+	case "@base":
+		base, _ := value.(string)
+		context.base = ResolveIri(context.base, base)
+		return nil
+	case "@version":
+		return nil
+	}
+
+	switch value := value.(type) {
+	case nil:
+		delete(context.terms, key)
+	case string:
+		context.terms[key] = jsonLdTerm{id: value}
+	case map[string]interface{}:
+		term := jsonLdTerm{}
+		term.id, _ = value["@id"].(string)
+		term.typeId, _ = value["@type"].(string)
+		term.container, _ = value["@container"].(string)
+		if language, ok := value["@language"]; ok {
+			text, _ := language.(string)
+			term.language = &text
+		}
+		if term.id == "" && strings.Contains(key, ":") {
+			term.id = key
+		}
+		context.terms[key] = term
+	default:
+		return errors.New("Invalid JSON-LD term definition for " + key)
+	}
+	return nil
+}
+
+// Expands a term, a compact IRI (prefix:suffix), or a vocabulary
+// relative IRI into an absolute IRI. Other relative IRIs are
+// resolved against the base. Vocab is true when expanding
+// properties and types.
+func (context jsonLdContext) expandIri(value string, vocab bool) string {
+	return context.expandIriDepth(value, vocab, 0)
+}
+
+func (context jsonLdContext) expandIriDepth(value string, vocab bool, depth int) string {
+	if strings.HasPrefix(value, "@") || depth > 10 {
+		return value
+	}
+
+	if term, ok := context.terms[value]; ok && vocab && term.id != value {
+		return context.expandIriDepth(term.id, true, depth+1)
+	}
+
+	if index := strings.Index(value, ":"); index != -1 {
+		prefix, suffix := value[:index], value[index+1:]
+		if prefix == "_" || strings.HasPrefix(suffix, "//") {
+			return value
+		}
+		if term, ok := context.terms[prefix]; ok && term.id != "" {
+			return context.expandIriDepth(term.id, true, depth+1) + suffix
+		}
+		return value
+	}
+
+	if vocab && context.vocab != "" {
+		return context.vocab + value
+	}
+	if !vocab {
+		return ResolveIri(context.base, value)
+	}
+	return value
+}
@@ -0,0 +1,565 @@
+// An LD Patch parser
+// https://www.w3.org/TR/ldpatch/
+//
+// An LD Patch document is a list of statements applied in order:
+//
+//	@prefix dc: <http://purl.org/dc/terms/> .
+//	Bind ?author <> / dc:creator [ dc:title "Alice" ] .
+//	Delete { ?author dc:title "Alice" } .
+//	Add { ?author dc:title "Alice Smith" } .
+//	UpdateList <> dc:subject 1..2 ( "Go" ) .
+//
+// The statements (and their short names) are Bind (B), Add (A),
+// AddNew (AN), Delete (D), DeleteExisting (DE), Cut (C), and
+// UpdateList (UL.) Each statement is an Update: Bind paths become
+// patterns that must have exactly one solution and the variables of
+// Add and Delete must have been bound by a previous Bind.
+//
+// In UpdateList slices missing indexes stand for the length of the
+// list (e.g. ".." appends the items) and negative ones are counted
+// from the end of the list. Negative indexes in paths are not
+// supported.
+package rdf
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const LdPatchContentType = "text/ldpatch"
+
+// Errors for LD Patch statements that do not apply to the graph.
+var CutError = errors.New("Cut must be applied to a blank node in the graph")
+var InvalidListError = errors.New("The list to update is not in the graph or the slice is out of bounds")
+
+type ldPatchParser struct {
+	turtle TurtleParser
+	bound  map[Term]bool // variables bound by Bind
+	values map[Term]Term // variables bound without a path (e.g. Bind ?x <> .)
+	count  int           // variables created for the steps of paths
+}
+
+// ParseLdPatch parses an LD Patch document resolving relative IRIs
+// against the base.
+func ParseLdPatch(text, base string) ([]Update, error) {
+	tokenizer := NewTokenizer(text)
+	tokenizer.sparql = true
+	tokenizer.ldpatch = true
+	parser := ldPatchParser{turtle: newTurtleParser(tokenizer, base),
+		bound: map[Term]bool{}, values: map[Term]Term{}}
+	return parser.parse()
+}
+
+func (parser *ldPatchParser) parse() ([]Update, error) {
+	updates := []Update{}
+	for {
+		token, err := parser.nextToken()
+		if err != nil {
+			return nil, err
+		}
+		if token == "" {
+			return updates, nil
+		}
+		if isDirective(token) {
+			if err = parser.turtle.parseNextDirective(token); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		var update *Update
+		switch token {
+		case "Bind", "B":
+			update, err = parser.parseBind()
+		case "Add", "A", "AddNew", "AN":
+			update, err = parser.parseAdd(token == "AddNew" || token == "AN")
+		case "Delete", "D", "DeleteExisting", "DE":
+			update, err = parser.parseDelete(token == "DeleteExisting" || token == "DE")
+		case "Cut", "C":
+			update, err = parser.parseCut()
+		case "UpdateList", "UL":
+			update, err = parser.parseUpdateList()
+		default:
+			err = parser.error("Unknown statement", token, "Bind", "Add", "AddNew", "Delete", "DeleteExisting", "Cut", "UpdateList")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if update != nil {
+			updates = append(updates, *update)
+		}
+	}
+}
+
+// Parses Bind ?var value path . which binds the variable to the
+// node at the end of the path. Returns nil if there is nothing to
+// match in the graph (e.g. Bind ?x <> .)
+func (parser *ldPatchParser) parseBind() (*Update, error) {
+	token, err := parser.nextToken()
+	if err != nil {
+		return nil, err
+	}
+	variable, err := parser.turtle.term(token)
+	if err != nil || !variable.IsVariable() {
+		return nil, parser.error("Expected a variable", token, "variable")
+	}
+	if token, err = parser.nextToken(); err != nil {
+		return nil, err
+	}
+	value, err := parser.value(token)
+	if err != nil {
+		return nil, err
+	}
+	pattern, end, token, err := parser.parsePath(value)
+	if err != nil {
+		return nil, err
+	}
+	if token != "." {
+		return nil, parser.error("Unexpected token in path", token, "/", "[", "!", ".")
+	}
+
+	isPathEnd := parser.isPathVariable(end)
+	parser.bound[variable] = true
+	if isPathEnd {
+		delete(parser.values, variable)
+		pattern = replaceTerm(pattern, end, variable)
+		return &Update{Where: pattern, Once: true, Bind: variable}, nil
+	}
+	parser.values[variable] = end
+	if len(pattern) == 0 {
+		return nil, nil
+	}
+	// only constraints, e.g. Bind ?x <> [ dc:title "A" ] .
+	return &Update{Where: pattern, Once: true}, nil
+}
+
+// Parses the steps and constraints of a path from the start node
+// until a token that is not part of the path, which is returned
+// along with the pattern for the path and the node at its end.
+func (parser *ldPatchParser) parsePath(start Term) ([]Triple, Term, string, error) {
+	pattern := []Triple{}
+	current := start
+	for {
+		token, err := parser.nextToken()
+		if err != nil {
+			return nil, Term{}, "", err
+		}
+		switch token {
+		case "/":
+			var step []Triple
+			if step, current, err = parser.parseStep(current); err != nil {
+				return nil, Term{}, "", err
+			}
+			pattern = append(pattern, step...)
+		case "[":
+			constraint, end, token, err := parser.parsePath(current)
+			if err != nil {
+				return nil, Term{}, "", err
+			}
+			if token == "=" {
+				if token, err = parser.nextToken(); err != nil {
+					return nil, Term{}, "", err
+				}
+				value, err := parser.value(token)
+				if err != nil {
+					return nil, Term{}, "", err
+				}
+				if !parser.isPathVariable(end) {
+					return nil, Term{}, "", parser.error("Expected a path before =", "=", "/")
+				}
+				constraint = replaceTerm(constraint, end, value)
+				if token, err = parser.nextToken(); err != nil {
+					return nil, Term{}, "", err
+				}
+			}
+			if token != "]" {
+				return nil, Term{}, "", parser.error("Unexpected token in path", token, "/", "[", "!", "=", "]")
+			}
+			pattern = append(pattern, constraint...)
+		case "!":
+			// Named variables must have a single value (see Update.Once)
+			if current.IsVariable() && strings.HasPrefix(current.Value(), "_:") {
+				unique := NewVariable("!" + current.Value()[2:])
+				pattern = replaceTerm(pattern, current, unique)
+				current = unique
+			}
+		default:
+			return pattern, current, token, nil
+		}
+	}
+}
+
+// Parses a step of a path (after /): a predicate, ^ and a predicate
+// for the reverse direction, or the index of an item in a list.
+func (parser *ldPatchParser) parseStep(current Term) ([]Triple, Term, error) {
+	token, err := parser.nextToken()
+	if err != nil {
+		return nil, Term{}, err
+	}
+	if index, ok := parser.index(token); ok {
+		if index < 0 {
+			return nil, Term{}, parser.error("Negative indexes are not supported in paths", token)
+		}
+		step := []Triple{}
+		for i := 0; i < index; i++ {
+			next := parser.pathVariable()
+			step = append(step, NewTriple(current, NewIri(RdfRestUri), next))
+			current = next
+		}
+		item := parser.pathVariable()
+		return append(step, NewTriple(current, NewIri(RdfFirstUri), item)), item, nil
+	}
+
+	reverse := token == "^"
+	if reverse {
+		if token, err = parser.nextToken(); err != nil {
+			return nil, Term{}, err
+		}
+	}
+	if isPunctuation(token) || token == "" {
+		return nil, Term{}, parser.error("Expected a predicate or index in path", token, "IRI", "prefixed name", "^", "index")
+	}
+	predicate, err := parser.turtle.predicate(token)
+	if err != nil {
+		return nil, Term{}, err
+	} else if !predicate.IsIri() {
+		return nil, Term{}, parser.error("Expected a predicate or index in path", token, "IRI", "prefixed name", "^", "index")
+	}
+	next := parser.pathVariable()
+	if reverse {
+		return []Triple{NewTriple(next, predicate, current)}, next, nil
+	}
+	return []Triple{NewTriple(current, predicate, next)}, next, nil
+}
+
+// Parses Add { triples } . or AddNew { triples } .
+func (parser *ldPatchParser) parseAdd(addNew bool) (*Update, error) {
+	triples, err := parser.parseGraph()
+	if err != nil {
+		return nil, err
+	}
+	return &Update{Insert: triples, InsertNew: addNew}, nil
+}
+
+// Parses Delete { triples } . or DeleteExisting { triples } .
+func (parser *ldPatchParser) parseDelete(deleteExisting bool) (*Update, error) {
+	triples, err := parser.parseGraph()
+	if err != nil {
+		return nil, err
+	}
+	for _, triple := range triples {
+		if triple.subject.IsBlankNode() || triple.predicate.IsBlankNode() || triple.object.IsBlankNode() {
+			return nil, parser.error("Blank nodes are not allowed in Delete", triple.String())
+		}
+	}
+	return &Update{Delete: triples, DeleteExisting: deleteExisting}, nil
+}
+
+// Parses { triples } . and replaces the variables with their values.
+func (parser *ldPatchParser) parseGraph() ([]Triple, error) {
+	if err := parser.expect("{"); err != nil {
+		return nil, err
+	}
+	triples, err := parser.turtle.parseBlock()
+	if err != nil {
+		return nil, err
+	}
+	for i := range triples {
+		terms := []*Term{&triples[i].subject, &triples[i].predicate, &triples[i].object}
+		for _, term := range terms {
+			if *term, err = parser.resolve(*term); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return triples, parser.expect(".")
+}
+
+// Parses Cut ?var . which deletes the triples of a blank node and
+// the blank nodes that only it links to.
+func (parser *ldPatchParser) parseCut() (*Update, error) {
+	token, err := parser.nextToken()
+	if err != nil {
+		return nil, err
+	}
+	if variable, err := parser.turtle.term(token); err != nil || !variable.IsVariable() {
+		return nil, parser.error("Expected a variable", token, "variable")
+	}
+	node, err := parser.value(token)
+	if err != nil {
+		return nil, err
+	}
+	if err = parser.expect("."); err != nil {
+		return nil, err
+	}
+	compute := func(graph RdfGraph, bindings Bindings) ([]Triple, []Triple, error) {
+		deleted, err := cutTriples(graph, bindings.matchTerm(node))
+		return deleted, nil, err
+	}
+	return &Update{compute: compute}, nil
+}
+
+// Parses UpdateList subject predicate slice ( items ) . which
+// replaces the slice of the list with the items.
+func (parser *ldPatchParser) parseUpdateList() (*Update, error) {
+	token, err := parser.nextToken()
+	if err != nil {
+		return nil, err
+	}
+	subject, err := parser.value(token)
+	if err != nil {
+		return nil, err
+	}
+	if token, err = parser.nextToken(); err != nil {
+		return nil, err
+	}
+	if isPunctuation(token) || token == "" {
+		return nil, parser.error("Expected a predicate", token, turtlePredicateTokens...)
+	}
+	predicate, err := parser.turtle.predicate(token)
+	if err != nil {
+		return nil, err
+	} else if !predicate.IsIri() {
+		return nil, parser.error("Expected a predicate", token, turtlePredicateTokens...)
+	}
+
+	slice := listSlice{}
+	if token, err = parser.nextToken(); err != nil {
+		return nil, err
+	}
+	if slice.start, slice.hasStart = parser.index(token); slice.hasStart {
+		if token, err = parser.nextToken(); err != nil {
+			return nil, err
+		}
+	}
+	if token != ".." {
+		return nil, parser.error("Expected a slice", token, "index", "..")
+	}
+	if token, err = parser.nextToken(); err != nil {
+		return nil, err
+	}
+	if slice.end, slice.hasEnd = parser.index(token); slice.hasEnd {
+		if token, err = parser.nextToken(); err != nil {
+			return nil, err
+		}
+	}
+	if token != "(" {
+		return nil, parser.error("Expected a collection", token, "index", "(")
+	}
+
+	// triples for blank node property lists and collections in the items
+	triples := []Triple{}
+	parser.turtle.emit = func(triple Triple) error {
+		triples = append(triples, triple)
+		return nil
+	}
+	items := []Term{}
+	for {
+		if token, err = parser.nextToken(); err != nil {
+			return nil, err
+		}
+		if token == ")" {
+			break
+		}
+		if token == "" || (isPunctuation(token) && !parser.turtle.isObjectStart(token)) {
+			return nil, parser.error("Unexpected token in collection", token, append(turtleObjectTokens, ")")...)
+		}
+		item, err := parser.turtle.parseObject(token)
+		if err == nil {
+			item, err = parser.resolve(item)
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err = parser.expect("."); err != nil {
+		return nil, err
+	}
+
+	compute := func(graph RdfGraph, bindings Bindings) ([]Triple, []Triple, error) {
+		return updateList(graph, bindings.matchTerm(subject), predicate, slice, items)
+	}
+	return &Update{Insert: triples, compute: compute}, nil
+}
+
+// Returns the term for a value (an IRI, a literal, or a variable
+// bound by a previous Bind.)
+func (parser *ldPatchParser) value(token string) (Term, error) {
+	if token == "" || isPunctuation(token) {
+		return Term{}, parser.error("Expected a value", token, "IRI", "prefixed name", "literal", "variable")
+	}
+	term, err := parser.turtle.term(token)
+	if err != nil {
+		return Term{}, err
+	}
+	return parser.resolve(term)
+}
+
+// Replaces variables bound without a path with their values. Other
+// variables must have been bound by a previous Bind.
+func (parser *ldPatchParser) resolve(term Term) (Term, error) {
+	if !term.IsVariable() {
+		return term, nil
+	}
+	if value, ok := parser.values[term]; ok {
+		return value, nil
+	}
+	if !parser.bound[term] {
+		return Term{}, parser.error("Unbound variable", term.String())
+	}
+	return term, nil
+}
+
+// Returns the value of an index token (an integer.)
+func (parser *ldPatchParser) index(token string) (int, bool) {
+	if !strings.HasPrefix(token, "\"") {
+		return 0, false
+	}
+	term, err := parser.turtle.literal(token)
+	if err != nil || term.Datatype() != xsdNamespace+"integer" {
+		return 0, false
+	}
+	index, err := strconv.Atoi(term.Value())
+	return index, err == nil
+}
+
+// Returns a new variable for a step of a path. Like blank nodes in
+// SPARQL patterns their values are not compared (see Update.Once.)
+func (parser *ldPatchParser) pathVariable() Term {
+	parser.count++
+	return NewVariable("_:" + strconv.Itoa(parser.count))
+}
+
+// Variables of paths are the ones not bound by a previous Bind.
+func (parser *ldPatchParser) isPathVariable(term Term) bool {
+	return term.IsVariable() && !parser.bound[term]
+}
+
+func (parser *ldPatchParser) expect(expected string) error {
+	token, err := parser.nextToken()
+	if err != nil {
+		return err
+	}
+	if token != expected {
+		return parser.error("Unexpected token", token, expected)
+	}
+	return nil
+}
+
+func (parser *ldPatchParser) nextToken() (string, error) {
+	return parser.turtle.tokenizer.GetNextToken()
+}
+
+func (parser *ldPatchParser) error(message, token string, expected ...string) error {
+	return parser.turtle.error(message, token, expected...)
+}
+
+// Returns the triples with a term replaced by another one.
+func replaceTerm(triples []Triple, old, new Term) []Triple {
+	replaced := make([]Triple, len(triples))
+	for i, triple := range triples {
+		terms := []*Term{&triple.subject, &triple.predicate, &triple.object}
+		for _, term := range terms {
+			if *term == old {
+				*term = new
+			}
+		}
+		replaced[i] = triple
+	}
+	return replaced
+}
+
+// Returns the triples that Cut deletes: the ones with the blank
+// node and, recursively, the ones of the blank nodes it links to.
+func cutTriples(graph RdfGraph, node Term) ([]Triple, error) {
+	if !node.IsBlankNode() {
+		return nil, CutError
+	}
+	deleted := graph.Match(Term{}, Term{}, node)
+	visited := map[Term]bool{node: true}
+	nodes := []Term{node}
+	for len(nodes) > 0 {
+		for _, triple := range graph.Match(nodes[0], Term{}, Term{}) {
+			deleted = append(deleted, triple)
+			if triple.object.IsBlankNode() && !visited[triple.object] {
+				visited[triple.object] = true
+				nodes = append(nodes, triple.object)
+			}
+		}
+		nodes = nodes[1:]
+	}
+	if len(deleted) == 0 {
+		return nil, CutError
+	}
+	return deleted, nil
+}
+
+// A slice of a list in UpdateList (see the package documentation
+// for missing and negative indexes.)
+type listSlice struct {
+	start, end       int
+	hasStart, hasEnd bool
+}
+
+// Returns the start and end indexes of the slice in a list of the
+// given length.
+func (slice listSlice) bounds(length int) (int, int, bool) {
+	start, end := length, length
+	if slice.hasStart {
+		start = slice.start
+	}
+	if slice.hasEnd {
+		end = slice.end
+	}
+	if start < 0 {
+		start += length
+	}
+	if end < 0 {
+		end += length
+	}
+	return start, end, start >= 0 && start <= end && end <= length
+}
+
+// Returns the triples that UpdateList deletes and inserts: the
+// nodes of the list are replaced with new ones for the new items.
+func updateList(graph RdfGraph, subject, predicate Term, slice listSlice, items []Term) ([]Triple, []Triple, error) {
+	heads := graph.Match(subject, predicate, Term{})
+	if len(heads) != 1 {
+		return nil, nil, InvalidListError
+	}
+
+	deleted := []Triple{heads[0]}
+	values := []Term{}
+	visited := map[Term]bool{}
+	for node := heads[0].object; node != NewIri(RdfNilUri); {
+		firsts := graph.Match(node, NewIri(RdfFirstUri), Term{})
+		rests := graph.Match(node, NewIri(RdfRestUri), Term{})
+		if len(firsts) != 1 || len(rests) != 1 || visited[node] {
+			return nil, nil, InvalidListError
+		}
+		visited[node] = true
+		deleted = append(deleted, firsts[0], rests[0])
+		values = append(values, firsts[0].object)
+		node = rests[0].object
+	}
+
+	start, end, ok := slice.bounds(len(values))
+	if !ok {
+		return nil, nil, InvalidListError
+	}
+	updated := append(append(append([]Term{}, values[:start]...), items...), values[end:]...)
+
+	blanks := newBlankNodes()
+	head := NewIri(RdfNilUri)
+	inserted := []Triple{}
+	for i := len(updated) - 1; i >= 0; i-- {
+		node := blanks.New()
+		inserted = append(inserted, NewTriple(node, NewIri(RdfFirstUri), updated[i]),
+			NewTriple(node, NewIri(RdfRestUri), head))
+		head = node
+	}
+	inserted = append(inserted, NewTriple(subject, predicate, head))
+	return deleted, inserted, nil
+}
@@ -0,0 +1,93 @@
+package rdf
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLdPatch(t *testing.T) {
+	a, title := NewIri("http://x/a"), NewIri("http://purl.org/dc/terms/title")
+	graph, err := applyPatch(t, ParseLdPatch, `@prefix dc: <http://purl.org/dc/terms/> .
+		Bind ?a <> / dc:subject [ / dc:title = "A" ] ! .
+		Delete { ?a dc:title "A" } .
+		A { ?a dc:title "A2" ; dc:creator [ dc:title "someone" ] } .
+		Bind ?creator ?a / dc:creator .
+		Add { ?creator dc:description "new" } .`)
+	if err != nil || graph.HasTriple(a, title, NewLiteral("A")) || !graph.HasTriple(a, title, NewLiteral("A2")) ||
+		len(graph.Match(Term{}, NewIri("http://purl.org/dc/terms/description"), NewLiteral("new"))) != 1 {
+		t.Errorf("Unexpected graph after LD Patch (%s):\n%s", err, graph)
+	}
+
+	// Cut deletes the blank node and the ones it links to
+	graph, err = applyPatch(t, ParseLdPatch, `@prefix dc: <http://purl.org/dc/terms/> .
+		Add { <> dc:creator [ dc:title "someone" ; dc:creator [ dc:title "else" ] ] } .
+		Bind ?creator <> / dc:creator .
+		Cut ?creator .`)
+	if err != nil || graph.Len() != 4 {
+		t.Errorf("Unexpected graph after Cut (%s):\n%s", err, graph)
+	}
+
+	conflicts := map[string]error{
+		`Bind ?s <> / <http://x/none> .`:                                                            NoSolutionError,
+		`Bind ?s <> / <http://purl.org/dc/terms/subject> .`:                                         ManySolutionsError,
+		`DeleteExisting { <> <http://x/p> "none" } .`:                                               TripleNotFoundError,
+		`AddNew { <> <http://purl.org/dc/terms/title> "old" } .`:                                    TripleExistsError,
+		`Bind ?a <> / <http://purl.org/dc/terms/subject> ! / ^<http://purl.org/dc/terms/subject> .`: ManySolutionsError,
+		`Bind ?a <> . Cut ?a .`:                                                                     CutError,
+		`UpdateList <> <http://purl.org/dc/terms/title> .. ( 1 ) .`:                                 InvalidListError,
+	}
+	for patch, expected := range conflicts {
+		if _, err = applyPatch(t, ParseLdPatch, patch); err != expected {
+			t.Errorf("Expected %s for %s, got %s", expected, patch, err)
+		}
+	}
+}
+
+func TestLdPatchUpdateList(t *testing.T) {
+	list := NewIri("http://x/list")
+	tests := map[string][]string{
+		`..`:    {"a", "b", "c", "x", "y"},
+		`0..0`:  {"x", "y", "a", "b", "c"},
+		`1..2`:  {"a", "x", "y", "c"},
+		`1..`:   {"a", "x", "y"},
+		`-1..`:  {"a", "b", "x", "y"},
+		`0..-1`: {"x", "y", "c"},
+	}
+	for slice, expected := range tests {
+		graph, err := applyPatch(t, ParseLdPatch, `Add { <> <http://x/list> ( "a" "b" "c" ) } .
+			UpdateList <> <http://x/list> `+slice+` ( "x" "y" ) .`)
+		if err != nil {
+			t.Errorf("Error updating list with %s: %s", slice, err)
+			continue
+		}
+		items := []string{}
+		node := graph.Match(NewIri("http://x/node"), list, Term{})[0].object
+		for node != NewIri(RdfNilUri) {
+			items = append(items, graph.Match(node, NewIri(RdfFirstUri), Term{})[0].object.Value())
+			node = graph.Match(node, NewIri(RdfRestUri), Term{})[0].object
+		}
+		if len(graph.Match(Term{}, NewIri(RdfFirstUri), Term{})) != len(expected) || fmt.Sprint(items) != fmt.Sprint(expected) {
+			t.Errorf("Unexpected list for %s: %v", slice, items)
+		}
+	}
+}
+
+func TestLdPatchErrors(t *testing.T) {
+	invalid := []string{
+		`Add { ?x <p> <o> } .`,
+		`Add { <s> <p> <o> }`,
+		`Delete { _:b <p> <o> } .`,
+		`Bind <s> <s> / <p> .`,
+		`Bind ?x <s> / -1 .`,
+		`Bind ?x <s> [ = <o> ] .`,
+		`Replace { <s> <p> <o> } .`,
+		`UpdateList <s> <p> 1 ( <o> ) .`,
+	}
+	for _, patch := range invalid {
+		if _, err := ParseLdPatch(patch, ""); err == nil {
+			t.Errorf("Invalid LD Patch not detected: %s", patch)
+		} else if _, ok := err.(*ParseError); !ok {
+			t.Errorf("Unexpected error type for %s: %#v", patch, err)
+		}
+	}
+}
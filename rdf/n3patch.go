@@ -0,0 +1,120 @@
+// An N3 Patch parser
+// https://solidproject.org/TR/protocol#n3-patch
+//
+// An N3 Patch is an N3 document (Turtle with variables and formulas
+// in braces) with one patch resource of type solid:InsertDeletePatch:
+//
+//	@prefix solid: <http://www.w3.org/ns/solid/terms#> .
+//	@prefix dc: <http://purl.org/dc/terms/> .
+//	_:patch a solid:InsertDeletePatch ;
+//	    solid:where { ?node dc:title "old" } ;
+//	    solid:inserts { ?node dc:title "new" } ;
+//	    solid:deletes { ?node dc:title "old" } .
+//
+// All three formulas are optional. The patch fails (see ApplyUpdates)
+// unless the where formula has exactly one solution and the triples
+// to delete are in the graph. Other N3 features (e.g. rules and
+// quantifiers) are not supported.
+package rdf
+
+const N3ContentType = "text/n3"
+
+// ParseN3Patch parses an N3 Patch document resolving relative IRIs
+// against the base.
+func ParseN3Patch(text, base string) ([]Update, error) {
+	tokenizer := NewTokenizer(text)
+	tokenizer.sparql = true
+	parser := newTurtleParser(tokenizer, base)
+	parser.formulas = map[Term]formula{}
+
+	// Keep the position of each triple (i.e. the end of its
+	// statement) to report errors in the patch resource.
+	var graph RdfGraph
+	positions := map[Triple]position{}
+	err := parser.ParseFunc(func(triple Triple) error {
+		graph.AppendTriple(triple)
+		positions[triple] = position{parser.tokenizer.tokenRow, parser.tokenizer.tokenCol}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	patches := graph.Match(Term{}, NewIri(RdfTypeUri), NewIri(SolidInsertDeletePatchUri))
+	if len(patches) == 0 {
+		return nil, &ParseError{Message: "The patch must have exactly one solid:InsertDeletePatch resource"}
+	}
+	if len(patches) > 1 {
+		return nil, n3PatchError("The patch must have exactly one solid:InsertDeletePatch resource",
+			patches[1].subject.String(), positions[patches[1]])
+	}
+	patch := patches[0].subject
+
+	update := Update{Once: true, DeleteExisting: true}
+	where, err := patchFormula(graph, parser.formulas, positions, patch, SolidWhereUri)
+	if err != nil {
+		return nil, err
+	}
+	inserts, err := patchFormula(graph, parser.formulas, positions, patch, SolidInsertsUri)
+	if err != nil {
+		return nil, err
+	}
+	deletes, err := patchFormula(graph, parser.formulas, positions, patch, SolidDeletesUri)
+	if err != nil {
+		return nil, err
+	}
+	update.Where, update.Insert, update.Delete = where.triples, inserts.triples, deletes.triples
+
+	// Blank nodes could never match the ones in the graph
+	for _, formula := range []formula{where, deletes} {
+		for _, triple := range formula.triples {
+			if triple.subject.IsBlankNode() || triple.predicate.IsBlankNode() || triple.object.IsBlankNode() {
+				return nil, n3PatchError("Blank nodes are not allowed in solid:where and solid:deletes",
+					triple.String(), formula.position)
+			}
+		}
+	}
+
+	// Variables must be bound by the where formula
+	bound := map[Term]bool{}
+	for _, triple := range update.Where {
+		for _, term := range []Term{triple.subject, triple.predicate, triple.object} {
+			if term.IsVariable() {
+				bound[term] = true
+			}
+		}
+	}
+	for _, formula := range []formula{inserts, deletes} {
+		for _, triple := range formula.triples {
+			for _, term := range []Term{triple.subject, triple.predicate, triple.object} {
+				if term.IsVariable() && !bound[term] {
+					return nil, n3PatchError("Variable not found in solid:where", term.String(), formula.position)
+				}
+			}
+		}
+	}
+	return []Update{update}, nil
+}
+
+// Returns the formula that is the value of a property of the patch
+// (or an empty one if the patch does not have the property.)
+func patchFormula(graph RdfGraph, formulas map[Term]formula, positions map[Triple]position, patch Term, property string) (formula, error) {
+	values := graph.Match(patch, NewIri(property), Term{})
+	switch {
+	case len(values) == 0:
+		return formula{}, nil
+	case len(values) > 1:
+		return formula{}, n3PatchError("The patch has more than one value for "+property, "", positions[values[1]])
+	}
+	value, ok := formulas[values[0].object]
+	if !ok {
+		return formula{}, n3PatchError("The value of "+property+" must be a formula",
+			values[0].object.String(), positions[values[0]])
+	}
+	return value, nil
+}
+
+// Reports an error at the position of a formula or a statement
+func n3PatchError(message, token string, at position) error {
+	return &ParseError{Message: message, Token: token, Line: at.line, Column: at.column}
+}
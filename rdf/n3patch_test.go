@@ -0,0 +1,81 @@
+package rdf
+
+import (
+	"testing"
+)
+
+// Applies a patch to the test graph (see sparqlTestGraph.)
+func applyPatch(t *testing.T, parse UpdateParser, patch string) (RdfGraph, error) {
+	graph, err := StringToGraph(sparqlTestGraph, "")
+	if err != nil {
+		t.Fatalf("Error parsing test graph: %s", err)
+	}
+	updates, err := parse(patch, "http://x/node")
+	if err != nil {
+		t.Fatalf("Error parsing patch: %s\n%s", err, patch)
+	}
+	updated, _, err := ApplyUpdates(graph, updates)
+	return updated, err
+}
+
+func TestN3Patch(t *testing.T) {
+	node, title := NewIri("http://x/node"), NewIri("http://purl.org/dc/terms/title")
+	graph, err := applyPatch(t, ParseN3Patch, `@prefix solid: <http://www.w3.org/ns/solid/terms#> .
+		@prefix dc: <http://purl.org/dc/terms/> .
+		_:patch a solid:InsertDeletePatch ;
+			solid:where { ?node dc:title "old" ; dc:subject ?a . ?a dc:title "A" } ;
+			solid:inserts { ?node dc:title "new" . ?a dc:description [ dc:title "B" ] } ;
+			solid:deletes { ?node dc:title "old" } .`)
+	if err != nil || graph.HasTriple(node, title, NewLiteral("old")) || !graph.HasTriple(node, title, NewLiteral("new")) ||
+		graph.Len() != 6 {
+		t.Errorf("Unexpected graph after N3 Patch (%s):\n%s", err, graph)
+	}
+
+	conflicts := map[string]error{
+		`{ ?s <http://purl.org/dc/terms/title> "none" }`: NoSolutionError,
+		`{ <> <http://purl.org/dc/terms/subject> ?s }`:   ManySolutionsError,
+	}
+	for where, expected := range conflicts {
+		_, err = applyPatch(t, ParseN3Patch, `[] a <http://www.w3.org/ns/solid/terms#InsertDeletePatch> ;
+			<http://www.w3.org/ns/solid/terms#where> `+where+` .`)
+		if err != expected {
+			t.Errorf("Expected %s for %s, got %s", expected, where, err)
+		}
+	}
+
+	_, err = applyPatch(t, ParseN3Patch, `[] a <http://www.w3.org/ns/solid/terms#InsertDeletePatch> ;
+		<http://www.w3.org/ns/solid/terms#deletes> { <> <http://purl.org/dc/terms/title> "none" } .`)
+	if err != TripleNotFoundError {
+		t.Errorf("Deleting a missing triple was not detected: %s", err)
+	}
+}
+
+func TestN3PatchErrors(t *testing.T) {
+	prefix := "@prefix solid: <http://www.w3.org/ns/solid/terms#> .\n"
+	invalid := []string{
+		`<> <p> "no patch" .`,
+		`_:a a solid:InsertDeletePatch . _:b a solid:InsertDeletePatch .`,
+		`[] a solid:InsertDeletePatch ; solid:inserts "not a formula" .`,
+		`[] a solid:InsertDeletePatch ; solid:inserts { ?s <p> <o> } .`,
+		`[] a solid:InsertDeletePatch ; solid:deletes { _:b <p> <o> } .`,
+		`[] a solid:InsertDeletePatch ; solid:where { <s> <p> <o> } , { <s> <p> <o2> } .`,
+		`[] a solid:InsertDeletePatch ; solid:inserts { <s> <p> <o> .`,
+	}
+	for i, patch := range invalid {
+		_, err := ParseN3Patch(prefix+patch, "")
+		parseErr, ok := err.(*ParseError)
+		if err == nil {
+			t.Errorf("Invalid N3 Patch not detected: %s", patch)
+		} else if !ok {
+			t.Errorf("Unexpected error type for %s: %#v", patch, err)
+		} else if i > 0 && parseErr.Line != 2 {
+			t.Errorf("Unexpected position for %s: %s", patch, err)
+		}
+	}
+
+	// Errors in a formula are reported at its opening brace
+	_, err := ParseN3Patch(prefix+invalid[3], "")
+	if parseErr, ok := err.(*ParseError); !ok || parseErr.Column != 46 {
+		t.Errorf("Unexpected position for an unbound variable: %s", err)
+	}
+}
@@ -0,0 +1,223 @@
+package rdf
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// NTriples returns the graph serialized as N-Triples
+// (https://www.w3.org/TR/n-triples/)
+func (graph RdfGraph) NTriples() string {
+	var text strings.Builder
+	for _, triple := range graph.Triples() {
+		text.WriteString(triple.StringLn())
+	}
+	return text.String()
+}
+
+func escapeNTriples(value string) string {
+	replacer := strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n", "\r", "\\r")
+	return replacer.Replace(value)
+}
+
+// ParseNTriples parses a document in N-Triples. Relative URIs are
+// accepted (e.g. <> for the node itself) and resolved against
+// the base. Blank node labels are scoped to the document.
+func ParseNTriples(text, base string) ([]Triple, error) {
+	triples := []Triple{}
+	err := ReadNTriples(strings.NewReader(text), base, func(triple Triple) error {
+		triples = append(triples, triple)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return triples, nil
+}
+
+// ReadNTriples parses the N-Triples in the reader one line at a
+// time and calls emit with each triple (see ParseNTriples.) Parsing
+// stops with the error returned by emit, if any.
+func ReadNTriples(reader io.Reader, base string, emit func(Triple) error) error {
+	blanks := newBlankNodes()
+	buffered := bufio.NewReader(reader)
+	for number := 1; ; number++ {
+		text, readErr := buffered.ReadString('\n')
+		if readErr != nil && readErr != io.EOF {
+			return readErr
+		}
+
+		line := nTriplesLine{text: strings.TrimRight(text, "\r\n"), number: number, base: base, blanks: blanks}
+		if !line.done() {
+			triple, err := line.triple()
+			if err != nil {
+				return err
+			}
+			if err = emit(triple); err != nil {
+				return err
+			}
+		}
+
+		if readErr == io.EOF {
+			return nil
+		}
+	}
+}
+
+type nTriplesLine struct {
+	text   string
+	index  int
+	number int
+	base   string
+	blanks *blankNodes
+}
+
+func (line *nTriplesLine) triple() (Triple, error) {
+	subject, err := line.subject()
+	if err != nil {
+		return Triple{}, err
+	}
+	predicate, err := line.uri()
+	if err != nil {
+		return Triple{}, err
+	}
+	object, err := line.object()
+	if err != nil {
+		return Triple{}, err
+	}
+	if !line.consume('.') || !line.done() {
+		return Triple{}, line.error("Triple did not end with a period", ".")
+	}
+	return NewTriple(subject, predicate, object), nil
+}
+
+// Returns a ParseError for the current character of the line.
+func (line *nTriplesLine) error(message string, expected ...string) error {
+	token := ""
+	if line.index < len(line.text) {
+		char, _ := utf8.DecodeRuneInString(line.text[line.index:])
+		token = string(char)
+	}
+	return &ParseError{Message: message, Line: line.number,
+		Column: utf8.RuneCountInString(line.text[:line.index]) + 1, Token: token, Expected: expected}
+}
+
+func (line *nTriplesLine) skipWhiteSpace() {
+	for line.index < len(line.text) && (line.text[line.index] == ' ' || line.text[line.index] == '\t') {
+		line.index++
+	}
+}
+
+// Returns true if there is nothing but white space
+// or a comment left on the line.
+func (line *nTriplesLine) done() bool {
+	line.skipWhiteSpace()
+	return line.index == len(line.text) || line.text[line.index] == '#'
+}
+
+func (line *nTriplesLine) consume(char byte) bool {
+	line.skipWhiteSpace()
+	if line.index < len(line.text) && line.text[line.index] == char {
+		line.index++
+		return true
+	}
+	return false
+}
+
+func (line *nTriplesLine) peek() byte {
+	line.skipWhiteSpace()
+	if line.index < len(line.text) {
+		return line.text[line.index]
+	}
+	return 0
+}
+
+func (line *nTriplesLine) subject() (Term, error) {
+	if line.peek() == '_' {
+		return line.blank()
+	}
+	return line.uri()
+}
+
+func (line *nTriplesLine) object() (Term, error) {
+	switch line.peek() {
+	case '_':
+		return line.blank()
+	case '"':
+		return line.literal()
+	}
+	return line.uri()
+}
+
+func (line *nTriplesLine) uri() (Term, error) {
+	line.skipWhiteSpace()
+	start := line.index
+	if !line.consume('<') {
+		return Term{}, line.error("Expected URI", "IRI")
+	}
+	for line.index < len(line.text) {
+		char := line.text[line.index]
+		if char <= ' ' || strings.IndexByte("<\"{}|^`", char) != -1 {
+			return Term{}, line.error("Invalid character in URI")
+		}
+		line.index++
+		if char == '>' {
+			return NewIri(ResolveIri(line.base, line.text[start+1:line.index-1])), nil
+		}
+	}
+	return Term{}, line.error("URI did not end with >", ">")
+}
+
+func (line *nTriplesLine) blank() (Term, error) {
+	start := line.index
+	if !strings.HasPrefix(line.text[start:], "_:") {
+		return Term{}, line.error("Expected blank node", "blank node")
+	}
+	line.index += 2
+	for line.index < len(line.text) && !strings.ContainsRune(" \t.", rune(line.text[line.index])) {
+		line.index++
+	}
+	if line.index == start+2 {
+		return Term{}, line.error("Empty blank node label")
+	}
+	return line.blanks.Label(line.text[start:line.index]), nil
+}
+
+func (line *nTriplesLine) literal() (Term, error) {
+	start := line.index
+	line.index++
+	for {
+		if line.index >= len(line.text) {
+			return Term{}, line.error("String did not end with \"", "\"")
+		}
+		char := line.text[line.index]
+		line.index++
+		if char == '\\' {
+			line.index++
+		} else if char == '"' {
+			break
+		}
+	}
+
+	value := unescapeLiteral(line.text[start+1 : line.index-1])
+	if line.index < len(line.text) && line.text[line.index] == '@' {
+		langStart := line.index + 1
+		line.index++
+		for line.index < len(line.text) && isLanguageTagChar(line.text[line.index]) {
+			line.index++
+		}
+		return NewLangLiteral(value, line.text[langStart:line.index]), nil
+	} else if strings.HasPrefix(line.text[line.index:], "^^") {
+		line.index += 2
+		datatype, err := line.uri()
+		return NewTypedLiteral(value, datatype.Value()), err
+	}
+	return NewLiteral(value), nil
+}
+
+func isLanguageTagChar(char byte) bool {
+	return (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') ||
+		(char >= '0' && char <= '9') || char == '-'
+}
@@ -0,0 +1,53 @@
+package rdf
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ParseError is a syntax error in an RDF document along with where
+// it was found. Lines and columns start at 1 (columns are counted in
+// characters rather than bytes) and are zero if the location is not
+// known.
+type ParseError struct {
+	Message  string
+	Line     int
+	Column   int
+	Token    string   // the offending token or character ("" at the end of the document)
+	Expected []string // the tokens that were expected instead (if known)
+}
+
+// Descriptions of the tokens expected in Turtle
+var (
+	turtleSubjectTokens   = []string{"IRI", "prefixed name", "blank node", "[", "("}
+	turtlePredicateTokens = []string{"IRI", "prefixed name", "a"}
+	turtleObjectTokens    = []string{"IRI", "prefixed name", "blank node", "literal", "[", "("}
+)
+
+func (e *ParseError) Error() string {
+	text := e.Message
+	if e.Token != "" {
+		text += " (" + e.Token + ")"
+	}
+	if len(e.Expected) > 0 {
+		text += ". Expected " + strings.Join(e.Expected, ", ")
+	}
+	if e.Line > 0 {
+		text += fmt.Sprintf(". Line %d, column %d", e.Line, e.Column)
+	}
+	return text + "."
+}
+
+// Returns the line and column of a byte offset in the text.
+func textPosition(text string, offset int) (int, int) {
+	if offset < 0 {
+		offset = 0
+	} else if offset > len(text) {
+		offset = len(text)
+	}
+	before := text[:offset]
+	line := strings.Count(before, "\n") + 1
+	column := utf8.RuneCountInString(before[strings.LastIndex(before, "\n")+1:]) + 1
+	return line, column
+}
@@ -0,0 +1,147 @@
+package rdf
+
+import (
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// A Parser returns the triples in the text of an RDF serialization.
+// Relative IRIs (e.g. <> for the node itself) must be resolved
+// against the base IRI (see ResolveIri.)
+type Parser func(text, base string) ([]Triple, error)
+
+// A TripleReader parses the document in a reader as it reads it and
+// calls emit with each triple. Parsing stops with the error returned
+// by emit, if any.
+type TripleReader func(reader io.Reader, base string, emit func(Triple) error) error
+
+type mediaTypeParser struct {
+	contentType string
+	parser      Parser
+	reader      TripleReader // nil if the media type cannot be streamed
+}
+
+// Parsers by media type in order of preference.
+var parsers = []mediaTypeParser{
+	{TurtleContentType, parseTurtle, readTurtle},
+	{JsonLdContentType, ParseJsonLd, nil},
+	{NTriplesContentType, ParseNTriples, ReadNTriples},
+}
+
+// Media types of RDF serializations that we don't parse. Requests
+// in these media types cannot be saved as non-RDF sources.
+var unsupportedRdfContentTypes = []string{RdfXmlContentType, "text/n3",
+	"application/n-quads", "application/trig", "application/rdf+json"}
+
+// IsRdfContentType returns true for the media types (without
+// parameters) of RDF serializations, whether we can parse them
+// or not (see ParserFor.)
+func IsRdfContentType(contentType string) bool {
+	if _, ok := ParserFor(contentType); ok {
+		return true
+	}
+	contentType = strings.ToLower(contentType)
+	for _, unsupported := range unsupportedRdfContentTypes {
+		if contentType == unsupported {
+			return true
+		}
+	}
+	return false
+}
+
+// RegisterParser adds (or replaces) the parser for a media type.
+func RegisterParser(contentType string, parser Parser) {
+	contentType = strings.ToLower(contentType)
+	for i, registered := range parsers {
+		if registered.contentType == contentType {
+			parsers[i] = mediaTypeParser{contentType, parser, nil}
+			return
+		}
+	}
+	parsers = append(parsers, mediaTypeParser{contentType, parser, nil})
+}
+
+// ParserFor returns the parser for the media type (without parameters)
+func ParserFor(contentType string) (Parser, bool) {
+	registered, ok := registeredParser(contentType)
+	return registered.parser, ok
+}
+
+func registeredParser(contentType string) (mediaTypeParser, bool) {
+	contentType = strings.ToLower(contentType)
+	for _, registered := range parsers {
+		if registered.contentType == contentType {
+			return registered, true
+		}
+	}
+	return mediaTypeParser{}, false
+}
+
+// ParserContentTypes returns the media types that can be parsed.
+func ParserContentTypes() []string {
+	contentTypes := []string{}
+	for _, registered := range parsers {
+		contentTypes = append(contentTypes, registered.contentType)
+	}
+	return contentTypes
+}
+
+// ParseGraph parses the text in the given media type resolving
+// relative IRIs against the base (e.g. <> becomes the URI of the
+// node.) Returns UnsupportedContentTypeError if there is no parser
+// for the media type.
+func ParseGraph(text, contentType, base string) (RdfGraph, error) {
+	return ReadGraph(strings.NewReader(text), contentType, base)
+}
+
+// ReadGraph is like ParseGraph but reads the document from a reader.
+// Media types that can be streamed (e.g. Turtle and N-Triples) are
+// parsed as they are read, without keeping the text in memory.
+func ReadGraph(reader io.Reader, contentType, base string) (RdfGraph, error) {
+	registered, ok := registeredParser(contentType)
+	if !ok {
+		return RdfGraph{}, UnsupportedContentTypeError
+	}
+
+	if registered.reader == nil {
+		data, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return RdfGraph{}, err
+		}
+		return parseGraph(registered.parser, string(data), base)
+	}
+
+	var graph RdfGraph
+	err := registered.reader(reader, base, func(triple Triple) error {
+		graph.AppendTriple(triple)
+		return nil
+	})
+	if err != nil {
+		return RdfGraph{}, err
+	}
+	return graph, nil
+}
+
+func parseGraph(parser Parser, text, base string) (RdfGraph, error) {
+	if len(strings.TrimSpace(text)) == 0 {
+		return RdfGraph{}, nil
+	}
+
+	triples, err := parser(text, base)
+	if err != nil {
+		return RdfGraph{}, err
+	}
+	return NewGraph(triples...), nil
+}
+
+func parseTurtle(text, base string) ([]Triple, error) {
+	parser := NewTurtleParserWithBase(text, base)
+	err := parser.Parse()
+	return parser.Triples(), err
+}
+
+func readTurtle(reader io.Reader, base string, emit func(Triple) error) error {
+	parser := NewTurtleReader(reader, base)
+	return parser.ParseFunc(emit)
+}
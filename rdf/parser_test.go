@@ -0,0 +1,177 @@
+package rdf
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestParseNTriples(t *testing.T) {
+	text := `# a comment
+<http://x/a> <http://x/p> <http://x/b> .
+<> <http://x/p> "hello \"world\""@en-us . # another comment
+
+_:b1 <http://x/p> "3"^^<http://www.w3.org/2001/XMLSchema#integer> .
+`
+	triples, err := ParseNTriples(text, "")
+	if err != nil || len(triples) != 3 {
+		t.Fatalf("Error parsing N-Triples: %s %v", err, triples)
+	}
+
+	if triples[1].Object() != NewLangLiteral(`hello "world"`, "en-us") || triples[1].Subject() != NewIri("") {
+		t.Errorf("Unexpected triple: %s", triples[1])
+	}
+
+	if !triples[2].Subject().IsBlankNode() || triples[2].Object() != NewTypedLiteral("3", xsdNamespace+"integer") {
+		t.Errorf("Unexpected triple: %s", triples[2])
+	}
+
+	invalid := []string{`<a> <b> <c>`, `<a> <b c> <d> .`, `<a> "b" <c> .`, `<a> <b> "c .`, `<a> <b> <c> . <d>`}
+	for _, test := range invalid {
+		if _, err := ParseNTriples(test, ""); err == nil {
+			t.Errorf("Invalid N-Triples not detected: %s", test)
+		}
+	}
+}
+
+func TestParseJsonLd(t *testing.T) {
+	text := `{
+  "@context": {
+    "dcterms": "http://purl.org/dc/terms/",
+    "title": {"@id": "dcterms:title", "@language": "en"},
+    "seeAlso": {"@id": "http://x/seeAlso", "@type": "@id"},
+    "parts": {"@id": "http://x/parts", "@container": "@list"}
+  },
+  "@id": "",
+  "@type": "http://x/Book",
+  "title": "hello",
+  "seeAlso": ["http://x/a", "http://x/b"],
+  "http://x/count": 3,
+  "dcterms:created": {"@value": "2016", "@type": "http://www.w3.org/2001/XMLSchema#gYear"},
+  "http://x/author": {"http://x/name": "someone"},
+  "parts": ["a", "b"],
+  "ignored": "not an IRI"
+}`
+	triples, err := ParseJsonLd(text, "")
+	if err != nil {
+		t.Fatalf("Error parsing JSON-LD: %s", err)
+	}
+
+	graph := NewGraph(triples...)
+	expected := []Triple{
+		NewTriple(NewIri(""), NewIri(RdfTypeUri), NewIri("http://x/Book")),
+		NewTriple(NewIri(""), NewIri("http://purl.org/dc/terms/title"), NewLangLiteral("hello", "en")),
+		NewTriple(NewIri(""), NewIri("http://x/seeAlso"), NewIri("http://x/b")),
+		NewTriple(NewIri(""), NewIri("http://x/count"), NewTypedLiteral("3", xsdNamespace+"integer")),
+		NewTriple(NewIri(""), NewIri("http://purl.org/dc/terms/created"), NewTypedLiteral("2016", xsdNamespace+"gYear")),
+	}
+	for _, triple := range expected {
+		if !graph.HasTriple(triple.Subject(), triple.Predicate(), triple.Object()) {
+			t.Errorf("Triple not found: %s\n%s", triple, graph)
+		}
+	}
+
+	author, _ := graph.GetObject(NewIri(""), NewIri("http://x/author"))
+	if !author.IsBlankNode() || !graph.HasTriple(author, NewIri("http://x/name"), NewLiteral("someone")) {
+		t.Errorf("Nested node not parsed: %s", graph)
+	}
+
+	list, _ := graph.GetObject(NewIri(""), NewIri("http://x/parts"))
+	if !graph.HasTriple(list, NewIri(RdfFirstUri), NewLiteral("a")) {
+		t.Errorf("List not parsed: %s", graph)
+	}
+
+	if graph.HasPredicate(NewIri(""), NewIri("ignored")) || graph.Len() != 13 {
+		t.Errorf("Unexpected number of triples: %d\n%s", graph.Len(), graph)
+	}
+}
+
+func TestParseJsonLdGraph(t *testing.T) {
+	text := `{"@context": {"@vocab": "http://x/"},
+		"@graph": [{"@id": "http://x/a", "name": "a"}, {"@id": "_:one", "name": "b"}, {"@id": "_:one", "name": "c"}]}`
+	triples, err := ParseJsonLd(text, "")
+	if err != nil || len(triples) != 3 {
+		t.Fatalf("Error parsing JSON-LD graph: %s %v", err, triples)
+	}
+
+	if triples[0].String() != `<http://x/a> <http://x/name> "a" .` {
+		t.Errorf("Unexpected triple: %s", triples[0])
+	}
+
+	if triples[1].Subject() != triples[2].Subject() || !triples[1].Subject().IsBlankNode() {
+		t.Errorf("Blank node not preserved: %v", triples)
+	}
+
+	if _, err := ParseJsonLd(`{"@context": "http://x/context.jsonld"}`, ""); err == nil {
+		t.Errorf("Remote context not rejected")
+	}
+}
+
+func TestParseGraph(t *testing.T) {
+	graph, err := ParseGraph(`{"@id": "", "http://x/p": "v"}`, "Application/LD+JSON", "http://x/node")
+	if err != nil || !graph.HasTriple(NewIri("http://x/node"), NewIri("http://x/p"), NewLiteral("v")) {
+		t.Errorf("JSON-LD not parsed: %s %s", err, graph)
+	}
+
+	if _, err := ParseGraph("", "text/html", "http://x/node"); err != UnsupportedContentTypeError {
+		t.Errorf("Unsupported content type not detected: %s", err)
+	}
+}
+
+func TestParseGraphRelativeIris(t *testing.T) {
+	subject := NewIri("http://x/container/node")
+	texts := map[string]string{
+		TurtleContentType:   `<> <p> <other> .`,
+		NTriplesContentType: `<> <p> <other> .`,
+		JsonLdContentType:   `{"@id": "", "http://x/container/p": {"@id": "other"}}`,
+	}
+	for contentType, text := range texts {
+		graph, err := ParseGraph(text, contentType, subject.Value())
+		if err != nil || !graph.HasTriple(subject, NewIri("http://x/container/p"), NewIri("http://x/container/other")) {
+			t.Errorf("Relative IRIs not resolved in %s: %s %s", contentType, err, graph)
+		}
+	}
+}
+
+func TestReadGraph(t *testing.T) {
+	texts := map[string]string{
+		TurtleContentType:   "@prefix x: <http://x/> .\n" + strings.Repeat("<> x:p x:o1, x:o2 ; x:q \"a\" .\n", 500),
+		NTriplesContentType: strings.Repeat("<> <http://x/p> <http://x/o1> .\n<> <http://x/p> <http://x/o2> .\n<> <http://x/q> \"a\" .\n", 500),
+		JsonLdContentType:   `{"@id": "", "http://x/p": [{"@id": "http://x/o1"}, {"@id": "http://x/o2"}], "http://x/q": "a"}`,
+	}
+	for contentType, text := range texts {
+		graph, err := ReadGraph(iotest.OneByteReader(strings.NewReader(text)), contentType, "http://x/node")
+		if err != nil || graph.Len() != 3 || !graph.HasTriple(NewIri("http://x/node"), NewIri("http://x/q"), NewLiteral("a")) {
+			t.Errorf("Error reading %s: %s %s", contentType, err, graph)
+		}
+	}
+
+	reader := io.MultiReader(strings.NewReader("<a> <b> <c> .\n"), iotest.ErrReader(errors.New("boom")))
+	if _, err := ReadGraph(reader, NTriplesContentType, ""); err == nil || err.Error() != "boom" {
+		t.Errorf("Read error not detected: %s", err)
+	}
+}
+
+func TestParseErrorPositions(t *testing.T) {
+	_, err := ParseNTriples("<a> <b> <c> .\n  <a> <b> <c d> .\n", "")
+	if parseErr, ok := err.(*ParseError); !ok || parseErr.Line != 2 || parseErr.Column != 13 || parseErr.Token != " " {
+		t.Errorf("Unexpected N-Triples error: %v", err)
+	}
+
+	_, err = ParseJsonLd("{\n  \"@id\": \"\",\n  \"http://x/p\" \"v\"\n}", "")
+	if parseErr, ok := err.(*ParseError); !ok || parseErr.Line != 3 || parseErr.Column != 16 {
+		t.Errorf("Unexpected JSON-LD error: %v", err)
+	}
+
+	_, err = ParseJsonLd(`{"@id": "",`, "")
+	if parseErr, ok := err.(*ParseError); !ok || parseErr.Line != 1 || parseErr.Column != 12 {
+		t.Errorf("Unexpected JSON-LD error at the end of the document: %v", err)
+	}
+
+	text := (&ParseError{Message: "Expected an object", Line: 2, Column: 5, Token: ".", Expected: []string{"IRI", "literal"}}).Error()
+	if text != "Expected an object (.). Expected IRI, literal. Line 2, column 5." {
+		t.Errorf("Unexpected error text: %s", text)
+	}
+}
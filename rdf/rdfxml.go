@@ -0,0 +1,114 @@
+package rdf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// RdfXml returns the graph serialized as RDF/XML
+// (https://www.w3.org/TR/rdf-syntax-grammar/) with the triples
+// grouped by subject. Returns an error if a predicate cannot
+// be represented as an XML element name.
+func (graph RdfGraph) RdfXml() (string, error) {
+	subjects := []Term{}
+	triplesBySubject := map[Term][]Triple{}
+	namespaces := []string{knownPrefixes[0].namespace}
+	prefixes := map[string]string{knownPrefixes[0].namespace: knownPrefixes[0].prefix}
+	for _, triple := range graph.Triples() {
+		if _, found := triplesBySubject[triple.subject]; !found {
+			subjects = append(subjects, triple.subject)
+		}
+		triplesBySubject[triple.subject] = append(triplesBySubject[triple.subject], triple)
+
+		namespace, _, err := splitXmlName(triple.predicate.Value())
+		if err != nil {
+			return "", err
+		}
+		if _, found := prefixes[namespace]; !found {
+			prefixes[namespace] = xmlPrefix(namespace, len(namespaces))
+			namespaces = append(namespaces, namespace)
+		}
+	}
+
+	var text bytes.Buffer
+	text.WriteString("<?xml version=\"1.0\" encoding=\"utf-8\"?>\n<rdf:RDF")
+	for _, namespace := range namespaces {
+		fmt.Fprintf(&text, "\n    xmlns:%s=\"%s\"", prefixes[namespace], xmlEscape(namespace))
+	}
+	text.WriteString(">\n")
+
+	for _, subject := range subjects {
+		if subject.IsBlankNode() {
+			fmt.Fprintf(&text, "  <rdf:Description rdf:nodeID=\"%s\">\n", xmlEscape(subject.Value()))
+		} else {
+			fmt.Fprintf(&text, "  <rdf:Description rdf:about=\"%s\">\n", xmlEscape(subject.Value()))
+		}
+
+		for _, triple := range triplesBySubject[subject] {
+			namespace, local, _ := splitXmlName(triple.predicate.Value())
+			name := prefixes[namespace] + ":" + local
+			object := triple.object
+			switch {
+			case object.IsBlankNode():
+				fmt.Fprintf(&text, "    <%s rdf:nodeID=\"%s\"/>\n", name, xmlEscape(object.Value()))
+			case object.IsLiteral():
+				attributes := ""
+				if object.Language() != "" {
+					attributes = fmt.Sprintf(" xml:lang=\"%s\"", xmlEscape(object.Language()))
+				} else if object.Datatype() != xsdStringUri {
+					attributes = fmt.Sprintf(" rdf:datatype=\"%s\"", xmlEscape(object.Datatype()))
+				}
+				fmt.Fprintf(&text, "    <%s%s>%s</%s>\n", name, attributes, xmlEscape(object.Value()), name)
+			default:
+				fmt.Fprintf(&text, "    <%s rdf:resource=\"%s\"/>\n", name, xmlEscape(object.Value()))
+			}
+		}
+		text.WriteString("  </rdf:Description>\n")
+	}
+	text.WriteString("</rdf:RDF>\n")
+	return text.String(), nil
+}
+
+// Splits an IRI into a namespace and a local name that is a
+// valid XML name (e.g. http://x/ns#name into http://x/ns# and name)
+func splitXmlName(iri string) (string, string, error) {
+	start := len(iri)
+	for start > 0 && isXmlNameChar(rune(iri[start-1])) {
+		start--
+	}
+	for start < len(iri) && !isXmlNameStartChar(rune(iri[start])) {
+		start++
+	}
+	if start == len(iri) {
+		return "", "", errors.New("Cannot represent predicate as RDF/XML: " + iri)
+	}
+	return iri[:start], iri[start:], nil
+}
+
+func isXmlNameStartChar(char rune) bool {
+	return char == '_' || (char < unicode.MaxASCII && unicode.IsLetter(char))
+}
+
+func isXmlNameChar(char rune) bool {
+	return isXmlNameStartChar(char) || char == '-' || char == '.' ||
+		(char < unicode.MaxASCII && unicode.IsDigit(char))
+}
+
+func xmlPrefix(namespace string, index int) string {
+	for _, known := range knownPrefixes {
+		if known.namespace == namespace {
+			return known.prefix
+		}
+	}
+	return fmt.Sprintf("ns%d", index)
+}
+
+func xmlEscape(text string) string {
+	var escaped strings.Builder
+	xml.EscapeText(&escaped, []byte(text))
+	return escaped.String()
+}
@@ -1,89 +1,150 @@
 package rdf
 
-import "fmt"
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
 
+// Scanner reads the characters of a document from a reader as they
+// are needed. Only the characters from the last call to Release on
+// are kept in memory (e.g. the current token) so that large documents
+// can be scanned without having the whole document in memory.
+//
+// Indexes (see Index and Substring) are relative to the beginning
+// of the document.
 type Scanner struct {
+	reader *bufio.Reader
+	chars  []rune // characters read that have not been discarded
+	start  int    // index of chars[0] in the document
+	kept   int    // characters before this index can be discarded
 	index  int
-	text   string
-	chars  []rune
-	length int
+	eof    bool
+	err    error
 	row    int
 	col    int
 }
 
-func NewScanner(text string) Scanner {
-	// Convert the original string to an array of unicode runes.
-	// This allows us to iterate on it as if it was an array
-	// of ASCII chars even if there are Unicode characters on it
-	// that use 2-4 bytes.
-	chars := stringToRunes(text)
-	scanner := Scanner{text: text, chars: chars, length: len(chars), row: 1, col: 1}
-	return scanner
+// Minimum number of characters released before we bother to
+// discard them.
+const scannerMinDiscard = 1024
+
+func NewScanner(text string) *Scanner {
+	return NewReaderScanner(strings.NewReader(text))
+}
+
+func NewReaderScanner(reader io.Reader) *Scanner {
+	return &Scanner{reader: bufio.NewReader(reader), row: 1, col: 1}
 }
 
-func (scanner Scanner) Index() int {
+func (scanner *Scanner) Index() int {
 	return scanner.index
 }
 
-func (scanner Scanner) Substring(start, end int) string {
-	return string(scanner.chars[start:end])
+// Substring returns the characters between two indexes. The
+// characters must not have been released.
+func (scanner *Scanner) Substring(start, end int) string {
+	return string(scanner.chars[start-scanner.start : end-scanner.start])
+}
+
+func (scanner *Scanner) SubstringFrom(start int) string {
+	return scanner.Substring(start, scanner.index)
 }
 
-func (scanner Scanner) SubstringFrom(start int) string {
-	return string(scanner.chars[start:scanner.index])
+// Release lets the scanner discard the characters before the
+// current one (i.e. they will not be needed by Substring.)
+func (scanner *Scanner) Release() {
+	scanner.kept = scanner.index
 }
 
 // Advances the index to the next character.
 func (scanner *Scanner) Advance() {
 	if scanner.CanRead() {
-		scanner.index++
-		if scanner.CanRead() && scanner.Char() == '\n' {
+		if scanner.Char() == '\n' {
 			scanner.row++
 			scanner.col = 1
 		} else {
 			scanner.col++
 		}
+		scanner.index++
 	}
 }
 
 func (scanner *Scanner) CanRead() bool {
-	if scanner.length == 0 {
-		return false
-	}
-	return scanner.index < scanner.length
+	return scanner.fill(scanner.index)
 }
 
-func (scanner Scanner) Char() rune {
-	return scanner.chars[scanner.index]
+// Returns the current character (or zero if there is none.)
+func (scanner *Scanner) Char() rune {
+	return scanner.at(scanner.index)
 }
 
-func (scanner Scanner) CharString() string {
-	return string(scanner.chars[scanner.index])
+func (scanner *Scanner) CharString() string {
+	return string(scanner.Char())
 }
 
 func (scanner *Scanner) Peek() (bool, rune) {
-	if scanner.length > 0 && scanner.index < (scanner.length-1) {
-		return true, scanner.chars[scanner.index+1]
+	if scanner.fill(scanner.index + 1) {
+		return true, scanner.at(scanner.index + 1)
 	}
 	return false, 0
 }
 
-func (scanner Scanner) Col() int {
+func (scanner *Scanner) Col() int {
 	return scanner.col
 }
 
-func (scanner Scanner) Row() int {
+func (scanner *Scanner) Row() int {
 	return scanner.row
 }
 
-func (scanner Scanner) Position() string {
+func (scanner *Scanner) Position() string {
 	return fmt.Sprintf("(%d, %d)", scanner.row, scanner.col)
 }
 
-func stringToRunes(text string) []rune {
-	var chars []rune
-	for _, c := range text {
-		chars = append(chars, c)
+// Err returns the error (if any) reading the document. Reaching
+// the end of the document is not an error.
+func (scanner *Scanner) Err() error {
+	return scanner.err
+}
+
+// Returns the character at an index of the document (or zero if
+// there is none.)
+func (scanner *Scanner) at(index int) rune {
+	if index < scanner.start || !scanner.fill(index) {
+		return 0
+	}
+	return scanner.chars[index-scanner.start]
+}
+
+// Reads characters until the one at the index has been read.
+// Returns false if the document ends before.
+func (scanner *Scanner) fill(index int) bool {
+	for index >= scanner.start+len(scanner.chars) && !scanner.eof {
+		if len(scanner.chars) == cap(scanner.chars) {
+			scanner.discard()
+		}
+		char, _, err := scanner.reader.ReadRune()
+		if err != nil {
+			scanner.eof = true
+			if err != io.EOF {
+				scanner.err = err
+			}
+			break
+		}
+		scanner.chars = append(scanner.chars, char)
+	}
+	return index < scanner.start+len(scanner.chars)
+}
+
+// Discards the characters that were released (if there are enough
+// of them) to make room for new ones.
+func (scanner *Scanner) discard() {
+	released := scanner.kept - scanner.start
+	if released >= scannerMinDiscard && released >= len(scanner.chars)/2 {
+		count := copy(scanner.chars, scanner.chars[released:])
+		scanner.chars = scanner.chars[:count]
+		scanner.start = scanner.kept
 	}
-	return chars
 }
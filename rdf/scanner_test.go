@@ -1,6 +1,12 @@
 package rdf
 
-import "testing"
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
 
 func TestPeek(t *testing.T) {
 	scanner := NewScanner("abc")
@@ -16,3 +22,34 @@ func TestPeek(t *testing.T) {
 		t.Errorf("Failed to detect that it cannot peek anymore")
 	}
 }
+
+func TestReaderScanner(t *testing.T) {
+	// Long enough for the released characters to be discarded
+	text := strings.Repeat("abcdé", 2000)
+	scanner := NewReaderScanner(strings.NewReader(text))
+	for i := 0; scanner.CanRead(); i++ {
+		if i%5 == 0 {
+			scanner.Release()
+		}
+		start := scanner.Index()
+		scanner.Advance()
+		if i%5 == 4 && scanner.Substring(start-4, scanner.Index()) != "abcdé" {
+			t.Fatalf("Unexpected substring at %d: %s", i, scanner.Substring(start-4, scanner.Index()))
+		}
+	}
+
+	if scanner.Index() != 10000 || len(scanner.chars) > 2*scannerMinDiscard {
+		t.Errorf("Unexpected scanner state: index %d, %d characters kept", scanner.Index(), len(scanner.chars))
+	}
+}
+
+func TestReaderScannerError(t *testing.T) {
+	reader := io.MultiReader(strings.NewReader("abc"), iotest.ErrReader(errors.New("boom")))
+	scanner := NewReaderScanner(reader)
+	for scanner.CanRead() {
+		scanner.Advance()
+	}
+	if scanner.Index() != 3 || scanner.Err() == nil {
+		t.Errorf("Read error not detected")
+	}
+}
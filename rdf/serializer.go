@@ -0,0 +1,134 @@
+package rdf
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const (
+	NTriplesContentType = "application/n-triples"
+	JsonLdContentType   = "application/ld+json"
+	RdfXmlContentType   = "application/rdf+xml"
+)
+
+const (
+	// Profiles for application/ld+json
+	JsonLdExpandedProfile  = "http://www.w3.org/ns/json-ld#expanded"
+	JsonLdCompactedProfile = "http://www.w3.org/ns/json-ld#compacted"
+)
+
+// SerializationContentTypes are the media types in which a graph
+// can be serialized (see Serialize) in order of preference.
+var SerializationContentTypes = []string{TurtleContentType, JsonLdContentType,
+	NTriplesContentType, RdfXmlContentType}
+
+var UnsupportedContentTypeError = errors.New("Unsupported RDF content type")
+
+// Prefixes used when serializing to formats that support them.
+var knownPrefixes = []struct{ prefix, namespace string }{
+	{"rdf", "http://www.w3.org/1999/02/22-rdf-syntax-ns#"},
+	{"ldp", "http://www.w3.org/ns/ldp#"},
+	{"dcterms", "http://purl.org/dc/terms/"},
+	{"xsd", "http://www.w3.org/2001/XMLSchema#"},
+	{"ldpserver", "http://hectorcorrea.com/ldpserver/ns/"},
+}
+
+const xsdNamespace = "http://www.w3.org/2001/XMLSchema#"
+const xsdStringUri = xsdNamespace + "string"
+
+// Serialize returns the graph in the given media type. The profile
+// is only used for JSON-LD (JsonLdExpandedProfile or
+// JsonLdCompactedProfile) and defaults to the compacted form.
+func (graph RdfGraph) Serialize(contentType, profile string) (string, error) {
+	switch contentType {
+	case TurtleContentType:
+		var text strings.Builder
+		err := NewTurtleWriter(&text).Write(graph)
+		return text.String(), err
+	case NTriplesContentType:
+		return graph.NTriples(), nil
+	case JsonLdContentType:
+		return graph.JsonLd(profile != JsonLdExpandedProfile)
+	case RdfXmlContentType:
+		return graph.RdfXml()
+	}
+	return "", UnsupportedContentTypeError
+}
+
+// Literal returns the (escaped and quoted) literal for a string.
+func Literal(value string) string {
+	return "\"" + escapeNTriples(value) + "\""
+}
+
+// Splits a literal token (see Tokenizer) in the form "value"@lang
+// or "value"^^<type> into its (unescaped) value, language, and
+// datatype token (e.g. <type> or xsd:type.)
+func literalParts(term string) (string, string, string) {
+	end := 1
+	for end < len(term) && term[end] != '"' {
+		if term[end] == '\\' {
+			end++
+		}
+		end++
+	}
+	if end >= len(term) {
+		return unescapeLiteral(term[1:]), "", ""
+	}
+
+	value := unescapeLiteral(term[1:end])
+	suffix := term[end+1:]
+	switch {
+	case strings.HasPrefix(suffix, "@"):
+		return value, suffix[1:], ""
+	case strings.HasPrefix(suffix, "^^"):
+		return value, "", suffix[2:]
+	}
+	return value, "", ""
+}
+
+func unescapeLiteral(text string) string {
+	if !strings.Contains(text, "\\") {
+		return text
+	}
+
+	var value strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] != '\\' || i == len(text)-1 {
+			value.WriteByte(text[i])
+			continue
+		}
+		i++
+		switch text[i] {
+		case 't':
+			value.WriteByte('\t')
+		case 'n':
+			value.WriteByte('\n')
+		case 'r':
+			value.WriteByte('\r')
+		case 'b':
+			value.WriteByte('\b')
+		case 'f':
+			value.WriteByte('\f')
+		case 'u', 'U':
+			size := 4
+			if text[i] == 'U' {
+				size = 8
+			}
+			if i+size < len(text) {
+				code, err := strconv.ParseUint(text[i+1:i+1+size], 16, 32)
+				if err == nil {
+					value.WriteRune(rune(code))
+					i += size
+					continue
+				}
+			}
+			value.WriteByte('\\')
+			value.WriteByte(text[i])
+		default:
+			// \" \' and \\
+			value.WriteByte(text[i])
+		}
+	}
+	return value.String()
+}
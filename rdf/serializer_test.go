@@ -0,0 +1,137 @@
+package rdf
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+var serializerTestTriples = `<http://x/a> a <http://www.w3.org/ns/ldp#BasicContainer> .
+<http://x/a> <http://www.w3.org/ns/ldp#contains> <http://x/a/b> .
+<http://x/a> <http://purl.org/dc/terms/title> "hello \"world\""@en .
+<http://x/a> <http://x/ns#count> "3"^^<http://www.w3.org/2001/XMLSchema#integer> .
+<http://x/a/b> <http://x/ns#name> "b & c" .
+`
+
+func serializerTestGraph(t *testing.T) RdfGraph {
+	graph, err := StringToGraph(serializerTestTriples, "http://x/a")
+	if err != nil {
+		t.Fatalf("Error parsing test triples: %s", err)
+	}
+	return graph
+}
+
+func TestNTriples(t *testing.T) {
+	text := serializerTestGraph(t).NTriples()
+	expected := []string{
+		`<http://x/a> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://www.w3.org/ns/ldp#BasicContainer> .`,
+		`<http://x/a> <http://purl.org/dc/terms/title> "hello \"world\""@en .`,
+		`<http://x/a> <http://x/ns#count> "3"^^<http://www.w3.org/2001/XMLSchema#integer> .`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(text, line+"\n") {
+			t.Errorf("N-Triples line not found: %s\n%s", line, text)
+		}
+	}
+}
+
+func TestJsonLdExpanded(t *testing.T) {
+	text, err := serializerTestGraph(t).JsonLd(false)
+	if err != nil {
+		t.Fatalf("Error serializing JSON-LD: %s", err)
+	}
+
+	var nodes []map[string]interface{}
+	if err := json.Unmarshal([]byte(text), &nodes); err != nil || len(nodes) != 2 {
+		t.Fatalf("Invalid expanded JSON-LD: %s\n%s", err, text)
+	}
+
+	node := nodes[0]
+	if node["@id"] != "http://x/a" || node["@type"].([]interface{})[0] != LdpBasicContainerUri {
+		t.Errorf("Unexpected node in expanded JSON-LD: %v", node)
+	}
+
+	title := node["http://purl.org/dc/terms/title"].([]interface{})[0].(map[string]interface{})
+	if title["@value"] != `hello "world"` || title["@language"] != "en" {
+		t.Errorf("Unexpected literal in expanded JSON-LD: %v", title)
+	}
+}
+
+func TestJsonLdCompacted(t *testing.T) {
+	text, err := serializerTestGraph(t).JsonLd(true)
+	if err != nil {
+		t.Fatalf("Error serializing JSON-LD: %s", err)
+	}
+
+	var document map[string]interface{}
+	if err := json.Unmarshal([]byte(text), &document); err != nil {
+		t.Fatalf("Invalid compacted JSON-LD: %s\n%s", err, text)
+	}
+
+	context := document["@context"].(map[string]interface{})
+	if context["ldp"] != "http://www.w3.org/ns/ldp#" || context["dcterms"] != "http://purl.org/dc/terms/" {
+		t.Errorf("Unexpected context in compacted JSON-LD: %v", context)
+	}
+
+	node := document["@graph"].([]interface{})[0].(map[string]interface{})
+	if node["@type"] != "ldp:BasicContainer" {
+		t.Errorf("Unexpected type in compacted JSON-LD: %v", node)
+	}
+
+	contains := node["ldp:contains"].(map[string]interface{})
+	if contains["@id"] != "http://x/a/b" {
+		t.Errorf("Unexpected object in compacted JSON-LD: %v", node)
+	}
+
+	single, _ := NewGraph(NewTriple(NewIri("http://x/a"), NewIri("http://x/ns#name"), NewLiteral("a"))).JsonLd(true)
+	if !strings.Contains(single, `"http://x/ns#name": "a"`) || strings.Contains(single, "@graph") {
+		t.Errorf("Unexpected compacted JSON-LD for a single node: %s", single)
+	}
+}
+
+func TestRdfXml(t *testing.T) {
+	text, err := serializerTestGraph(t).RdfXml()
+	if err != nil {
+		t.Fatalf("Error serializing RDF/XML: %s", err)
+	}
+
+	var document struct {
+		Descriptions []struct {
+			About      string `xml:"about,attr"`
+			Properties []struct {
+				XMLName  xml.Name
+				Resource string `xml:"resource,attr"`
+				Value    string `xml:",chardata"`
+			} `xml:",any"`
+		} `xml:"Description"`
+	}
+	if err := xml.Unmarshal([]byte(text), &document); err != nil {
+		t.Fatalf("Invalid RDF/XML: %s\n%s", err, text)
+	}
+
+	if len(document.Descriptions) != 2 || document.Descriptions[0].About != "http://x/a" {
+		t.Fatalf("Unexpected descriptions in RDF/XML: %s", text)
+	}
+
+	property := document.Descriptions[1].Properties[0]
+	if property.XMLName.Space != "http://x/ns#" || property.XMLName.Local != "name" || property.Value != "b & c" {
+		t.Errorf("Unexpected property in RDF/XML: %v", property)
+	}
+
+	_, err = NewGraph(NewTriple(NewIri("http://x/a"), NewIri("http://x/123"), NewLiteral("a"))).RdfXml()
+	if err == nil {
+		t.Errorf("Invalid RDF/XML predicate not detected")
+	}
+}
+
+func TestLiteral(t *testing.T) {
+	literal := Literal("text/plain; profile=\"a\\b\"")
+	if literal != `"text/plain; profile=\"a\\b\""` {
+		t.Errorf("Unexpected literal: %s", literal)
+	}
+
+	if value := termOf(literal).Value(); value != "text/plain; profile=\"a\\b\"" {
+		t.Errorf("Unexpected literal value: %s", value)
+	}
+}
@@ -0,0 +1,247 @@
+// A basic SPARQL 1.1 Update parser
+// https://www.w3.org/TR/sparql11-update/
+//
+// Only the operations that make sense on the graph of a single
+// resource are supported:
+//
+//	INSERT DATA { triples }
+//	DELETE DATA { triples }
+//	DELETE { template } INSERT { template } WHERE { pattern }
+//	DELETE WHERE { pattern }
+//
+// Either the DELETE or the INSERT template of the third form can be
+// left out. Operations are separated with ";" and can be preceded by
+// PREFIX and BASE declarations. The triples are in Turtle syntax (see
+// TurtleParser) and the templates and patterns can have variables
+// (?x or $x.) Named graphs (GRAPH, WITH, USING), filters, optional
+// patterns, and property paths are not supported.
+//
+// Sample usage:
+//
+//	updates, err := ParseSparqlUpdate(`PREFIX dc: <http://purl.org/dc/terms/>
+//	    DELETE { <> dc:title ?title } INSERT { <> dc:title "new" }
+//	    WHERE { <> dc:title ?title }`, "http://localhost/node1")
+//	updated, changes, err := ApplyUpdates(graph, updates)
+package rdf
+
+import (
+	"strings"
+)
+
+type sparqlParser struct {
+	turtle TurtleParser
+}
+
+// ParseSparqlUpdate parses a SPARQL Update request resolving relative
+// IRIs against the base (e.g. <> becomes the URI of the node.)
+func ParseSparqlUpdate(text, base string) ([]Update, error) {
+	tokenizer := NewTokenizer(text)
+	tokenizer.sparql = true
+	parser := sparqlParser{turtle: newTurtleParser(tokenizer, base)}
+	return parser.parse()
+}
+
+func (parser *sparqlParser) parse() ([]Update, error) {
+	updates := []Update{}
+	for {
+		token, err := parser.nextToken()
+		if err != nil {
+			return nil, err
+		}
+		for strings.EqualFold(token, "PREFIX") || strings.EqualFold(token, "BASE") {
+			if err = parser.turtle.parseNextDirective(token); err != nil {
+				return nil, err
+			}
+			if token, err = parser.nextToken(); err != nil {
+				return nil, err
+			}
+		}
+		if token == "" {
+			return updates, nil
+		}
+
+		update, err := parser.parseOperation(token)
+		if err != nil {
+			return nil, err
+		}
+		updates = append(updates, update)
+
+		token, err = parser.nextToken()
+		if err != nil {
+			return nil, err
+		} else if token == "" {
+			return updates, nil
+		} else if token != ";" {
+			return nil, parser.error("Unexpected token after operation", token, ";")
+		}
+	}
+}
+
+func (parser *sparqlParser) parseOperation(token string) (Update, error) {
+	var update Update
+	var err error
+	switch strings.ToUpper(token) {
+	case "INSERT":
+		token, err = parser.nextToken()
+		if err != nil {
+			return Update{}, err
+		}
+		if strings.EqualFold(token, "DATA") {
+			update.Insert, err = parser.parseData("INSERT DATA")
+			return update, err
+		}
+		if token != "{" {
+			return Update{}, parser.error("Unexpected token", token, "DATA", "{")
+		}
+		update.Insert, err = parser.parseTriples()
+		if err != nil {
+			return Update{}, err
+		}
+		update.Where, err = parser.parseWhere()
+		return update, err
+	case "DELETE":
+		token, err = parser.nextToken()
+		if err != nil {
+			return Update{}, err
+		}
+		switch {
+		case strings.EqualFold(token, "DATA"):
+			update.Delete, err = parser.parseData("DELETE DATA")
+			if err == nil {
+				err = parser.checkNoBlankNodes(update.Delete, "DELETE DATA")
+			}
+			return update, err
+		case strings.EqualFold(token, "WHERE"):
+			if err = parser.expect("{"); err != nil {
+				return Update{}, err
+			}
+			update.Where, err = parser.parseTriples()
+			if err == nil {
+				err = parser.checkNoBlankNodes(update.Where, "DELETE WHERE")
+			}
+			update.Delete = update.Where
+			return update, err
+		case token != "{":
+			return Update{}, parser.error("Unexpected token", token, "DATA", "WHERE", "{")
+		}
+		update.Delete, err = parser.parseTriples()
+		if err == nil {
+			err = parser.checkNoBlankNodes(update.Delete, "DELETE")
+		}
+		if err != nil {
+			return Update{}, err
+		}
+		token, err = parser.nextToken()
+		if err != nil {
+			return Update{}, err
+		}
+		if strings.EqualFold(token, "INSERT") {
+			if err = parser.expect("{"); err != nil {
+				return Update{}, err
+			}
+			if update.Insert, err = parser.parseTriples(); err != nil {
+				return Update{}, err
+			}
+			token, err = parser.nextToken()
+			if err != nil {
+				return Update{}, err
+			}
+		}
+		if !strings.EqualFold(token, "WHERE") {
+			return Update{}, parser.error("Unexpected token", token, "INSERT", "WHERE")
+		}
+		if err = parser.expect("{"); err != nil {
+			return Update{}, err
+		}
+		update.Where, err = parser.parseWhereTriples()
+		return update, err
+	}
+	return Update{}, parser.error("Unsupported operation", token, "INSERT", "DELETE")
+}
+
+// Parses the triples of INSERT DATA and DELETE DATA, which cannot
+// have variables.
+func (parser *sparqlParser) parseData(operation string) ([]Triple, error) {
+	if err := parser.expect("{"); err != nil {
+		return nil, err
+	}
+	triples, err := parser.parseTriples()
+	if err != nil {
+		return nil, err
+	}
+	for _, triple := range triples {
+		if triple.subject.IsVariable() || triple.predicate.IsVariable() || triple.object.IsVariable() {
+			return nil, parser.error("Variables are not allowed in "+operation, triple.String())
+		}
+	}
+	return triples, nil
+}
+
+// Parses WHERE { pattern }
+func (parser *sparqlParser) parseWhere() ([]Triple, error) {
+	token, err := parser.nextToken()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(token, "WHERE") {
+		return nil, parser.error("Unexpected token", token, "WHERE")
+	}
+	if err = parser.expect("{"); err != nil {
+		return nil, err
+	}
+	return parser.parseWhereTriples()
+}
+
+// Parses the triples of a pattern. Blank nodes in patterns are
+// like variables (they match any term.)
+func (parser *sparqlParser) parseWhereTriples() ([]Triple, error) {
+	triples, err := parser.parseTriples()
+	if err != nil {
+		return nil, err
+	}
+	for i := range triples {
+		terms := []*Term{&triples[i].subject, &triples[i].predicate, &triples[i].object}
+		for _, term := range terms {
+			if term.IsBlankNode() {
+				// not a valid variable name so it cannot clash
+				*term = NewVariable("_:" + term.Value())
+			}
+		}
+	}
+	return triples, nil
+}
+
+// Parses the triples inside { } (the opening brace has been read.)
+func (parser *sparqlParser) parseTriples() ([]Triple, error) {
+	return parser.turtle.parseBlock()
+}
+
+// Blank nodes are not allowed in the triples to delete since they
+// could never match the ones in the graph.
+func (parser *sparqlParser) checkNoBlankNodes(triples []Triple, operation string) error {
+	for _, triple := range triples {
+		if triple.subject.IsBlankNode() || triple.predicate.IsBlankNode() || triple.object.IsBlankNode() {
+			return parser.error("Blank nodes are not allowed in "+operation, triple.String())
+		}
+	}
+	return nil
+}
+
+func (parser *sparqlParser) expect(expected string) error {
+	token, err := parser.nextToken()
+	if err != nil {
+		return err
+	}
+	if token != expected {
+		return parser.error("Unexpected token", token, expected)
+	}
+	return nil
+}
+
+func (parser *sparqlParser) nextToken() (string, error) {
+	return parser.turtle.tokenizer.GetNextToken()
+}
+
+func (parser *sparqlParser) error(message, token string, expected ...string) error {
+	return parser.turtle.error(message, token, expected...)
+}
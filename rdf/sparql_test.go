@@ -0,0 +1,118 @@
+package rdf
+
+import (
+	"testing"
+)
+
+var sparqlTestGraph = `@prefix dc: <http://purl.org/dc/terms/> .
+<http://x/node> dc:title "old" ;
+    dc:subject <http://x/a>, <http://x/b> .
+<http://x/a> dc:title "A" .
+`
+
+func applySparql(t *testing.T, update string) (RdfGraph, RdfGraph) {
+	graph, err := StringToGraph(sparqlTestGraph, "")
+	if err != nil {
+		t.Fatalf("Error parsing test graph: %s", err)
+	}
+	updates, err := ParseSparqlUpdate(update, "http://x/node")
+	if err != nil {
+		t.Fatalf("Error parsing SPARQL Update: %s\n%s", err, update)
+	}
+	updated, changes, err := ApplyUpdates(graph, updates)
+	if err != nil {
+		t.Fatalf("Error applying SPARQL Update: %s\n%s", err, update)
+	}
+	if graph.Len() != 4 {
+		t.Errorf("The original graph was changed: %s", graph)
+	}
+	return updated, changes
+}
+
+func TestSparqlInsertDeleteData(t *testing.T) {
+	node, title := NewIri("http://x/node"), NewIri("http://purl.org/dc/terms/title")
+	graph, changes := applySparql(t, `PREFIX dc: <http://purl.org/dc/terms/>
+		DELETE DATA { <> dc:title "old" } ;
+		INSERT DATA { <> dc:title "new"@en ; dc:creator [ dc:title "someone" ] . }`)
+	if graph.HasTriple(node, title, NewLiteral("old")) || !graph.HasTriple(node, title, NewLangLiteral("new", "en")) ||
+		graph.Len() != 6 || changes.Len() != 4 {
+		t.Errorf("Unexpected graph after update:\n%s", graph)
+	}
+}
+
+func TestSparqlDeleteInsertWhere(t *testing.T) {
+	title := NewIri("http://purl.org/dc/terms/title")
+	graph, changes := applySparql(t, `PREFIX dc: <http://purl.org/dc/terms/>
+		DELETE { ?s dc:title ?title }
+		INSERT { ?s dc:title "changed" ; dc:description [ dc:title ?title ] }
+		WHERE { <> dc:subject ?s . ?s dc:title ?title }`)
+	a := NewIri("http://x/a")
+	if graph.HasTriple(a, title, NewLiteral("A")) || !graph.HasTriple(a, title, NewLiteral("changed")) ||
+		len(graph.Match(Term{}, title, NewLiteral("A"))) != 1 || changes.Len() != 4 {
+		t.Errorf("Unexpected graph after update:\n%s", graph)
+	}
+
+	// No solutions, no changes
+	graph, changes = applySparql(t, `DELETE { ?s ?p ?o } WHERE { ?s ?p ?o ; <http://x/none> ?x }`)
+	if graph.Len() != 4 || changes.Len() != 0 {
+		t.Errorf("Unexpected graph after update without solutions:\n%s", graph)
+	}
+
+	graph, _ = applySparql(t, `DELETE WHERE { <http://x/node> <http://purl.org/dc/terms/subject> ?s }`)
+	if graph.Len() != 2 {
+		t.Errorf("Unexpected graph after DELETE WHERE:\n%s", graph)
+	}
+
+	graph, _ = applySparql(t, `INSERT { ?s a <http://x/Thing> } WHERE { ?s <http://purl.org/dc/terms/title> [] }`)
+	if len(graph.Match(Term{}, NewIri(RdfTypeUri), NewIri("http://x/Thing"))) != 2 {
+		t.Errorf("Unexpected graph after INSERT WHERE:\n%s", graph)
+	}
+}
+
+func TestSparqlUpdateErrors(t *testing.T) {
+	invalid := []string{
+		`INSERT DATA { ?s <p> <o> }`,
+		`DELETE DATA { _:b1 <p> <o> }`,
+		`DELETE { ?s <p> [] } WHERE { ?s <p> ?o }`,
+		`INSERT { ?s <p> <o> }`,
+		`INSERT DATA { <s> <p> <o> } INSERT DATA { <s> <p> <o2> }`,
+		`DELETE WHERE { ?s <p> ?o . FILTER(?o > 3) }`,
+		`WITH <g> DELETE { ?s <p> ?o } WHERE { ?s <p> ?o }`,
+		`INSERT DATA { GRAPH <g> { <s> <p> <o> } }`,
+		`INSERT DATA { <s> <p> <o>`,
+		`@prefix x: <http://x/> .`,
+	}
+	for _, test := range invalid {
+		if _, err := ParseSparqlUpdate(test, ""); err == nil {
+			t.Errorf("Invalid SPARQL Update not detected: %s", test)
+		} else if _, ok := err.(*ParseError); !ok {
+			t.Errorf("Unexpected error type for %s: %s", test, err)
+		}
+	}
+
+	if updates, err := ParseSparqlUpdate("PREFIX x: <http://x/>\n", ""); err != nil || len(updates) != 0 {
+		t.Errorf("Empty SPARQL Update not accepted: %s", err)
+	}
+}
+
+func TestSolve(t *testing.T) {
+	graph := NewGraph(
+		NewTriple(NewIri("a"), NewIri("p"), NewIri("a")),
+		NewTriple(NewIri("a"), NewIri("p"), NewIri("b")),
+		NewTriple(NewIri("b"), NewIri("p"), NewIri("c")))
+	x, y := NewVariable("x"), NewVariable("?y")
+
+	solutions := graph.Solve([]Triple{NewTriple(x, NewIri("p"), x)})
+	if len(solutions) != 1 || solutions[0][x] != NewIri("a") {
+		t.Errorf("Unexpected solutions: %v", solutions)
+	}
+
+	solutions = graph.Solve([]Triple{NewTriple(x, NewIri("p"), y), NewTriple(y, NewIri("p"), NewIri("c"))})
+	if len(solutions) != 1 || solutions[0][x] != NewIri("a") || solutions[0][y] != NewIri("b") {
+		t.Errorf("Unexpected solutions: %v", solutions)
+	}
+
+	if solutions = graph.Solve(nil); len(solutions) != 1 || len(solutions[0]) != 0 {
+		t.Errorf("Unexpected solutions for an empty pattern: %v", solutions)
+	}
+}
@@ -0,0 +1,182 @@
+package rdf
+
+import (
+	"errors"
+	"strings"
+)
+
+// TermKind indicates whether a Term is an IRI, a blank node,
+// or a literal. Variables are only used in patterns (see Update.)
+type TermKind int
+
+const (
+	IriKind TermKind = iota + 1
+	BlankNodeKind
+	LiteralKind
+	VariableKind
+)
+
+// A Term is the subject, predicate, or object of a triple. Terms
+// are values that can be compared with == (or used as map keys)
+// since literals are normalized when they are created: literals
+// of type xsd:string are the same as literals without a datatype.
+// The zero Term is not a valid term (see IsZero.)
+type Term struct {
+	kind     TermKind
+	value    string // the IRI, the blank node label, the lexical form, or the variable name
+	datatype string // only for typed literals
+	language string // only for language-tagged literals
+}
+
+// NewIri returns the term for an IRI (without the angle brackets.)
+func NewIri(iri string) Term {
+	return Term{kind: IriKind, value: iri}
+}
+
+// NewBlankNode returns the term for a blank node label. The _:
+// prefix is optional (i.e. _:b1 and b1 are the same blank node.)
+func NewBlankNode(label string) Term {
+	return Term{kind: BlankNodeKind, value: strings.TrimPrefix(label, "_:")}
+}
+
+// NewLiteral returns the term for a simple literal (i.e. of
+// type xsd:string) with the (unescaped) value.
+func NewLiteral(value string) Term {
+	return Term{kind: LiteralKind, value: value}
+}
+
+// NewLangLiteral returns the term for a literal with a language
+// tag (e.g. "hello"@en.)
+func NewLangLiteral(value, language string) Term {
+	return Term{kind: LiteralKind, value: value, language: language}
+}
+
+// NewTypedLiteral returns the term for a literal with a datatype
+// IRI (e.g. "3"^^xsd:integer.)
+func NewTypedLiteral(value, datatype string) Term {
+	if datatype == xsdStringUri {
+		datatype = ""
+	}
+	return Term{kind: LiteralKind, value: value, datatype: datatype}
+}
+
+// NewVariable returns the term for a variable in a pattern. The
+// ? prefix is optional (i.e. ?x and x are the same variable.)
+func NewVariable(name string) Term {
+	return Term{kind: VariableKind, value: strings.TrimPrefix(name, "?")}
+}
+
+// ParseTerm parses a single term in N-Triples or Turtle syntax
+// (e.g. <http://x/y>, _:b1, "hello"@en.) Prefixed names are not
+// valid since there are no prefixes declared.
+func ParseTerm(text string) (Term, error) {
+	parser := NewTurtleParser(text)
+	parser.KeepBlankLabels()
+	token, err := parser.tokenizer.GetNextToken()
+	if err != nil {
+		return Term{}, err
+	}
+	if token == "" || isPunctuation(token) {
+		return Term{}, errors.New("Invalid term (" + text + ")")
+	}
+	parser.tokenizer.AdvanceWhiteSpace()
+	if parser.tokenizer.CanRead() {
+		return Term{}, errors.New("Unexpected text after term (" + text + ")")
+	}
+	return parser.term(token)
+}
+
+func (term Term) Kind() TermKind {
+	return term.kind
+}
+
+func (term Term) IsIri() bool {
+	return term.kind == IriKind
+}
+
+func (term Term) IsBlankNode() bool {
+	return term.kind == BlankNodeKind
+}
+
+func (term Term) IsLiteral() bool {
+	return term.kind == LiteralKind
+}
+
+func (term Term) IsVariable() bool {
+	return term.kind == VariableKind
+}
+
+// IsZero returns true for the zero Term (i.e. no term at all.)
+func (term Term) IsZero() bool {
+	return term.kind == 0
+}
+
+// Value returns the IRI of an IRI, the label (without _:) of a
+// blank node, or the lexical form (unescaped) of a literal.
+func (term Term) Value() string {
+	return term.value
+}
+
+// Datatype returns the datatype IRI of a literal, which is
+// xsd:string for simple literals and rdf:langString for literals
+// with a language tag. It is empty for IRIs and blank nodes.
+func (term Term) Datatype() string {
+	switch {
+	case term.kind != LiteralKind:
+		return ""
+	case term.language != "":
+		return RdfLangStringUri
+	case term.datatype == "":
+		return xsdStringUri
+	}
+	return term.datatype
+}
+
+// Language returns the language tag of a literal (if any.)
+func (term Term) Language() string {
+	return term.language
+}
+
+func (term Term) Equal(other Term) bool {
+	return term == other
+}
+
+// Compare orders terms by kind (IRIs, blank nodes, and literals)
+// and then by value. Returns -1, 0, or +1 like strings.Compare.
+func (term Term) Compare(other Term) int {
+	switch {
+	case term.kind < other.kind:
+		return -1
+	case term.kind > other.kind:
+		return 1
+	}
+	if result := strings.Compare(term.value, other.value); result != 0 {
+		return result
+	}
+	if result := strings.Compare(term.datatype, other.datatype); result != 0 {
+		return result
+	}
+	return strings.Compare(term.language, other.language)
+}
+
+// String returns the term in N-Triples syntax (variables as ?name.)
+func (term Term) String() string {
+	switch term.kind {
+	case IriKind:
+		return "<" + term.value + ">"
+	case BlankNodeKind:
+		return "_:" + term.value
+	case VariableKind:
+		return "?" + term.value
+	case LiteralKind:
+		literal := Literal(term.value)
+		if term.language != "" {
+			return literal + "@" + term.language
+		}
+		if term.datatype != "" {
+			return literal + "^^<" + term.datatype + ">"
+		}
+		return literal
+	}
+	return ""
+}
@@ -0,0 +1,81 @@
+package rdf
+
+import "testing"
+
+// Returns the term for its N-Triples text (e.g. <s> or "o"@en)
+func termOf(text string) Term {
+	term, err := ParseTerm(text)
+	if err != nil {
+		panic(err)
+	}
+	return term
+}
+
+func TestTermString(t *testing.T) {
+	tests := map[string]Term{
+		"<http://x/a>":            NewIri("http://x/a"),
+		"_:b1":                    NewBlankNode("_:b1"),
+		`"a \"b\""`:               NewLiteral(`a "b"`),
+		`"hello"@en`:              NewLangLiteral("hello", "en"),
+		`"3"^^<http://x/integer>`: NewTypedLiteral("3", "http://x/integer"),
+		`"s"`:                     NewTypedLiteral("s", xsdStringUri),
+	}
+	for expected, term := range tests {
+		if term.String() != expected {
+			t.Errorf("Unexpected string for term. Expected %s, got %s", expected, term)
+		}
+	}
+
+	if NewBlankNode("b1") != NewBlankNode("_:b1") {
+		t.Errorf("Blank node labels with and without _: are different")
+	}
+
+	if (Term{}).String() != "" || !(Term{}).IsZero() {
+		t.Errorf("Unexpected zero term")
+	}
+}
+
+func TestTermValues(t *testing.T) {
+	literal := NewLangLiteral("hello", "en")
+	if !literal.IsLiteral() || literal.Value() != "hello" || literal.Language() != "en" ||
+		literal.Datatype() != RdfLangStringUri {
+		t.Errorf("Unexpected language literal: %v", literal)
+	}
+
+	if NewLiteral("a").Datatype() != xsdStringUri || NewIri("http://x/a").Datatype() != "" {
+		t.Errorf("Unexpected datatypes")
+	}
+
+	if !NewLiteral("a").Equal(NewTypedLiteral("a", xsdStringUri)) || NewLiteral("a").Equal(NewIri("a")) {
+		t.Errorf("Literals of type xsd:string are not the same as simple literals")
+	}
+
+	if NewIri("z").Compare(NewBlankNode("a")) != -1 || NewLiteral("a").Compare(NewLiteral("b")) != -1 ||
+		NewLiteral("a").Compare(NewLiteral("a")) != 0 {
+		t.Errorf("Unexpected order of terms")
+	}
+}
+
+func TestParseTerm(t *testing.T) {
+	tests := map[string]Term{
+		"<http://x/a>":                NewIri("http://x/a"),
+		" _:b1 ":                      NewBlankNode("b1"),
+		`"a\tb"`:                      NewLiteral("a\tb"),
+		`'hello'@en-us`:               NewLangLiteral("hello", "en-us"),
+		`"3"^^<http://x/i>`:           NewTypedLiteral("3", "http://x/i"),
+		"12":                          NewTypedLiteral("12", xsdNamespace+"integer"),
+		`"v"^^<` + xsdStringUri + ">": NewLiteral("v"),
+	}
+	for text, expected := range tests {
+		if term, err := ParseTerm(text); err != nil || term != expected {
+			t.Errorf("Error parsing term %s. Expected %s, got %s (%s)", text, expected, term, err)
+		}
+	}
+
+	invalid := []string{"", ".", "<a> <b>", `"unterminated`, "xx:version", "version"}
+	for _, text := range invalid {
+		if _, err := ParseTerm(text); err == nil {
+			t.Errorf("Invalid term not detected: %s", text)
+		}
+	}
+}
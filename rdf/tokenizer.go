@@ -1,45 +1,76 @@
 package rdf
 
 import (
-	"errors"
-	"fmt"
+	"io"
 	// "log"
+	"regexp"
+	"strings"
+	"unicode"
 )
 
 type Tokenizer struct {
-	scanner Scanner
+	scanner  *Scanner
+	tokenRow int // position of the last token
+	tokenCol int
+	sparql   bool // accept variables (?x) and braces as in SPARQL
+	ldpatch  bool // accept paths (/ ^ ! =) and slices (..) as in LD Patch
 }
 
 func NewTokenizer(text string) Tokenizer {
 	return Tokenizer{scanner: NewScanner(text)}
 }
 
+// NewReaderTokenizer returns a tokenizer that reads the text from
+// the reader as the tokens are requested.
+func NewReaderTokenizer(reader io.Reader) Tokenizer {
+	return Tokenizer{scanner: NewReaderScanner(reader)}
+}
+
 func (tokenizer *Tokenizer) GetNextToken() (string, error) {
 	var err error
 	var value string
 
 	tokenizer.AdvanceWhiteSpace()
 	tokenizer.AdvanceComments()
+	tokenizer.tokenRow, tokenizer.tokenCol = tokenizer.scanner.Row(), tokenizer.scanner.Col()
 	if !tokenizer.scanner.CanRead() {
-		return "", nil
+		return "", tokenizer.scanner.Err()
 	}
+	// Nothing before the token is needed anymore
+	tokenizer.scanner.Release()
 
 	firstChar := tokenizer.scanner.Char()
 	switch {
+	case tokenizer.ldpatch && firstChar == '.' && tokenizer.charAt(1) == '.':
+		tokenizer.scanner.Advance()
+		value = ".."
+	case tokenizer.ldpatch && strings.ContainsRune("/^!=", firstChar):
+		value = string(firstChar)
+	case tokenizer.isNumberStart():
+		value, err = tokenizer.parseNumber()
 	case firstChar == '.':
 		value = "."
 	case firstChar == ',':
 		value = ","
 	case firstChar == ';':
 		value = ";"
+	case strings.ContainsRune("[]()", firstChar):
+		value = string(firstChar)
+	case tokenizer.sparql && (firstChar == '{' || firstChar == '}'):
+		value = string(firstChar)
+	case tokenizer.sparql && (firstChar == '?' || firstChar == '$'):
+		value, err = tokenizer.parseVariable()
 	case firstChar == '@':
 		value, err = tokenizer.parseDirective()
 	case firstChar == '<':
 		value, err = tokenizer.parseUri()
-	case firstChar == '"':
+	case firstChar == '"' || firstChar == '\'':
 		value, err = tokenizer.parseString()
 	case tokenizer.isNamespacedChar():
 		value = tokenizer.parseNamespacedValue()
+		if value == "true" || value == "false" {
+			value = "\"" + value + "\"^^<" + xsdNamespace + "boolean>"
+		}
 	default:
 		return "", tokenizer.Error("Invalid first character")
 	}
@@ -62,7 +93,7 @@ func (tokenizer *Tokenizer) AdvanceTriple() error {
 			tokenizer.scanner.Advance()
 			continue
 		}
-		return tokenizer.Error("Triple did not end with a period.")
+		return tokenizer.Error("Triple did not end with a period", ".")
 	}
 	tokenizer.scanner.Advance()
 	return nil
@@ -78,6 +109,7 @@ func (tokenizer *Tokenizer) AdvanceWhiteSpace() {
 			break
 		}
 		tokenizer.scanner.Advance()
+		tokenizer.scanner.Release()
 	}
 }
 
@@ -94,41 +126,44 @@ func (tokenizer *Tokenizer) AdvanceComments() {
 			}
 		}
 		tokenizer.scanner.Advance()
+		tokenizer.scanner.Release()
 	}
 }
 
-func (tokenizer Tokenizer) isLanguageChar() bool {
-	char := tokenizer.scanner.Char()
+var languageRegex = regexp.MustCompile(`^@[a-zA-Z]+(-[a-zA-Z0-9]+)*$`)
+
+func isLanguageRune(char rune) bool {
 	return (char >= 'a' && char <= 'z') ||
 		(char >= 'A' && char <= 'Z') ||
+		(char >= '0' && char <= '9') ||
 		(char == '-')
 }
 
-func (tokenizer Tokenizer) isDirectiveChar() bool {
-	char := tokenizer.scanner.Char()
+func isDirectiveRune(char rune) bool {
 	return (char >= 'a' && char <= 'z') ||
-		(char >= 'A' && char <= 'Z') ||
-		(char == '@')
+		(char >= 'A' && char <= 'Z')
 }
 
 func (tokenizer Tokenizer) isNamespacedChar() bool {
-	char := tokenizer.scanner.Char()
+	return isNamespacedRune(tokenizer.scanner.Char())
+}
+
+// Characters allowed in prefixed names (e.g. dc:title) as well
+// as in bare words like "a" or "true".
+func isNamespacedRune(char rune) bool {
 	return (char >= 'a' && char <= 'z') ||
 		(char >= 'A' && char <= 'Z') ||
 		(char >= '0' && char <= '9') ||
-		(char == ':') ||
-		(char == '_')
+		(char > 0x7F && (unicode.IsLetter(char) || unicode.IsDigit(char))) ||
+		strings.ContainsRune(":_-.%", char)
 }
 
+// Any character but the ones excluded by IRIREF in the Turtle
+// grammar. We need to accept the same URIs that other formats
+// (e.g. JSON-LD) accept since we save the triples as Turtle.
 func (tokenizer Tokenizer) isUriChar() bool {
 	char := tokenizer.scanner.Char()
-	return (char >= 'a' && char <= 'z') ||
-		(char >= 'A' && char <= 'Z') ||
-		(char >= '0' && char <= '9') ||
-		(char == ':') || (char == '/') ||
-		(char == '%') || (char == '#') ||
-		(char == '+') || (char == '-') ||
-		(char == '.') || (char == '_')
+	return char > ' ' && !strings.ContainsRune("<>\"{}|^`\\", char)
 }
 
 func (tokenizer Tokenizer) isWhiteSpaceChar() bool {
@@ -136,106 +171,235 @@ func (tokenizer Tokenizer) isWhiteSpaceChar() bool {
 	return char == ' ' || char == '\t' || char == '\n' || char == '\r'
 }
 
-// Extracts a value in the form xx:yy or xx
+// Extracts a value in the form xx:yy or xx. Periods are allowed
+// inside the value but not at the end (where they end the triple.)
 func (tokenizer *Tokenizer) parseNamespacedValue() string {
 	start := tokenizer.scanner.Index()
-	tokenizer.scanner.Advance()
-	for tokenizer.CanRead() {
-		if tokenizer.isNamespacedChar() {
-			tokenizer.scanner.Advance()
-			continue
-		} else {
-			break
-		}
+	end := start + 1
+	for isNamespacedRune(tokenizer.scanner.at(end)) {
+		end++
 	}
-	return tokenizer.scanner.SubstringFrom(start)
+	for end > start+1 && tokenizer.scanner.at(end-1) == '.' {
+		end--
+	}
+	for tokenizer.scanner.Index() < end-1 {
+		tokenizer.scanner.Advance()
+	}
+	return tokenizer.scanner.Substring(start, end)
 }
 
-func (tokenizer *Tokenizer) parseLanguage() string {
+// Extracts a variable in the form ?x or $x (returned as ?x)
+func (tokenizer *Tokenizer) parseVariable() (string, error) {
 	start := tokenizer.scanner.Index()
-	tokenizer.scanner.Advance()
-	for tokenizer.CanRead() {
-		if tokenizer.isLanguageChar() {
-			tokenizer.scanner.Advance()
-		} else {
-			break
-		}
+	tokenizer.advanceWhile(isVariableRune)
+	if tokenizer.scanner.Index() == start {
+		return "", tokenizer.Error("Empty variable name")
 	}
-	// Should be indicate error if the language is empty?
-	return tokenizer.scanner.SubstringFrom(start)
+	return "?" + tokenizer.scanner.Substring(start+1, tokenizer.scanner.Index()+1), nil
 }
 
-// Extracts a value in the form @hello
-func (tokenizer *Tokenizer) parseDirective() (string, error) {
+func isVariableRune(char rune) bool {
+	return (char >= 'a' && char <= 'z') ||
+		(char >= 'A' && char <= 'Z') ||
+		(char >= '0' && char <= '9') ||
+		(char > 0x7F && (unicode.IsLetter(char) || unicode.IsDigit(char))) ||
+		char == '_'
+}
+
+// Extracts a language tag in the form @en or @en-us
+func (tokenizer *Tokenizer) parseLanguage() (string, error) {
 	start := tokenizer.scanner.Index()
-	tokenizer.scanner.Advance()
-	for tokenizer.CanRead() {
-		if tokenizer.isDirectiveChar() {
-			tokenizer.scanner.Advance()
-		} else {
-			break
-		}
+	tokenizer.advanceWhile(isLanguageRune)
+	lang := tokenizer.scanner.Substring(start, tokenizer.scanner.Index()+1)
+	if !languageRegex.MatchString(lang) {
+		return "", tokenizer.Error("Invalid language tag (" + lang + ")")
 	}
+	return lang, nil
+}
 
-	directive := tokenizer.scanner.SubstringFrom(start)
-	if directive == "" {
+// Extracts a value in the form @hello
+func (tokenizer *Tokenizer) parseDirective() (string, error) {
+	start := tokenizer.scanner.Index()
+	tokenizer.advanceWhile(isDirectiveRune)
+	directive := tokenizer.scanner.Substring(start, tokenizer.scanner.Index()+1)
+	if directive == "@" {
 		return "", tokenizer.Error("Empty directive detected")
 	}
 
 	return directive, nil
 }
 
-// Extracts a value in quotes, for example
-//		"hello"
-//      "hello \"world\""
-// 		"hello"@en-us
-//		"hello"^^<http://somedomain>
+// Advances the index while the next character matches. The
+// index is left on the last matching character.
+func (tokenizer *Tokenizer) advanceWhile(matches func(rune) bool) {
+	for {
+		canPeek, nextChar := tokenizer.scanner.Peek()
+		if !canPeek || !matches(nextChar) {
+			break
+		}
+		tokenizer.scanner.Advance()
+	}
+}
+
+// Returns the character at an offset from the current one
+// (or zero if there is none.)
+func (tokenizer Tokenizer) charAt(offset int) rune {
+	return tokenizer.scanner.at(tokenizer.scanner.Index() + offset)
+}
+
+// Extracts a string in single, double, or triple quotes with its
+// optional language or datatype, for example
+//
+//	"hello"
+//	'hello "world"'
+//	"""hello
+//	world"""@en-us
+//	"hello"^^<http://somedomain>
+//	"hello"^^xsd:string
+//
+// Escape sequences (e.g. \n or \u00E9) are decoded and the string
+// is returned in double quotes with only the characters that
+// require it escaped (see Literal.)
 func (tokenizer *Tokenizer) parseString() (string, error) {
-	start := tokenizer.scanner.Index()
-	lastChar := tokenizer.scanner.Char()
+	quote := tokenizer.scanner.Char()
+	long := tokenizer.charAt(1) == quote && tokenizer.charAt(2) == quote
+	if long {
+		tokenizer.scanner.Advance()
+		tokenizer.scanner.Advance()
+	}
+
+	var value strings.Builder
 	tokenizer.scanner.Advance()
 	for tokenizer.CanRead() {
-		if tokenizer.scanner.Char() == '"' {
-			if lastChar == '\\' {
-				lastChar = tokenizer.scanner.Char()
-				tokenizer.scanner.Advance()
-				continue
+		// The value is kept in the builder, the scanner
+		// does not need to keep the characters (long strings
+		// would otherwise be kept twice.)
+		tokenizer.scanner.Release()
+		char := tokenizer.scanner.Char()
+		switch {
+		case char == '\\':
+			tokenizer.scanner.Advance()
+			decoded, err := tokenizer.parseEscape()
+			if err != nil {
+				return "", err
 			}
-			str := tokenizer.scanner.Substring(start, tokenizer.scanner.Index()+1)
-			lang := ""
-			datatype := ""
-			canPeek, nextChar := tokenizer.scanner.Peek()
-			var err error
-			if canPeek {
-				switch nextChar {
-				case '@':
-					tokenizer.scanner.Advance()
-					lang = tokenizer.parseLanguage()
-					str += lang
-				case '^':
-					tokenizer.scanner.Advance()
-					datatype, err = tokenizer.parseType()
-					str += datatype
-				}
+			value.WriteRune(decoded)
+		case char == quote && !long:
+			return tokenizer.parseLiteralSuffix(Literal(value.String()))
+		case char == quote && tokenizer.charAt(1) == quote && tokenizer.charAt(2) == quote:
+			tokenizer.scanner.Advance()
+			tokenizer.scanner.Advance()
+			return tokenizer.parseLiteralSuffix(Literal(value.String()))
+		case !long && (char == '\n' || char == '\r'):
+			return "", tokenizer.Error("Line break in string")
+		default:
+			value.WriteRune(char)
+		}
+		tokenizer.scanner.Advance()
+	}
+	return "", tokenizer.Error("String did not end with "+string(quote), string(quote))
+}
+
+// Decodes the escape sequence that starts at the current character
+// (i.e. the one after the backslash.) The index is left on the last
+// character of the sequence.
+func (tokenizer *Tokenizer) parseEscape() (rune, error) {
+	if !tokenizer.CanRead() {
+		return 0, tokenizer.Error("Invalid escape sequence")
+	}
+
+	char := tokenizer.scanner.Char()
+	switch char {
+	case 't':
+		return '\t', nil
+	case 'b':
+		return '\b', nil
+	case 'n':
+		return '\n', nil
+	case 'r':
+		return '\r', nil
+	case 'f':
+		return '\f', nil
+	case '"', '\'', '\\':
+		return char, nil
+	case 'u', 'U':
+		size := 4
+		if char == 'U' {
+			size = 8
+		}
+		code := 0
+		for i := 1; i <= size; i++ {
+			digit := hexValue(tokenizer.charAt(i))
+			if digit == -1 {
+				return 0, tokenizer.Error("Invalid unicode escape sequence")
 			}
-			return str, err
+			code = code*16 + digit
+		}
+		if code > unicode.MaxRune {
+			return 0, tokenizer.Error("Invalid unicode escape sequence")
+		}
+		for i := 0; i < size; i++ {
+			tokenizer.scanner.Advance()
 		}
-		lastChar = tokenizer.scanner.Char()
+		return rune(code), nil
+	}
+	return 0, tokenizer.Error("Invalid escape sequence")
+}
+
+func hexValue(char rune) int {
+	switch {
+	case char >= '0' && char <= '9':
+		return int(char - '0')
+	case char >= 'a' && char <= 'f':
+		return int(char-'a') + 10
+	case char >= 'A' && char <= 'F':
+		return int(char-'A') + 10
+	}
+	return -1
+}
+
+// Adds the language or datatype (if any) that follows the
+// closing quote of a string.
+func (tokenizer *Tokenizer) parseLiteralSuffix(literal string) (string, error) {
+	canPeek, nextChar := tokenizer.scanner.Peek()
+	if !canPeek {
+		return literal, nil
+	}
+
+	switch nextChar {
+	case '@':
 		tokenizer.scanner.Advance()
+		lang, err := tokenizer.parseLanguage()
+		return literal + lang, err
+	case '^':
+		tokenizer.scanner.Advance()
+		datatype, err := tokenizer.parseType()
+		return literal + datatype, err
 	}
-	return "", tokenizer.Error("String did not end with \"")
+	return literal, nil
 }
 
+// Extracts the datatype of a literal in the form ^^<hello>
+// or ^^xsd:hello
 func (tokenizer *Tokenizer) parseType() (string, error) {
 	canPeek, nextChar := tokenizer.scanner.Peek()
 	if !canPeek || nextChar != '^' {
-		return "", tokenizer.Error("Invalid type delimiter")
+		return "", tokenizer.Error("Invalid type delimiter", "^^")
 	}
 
 	tokenizer.scanner.Advance()
 	canPeek, nextChar = tokenizer.scanner.Peek()
+	if canPeek && isNamespacedRune(nextChar) && nextChar != '.' {
+		tokenizer.scanner.Advance()
+		name := tokenizer.parseNamespacedValue()
+		if !strings.Contains(name, ":") || tokenizer.charAt(1) == '/' {
+			return "", tokenizer.Error("Invalid prefixed name in type delimiter")
+		}
+		return "^^" + name, nil
+	}
+
 	if !canPeek || nextChar != '<' {
-		return "", tokenizer.Error("Invalid URI in type delimiter")
+		return "", tokenizer.Error("Invalid URI in type delimiter", "IRI", "prefixed name")
 	}
 
 	tokenizer.scanner.Advance()
@@ -243,6 +407,64 @@ func (tokenizer *Tokenizer) parseType() (string, error) {
 	return "^^" + uri, err
 }
 
+// Extracts a number in the form 42, -3.14, or 1e5 and returns it as
+// a literal of type xsd:integer, xsd:decimal, or xsd:double.
+func (tokenizer *Tokenizer) parseNumber() (string, error) {
+	start := tokenizer.scanner.Index()
+	tokenizer.advanceWhile(isDigit)
+	if tokenizer.charAt(1) == '.' && isDigit(tokenizer.charAt(2)) {
+		tokenizer.scanner.Advance()
+		tokenizer.advanceWhile(isDigit)
+	}
+
+	next := tokenizer.charAt(1)
+	if next == 'e' || next == 'E' {
+		offset := 2
+		if tokenizer.charAt(offset) == '+' || tokenizer.charAt(offset) == '-' {
+			offset++
+		}
+		if isDigit(tokenizer.charAt(offset)) {
+			for i := 1; i < offset; i++ {
+				tokenizer.scanner.Advance()
+			}
+			tokenizer.advanceWhile(isDigit)
+		}
+	}
+
+	next = tokenizer.charAt(1)
+	if isNamespacedRune(next) && next != '.' {
+		return "", tokenizer.Error("Invalid number")
+	}
+
+	number := tokenizer.scanner.Substring(start, tokenizer.scanner.Index()+1)
+	datatype := "integer"
+	if strings.ContainsAny(number, "eE") {
+		datatype = "double"
+	} else if strings.Contains(number, ".") {
+		datatype = "decimal"
+	}
+	return "\"" + number + "\"^^<" + xsdNamespace + datatype + ">", nil
+}
+
+// Numbers start with a digit, a sign, or a period followed by a digit.
+func (tokenizer Tokenizer) isNumberStart() bool {
+	char := tokenizer.scanner.Char()
+	next := tokenizer.charAt(1)
+	switch {
+	case isDigit(char):
+		return true
+	case char == '+' || char == '-':
+		return isDigit(next) || (next == '.' && isDigit(tokenizer.charAt(2)))
+	case char == '.':
+		return isDigit(next)
+	}
+	return false
+}
+
+func isDigit(char rune) bool {
+	return char >= '0' && char <= '9'
+}
+
 // Extracts an URI in the form <hello>
 func (tokenizer *Tokenizer) parseUri() (string, error) {
 	start := tokenizer.scanner.Index()
@@ -257,14 +479,15 @@ func (tokenizer *Tokenizer) parseUri() (string, error) {
 		}
 		tokenizer.scanner.Advance()
 	}
-	return "", tokenizer.Error("URI did not end with >")
+	return "", tokenizer.Error("URI did not end with >", ">")
 }
 
-func (tokenizer *Tokenizer) Error(message string) error {
+// Error returns a ParseError for the current character.
+func (tokenizer *Tokenizer) Error(message string, expected ...string) error {
 	lastChar := ""
 	if tokenizer.CanRead() {
 		lastChar = tokenizer.scanner.CharString()
 	}
-	errorMsg := fmt.Sprintf("%s. Character (%s) at %s.", message, lastChar, tokenizer.scanner.Position())
-	return errors.New(errorMsg)
+	return &ParseError{Message: message, Line: tokenizer.scanner.Row(), Column: tokenizer.scanner.Col(),
+		Token: lastChar, Expected: expected}
 }
@@ -45,13 +45,13 @@ func TestGoodLanguage(t *testing.T) {
 }
 
 func TestBadLanguage(t *testing.T) {
-	test := "\"hello\"@/en-us"
-	tokenizer := NewTokenizer(test)
-	token, err := tokenizer.GetNextToken()
-	if err != nil {
-		t.Errorf("Error parsing token with bad language: (%s). Error: %s.", test, err)
-	} else if token != "\"hello\"@" {
-		t.Errorf("Token with bad language (%s) parsed incorrectly (%s)", test, token)
+	tests := []string{"\"hello\"@/en-us", "\"hello\"@en-", "\"hello\"@1en"}
+	for _, test := range tests {
+		tokenizer := NewTokenizer(test)
+		token, err := tokenizer.GetNextToken()
+		if err == nil {
+			t.Errorf("Did not detect bad language: (%s). Result: (%s)", test, token)
+		}
 	}
 }
 
@@ -79,3 +79,51 @@ func TestBadType(t *testing.T) {
 		}
 	}
 }
+
+func TestUriWithQueryString(t *testing.T) {
+	tokenizer := NewTokenizer("<http://x/a?b=c&d=e~f>")
+	token, err := tokenizer.GetNextToken()
+	if err != nil || token != "<http://x/a?b=c&d=e~f>" {
+		t.Errorf("URI with query string not parsed: %s %s", token, err)
+	}
+}
+
+func TestLiterals(t *testing.T) {
+	xsd := "^^<http://www.w3.org/2001/XMLSchema#"
+	tests := [][]string{
+		{`'hello "world"'`, `"hello \"world\""`},
+		{`"""line one
+line "two" ""` + `"`, `"line one\nline \"two\" "`},
+		{`''` + `'it's'` + `''`, `"it's"`},
+		{`"tab\there \u00E9\U0001F600 \'"@en-US`, "\"tab\there \u00E9\U0001F600 '\"@en-US"},
+		{`"a\\"`, `"a\\"`},
+		{`"1"^^xsd:integer`, `"1"^^xsd:integer`},
+		{"42", `"42"` + xsd + `integer>`},
+		{"-5", `"-5"` + xsd + `integer>`},
+		{"+3.14", `"+3.14"` + xsd + `decimal>`},
+		{".5", `".5"` + xsd + `decimal>`},
+		{"1e5", `"1e5"` + xsd + `double>`},
+		{"-1.5E-3", `"-1.5E-3"` + xsd + `double>`},
+		{"true", `"true"` + xsd + `boolean>`},
+		{"false", `"false"` + xsd + `boolean>`},
+		{"42.", `"42"` + xsd + `integer>`},
+	}
+	for _, test := range tests {
+		tokenizer := NewTokenizer(test[0])
+		token, err := tokenizer.GetNextToken()
+		if err != nil {
+			t.Errorf("Error parsing literal: (%s). Error: %s.", test[0], err)
+		} else if token != test[1] {
+			t.Errorf("Literal (%s) parsed incorrectly (%s)", test[0], token)
+		}
+	}
+
+	invalid := []string{`"line
+break"`, `'unterminated`, `"""unterminated""`, `"\x"`, `"\u12"`, "12abc", "+"}
+	for _, test := range invalid {
+		tokenizer := NewTokenizer(test)
+		if token, err := tokenizer.GetNextToken(); err == nil {
+			t.Errorf("Did not detect invalid literal: (%s). Result: (%s)", test, token)
+		}
+	}
+}
@@ -3,12 +3,12 @@ package rdf
 import "fmt"
 
 type Triple struct {
-	subject   string
-	predicate string
-	object    string
+	subject   Term
+	predicate Term
+	object    Term
 }
 
-func NewTriple(subject, predicate, object string) Triple {
+func NewTriple(subject, predicate, object Term) Triple {
 	return Triple{subject: subject, predicate: predicate, object: object}
 }
 
@@ -20,39 +20,45 @@ func (t Triple) StringLn() string {
 	return fmt.Sprintf("%s %s %s .\n", t.subject, t.predicate, t.object)
 }
 
-func (t Triple) Predicate() string {
+func (t Triple) Subject() Term {
+	return t.subject
+}
+
+func (t Triple) Predicate() Term {
 	return t.predicate
 }
 
-func (t Triple) Object() string {
+func (t Triple) Object() Term {
 	return t.object
 }
 
+// Is returns true if the predicate of the triple is the IRI.
 func (t Triple) Is(predicate string) bool {
-	return t.predicate == predicate
+	return t.predicate == NewIri(predicate)
 }
 
-func (triple *Triple) ReplaceBlankUri(blank string) {
-	if triple.subject == "<>" {
+// ReplaceBlankUri replaces the empty IRI (i.e. <> parsed without
+// a base) with the given term.
+func (triple *Triple) ReplaceBlankUri(blank Term) {
+	empty := NewIri("")
+	if triple.subject == empty {
 		triple.subject = blank
 	}
-	if triple.predicate == "<>" {
+	if triple.predicate == empty {
 		triple.predicate = blank
 	}
-	if triple.object == "<>" {
+	if triple.object == empty {
 		triple.object = blank
 	}
 }
 
-func StringToTriples(text, blank string) ([]Triple, error) {
-	var triples []Triple
-	parser := NewTurtleParser(text)
+// StringToTriples parses the Turtle in text resolving
+// relative IRIs against the base.
+func StringToTriples(text, base string) ([]Triple, error) {
+	parser := NewTurtleParserWithBase(text, base)
 	err := parser.Parse()
 	if err != nil {
-		return triples, err
-	}
-	for _, triple := range parser.Triples() {
-		triple.ReplaceBlankUri(blank)
+		return nil, err
 	}
-	return triples, nil
+	return parser.Triples(), nil
 }
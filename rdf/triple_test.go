@@ -4,13 +4,13 @@ import "testing"
 import "fmt"
 
 func TestTripleToString(t *testing.T) {
-	triple1 := NewTriple("<s>", "<p>", "<o>")
+	triple1 := NewTriple(NewIri("s"), NewIri("p"), NewIri("o"))
 	str := fmt.Sprintf("%s", triple1)
 	if str != "<s> <p> <o> ." {
 		t.Errorf("Triple to string failed: %s", str)
 	}
 
-	triple2 := NewTriple("<s>", "<p>", `"o"`)
+	triple2 := NewTriple(NewIri("s"), NewIri("p"), NewLiteral("o"))
 	str2 := fmt.Sprintf("%s", triple2)
 	if str2 != `<s> <p> "o" .` {
 		t.Errorf("Triple to string failed: %s", str2)
@@ -20,8 +20,8 @@ func TestTripleToString(t *testing.T) {
 func TestStringToTriple(t *testing.T) {
 	validTests := []string{`<a> <b> <c> .`, `<a> <b> "c" .`}
 	for _, test := range validTests {
-		_, err := StringToTriples(test, "")
-		if err != nil {
+		triples, err := StringToTriples(test, "")
+		if err != nil || len(triples) != 1 {
 			t.Errorf("Failed to parse valid triple %s. Err: %s", test, err)
 		}
 	}
@@ -36,22 +36,23 @@ func TestStringToTriple(t *testing.T) {
 }
 
 func TestReplaceBlank(t *testing.T) {
-	testUri := "<http://localhost/root/>"
-	triple := NewTriple("<>", "<p>", "<o>")
+	testUri := NewIri("http://localhost/root/")
+	s, p, o := NewIri("s"), NewIri("p"), NewIri("o")
+	triple := NewTriple(NewIri(""), p, o)
 	triple.ReplaceBlankUri(testUri)
-	if triple.subject != testUri || triple.predicate != "<p>" || triple.object != "<o>" {
+	if triple.subject != testUri || triple.predicate != p || triple.object != o {
 		t.Error("Blank subject handled incorretly")
 	}
 
-	triple = NewTriple("<s>", "<>", "<o>")
+	triple = NewTriple(s, NewIri(""), o)
 	triple.ReplaceBlankUri(testUri)
-	if triple.subject != "<s>" || triple.predicate != testUri || triple.object != "<o>" {
+	if triple.subject != s || triple.predicate != testUri || triple.object != o {
 		t.Error("Blank predicate handled incorretly")
 	}
 
-	triple = NewTriple("<s>", "<p>", "<>")
+	triple = NewTriple(s, p, NewIri(""))
 	triple.ReplaceBlankUri(testUri)
-	if triple.subject != "<s>" || triple.predicate != "<p>" || triple.object != testUri {
+	if triple.subject != s || triple.predicate != p || triple.object != testUri {
 		t.Error("Blank object handled incorretly")
 	}
 }
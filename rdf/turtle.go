@@ -13,6 +13,21 @@
 // and object values as they are parsed. This structure allows
 // us to parse multi-predicate (;) and multi-object (,) triples.
 //
+// Blank node property lists ([ ]) and collections (( )) are expanded
+// into triples for new blank nodes. Blank node labels (_:xyz) are
+// scoped to the document (see blankNodes) unless KeepBlankLabels
+// is used.
+//
+// Prefixed names (e.g. dc:title) are expanded using the @prefix
+// and PREFIX directives and relative IRIs are resolved against
+// the base IRI (see NewTurtleParserWithBase) or the one set with
+// the @base and BASE directives.
+//
+// The text can also be read from a reader (see NewTurtleReader) and
+// the triples handled as they are parsed (see ParseFunc) so that large
+// documents are not kept in memory. Only the current token and the
+// triples for the current subject are kept.
+//
 // Sample usage:
 //     parser := NewTurtleParser("<s> <p1> <o1> , <o2> ; <p2> <o3> .")
 //     err := parser.Parse()
@@ -27,29 +42,77 @@
 package rdf
 
 import (
-	"errors"
+	"io"
 	// "log"
 	"strings"
 )
 
-type Directive struct {
-	name  string
-	value string
+type TurtleParser struct {
+	tokenizer Tokenizer
+	triples   []Triple
+	emit      func(Triple) error
+	base      string
+	prefixes  map[string]string
+	blanks    *blankNodes
+	formulas  map[Term]formula // N3 formulas ({ }) by the blank node that stands for them (nil if not parsing N3)
 }
 
-type TurtleParser struct {
-	tokenizer  Tokenizer
-	triples    []Triple
-	directives []Directive
+// A position in a document (to report errors)
+type position struct {
+	line   int
+	column int
+}
+
+// The triples of an N3 formula and the position of its opening brace
+type formula struct {
+	triples []Triple
+	position
 }
 
 func NewTurtleParser(text string) TurtleParser {
-	tokenizer := NewTokenizer(text)
-	parser := TurtleParser{tokenizer: tokenizer}
-	return parser
+	return NewTurtleParserWithBase(text, "")
+}
+
+// NewTurtleParserWithBase creates a parser that resolves relative
+// IRIs against the base IRI (e.g. the URI of the document) until a
+// @base or BASE directive changes it.
+func NewTurtleParserWithBase(text, base string) TurtleParser {
+	return newTurtleParser(NewTokenizer(text), base)
+}
+
+// NewTurtleReader creates a parser that reads the document from
+// the reader as it parses it. Relative IRIs are resolved against
+// the base IRI (see NewTurtleParserWithBase.)
+func NewTurtleReader(reader io.Reader, base string) TurtleParser {
+	return newTurtleParser(NewReaderTokenizer(reader), base)
 }
 
+func newTurtleParser(tokenizer Tokenizer, base string) TurtleParser {
+	return TurtleParser{tokenizer: tokenizer, base: base,
+		prefixes: map[string]string{}, blanks: newBlankNodes()}
+}
+
+// KeepBlankLabels makes the parser keep the blank node labels
+// (e.g. _:b1) as they are in the document rather than scoping
+// them to the document. This is only safe for documents that we
+// saved ourselves.
+func (parser *TurtleParser) KeepBlankLabels() {
+	parser.blanks.keep = true
+}
+
+// Parse parses the document and keeps the triples (see Triples.)
 func (parser *TurtleParser) Parse() error {
+	return parser.ParseFunc(func(triple Triple) error {
+		parser.triples = append(parser.triples, triple)
+		return nil
+	})
+}
+
+// ParseFunc parses the document and calls emit with the triples
+// as they are parsed rather than keeping them. Parsing stops with
+// the error returned by emit, if any.
+func (parser *TurtleParser) ParseFunc(emit func(Triple) error) error {
+	parser.emit = emit
 	for parser.tokenizer.CanRead() {
 		err := parser.parseNextTriples()
 		if err != nil {
@@ -57,36 +120,13 @@ func (parser *TurtleParser) Parse() error {
 		}
 		parser.tokenizer.AdvanceWhiteSpace()
 	}
-
-	parser.applyBaseDirective()
-	return nil
+	return parser.tokenizer.scanner.Err()
 }
 
 func (parser TurtleParser) Triples() []Triple {
 	return parser.triples
 }
 
-func (parser *TurtleParser) applyBaseDirective() {
-	if len(parser.directives) == 0 {
-		return
-	}
-
-	if parser.directives[0].name != "@base" {
-		// unknown directive
-		return
-	}
-
-	base := parser.directives[0]
-	for i, triple := range parser.triples {
-		if triple.subject == "<>" {
-			parser.triples[i].subject = base.value
-		}
-		if triple.object == "<>" {
-			parser.triples[i].object = base.value
-		}
-	}
-}
-
 func (parser *TurtleParser) parseNextTriples() error {
 	var err error
 	var token string
@@ -97,90 +137,401 @@ func (parser *TurtleParser) parseNextTriples() error {
 			break
 		}
 
-		isDirective := strings.HasPrefix(token, "@")
-		if isDirective {
+		if isDirective(token) {
 			err = parser.parseNextDirective(token)
 			continue
 		}
 
 		// triples
-		subject := NewSubjectNode(token)
-		err = parser.parsePredicates(&subject)
+		var value Term
+		value, err = parser.parseSubject(token)
+		if err != nil {
+			break
+		}
+		subject := NewSubjectNode(value)
+		err = parser.parsePredicates(&subject, ".", token == "[")
 		if err == nil {
-			for _, triple := range subject.RenderTriples() {
-				parser.triples = append(parser.triples, triple)
-			}
+			err = parser.addTriples(subject.RenderTriples()...)
 		}
-
 	}
 	return err
 }
 
+// Turtle directives (@prefix and @base) end with a period,
+// the SPARQL style ones (PREFIX and BASE) don't.
+func isDirective(token string) bool {
+	return strings.HasPrefix(token, "@") ||
+		strings.EqualFold(token, "PREFIX") ||
+		strings.EqualFold(token, "BASE")
+}
+
 func (parser *TurtleParser) parseNextDirective(name string) error {
-	if !parser.tokenizer.CanRead() {
-		return errors.New("No value found for directive (" + name + ")")
+	var err error
+	var token string
+	prefix := ""
+	isBase := false
+
+	switch name {
+	case "@prefix":
+	case "@base":
+		isBase = true
+	default:
+		isBase = strings.EqualFold(name, "BASE")
+		if strings.HasPrefix(name, "@") {
+			return parser.error("Unknown directive", name, "@prefix", "@base")
+		}
 	}
 
-	value, err := parser.tokenizer.GetNextToken()
+	if !isBase {
+		prefix, err = parser.tokenizer.GetNextToken()
+		if err != nil {
+			return err
+		}
+		if !strings.HasSuffix(prefix, ":") || strings.Count(prefix, ":") > 1 {
+			return parser.error("Invalid prefix in directive "+name, prefix, "prefix:")
+		}
+	}
+
+	token, err = parser.tokenizer.GetNextToken()
 	if err != nil {
 		return err
 	}
+	if !isIriToken(token) {
+		return parser.error("No IRI found for directive "+name, token, "IRI")
+	}
+	iri := ResolveIri(parser.base, token[1:len(token)-1])
+
+	if strings.HasPrefix(name, "@") {
+		token, err = parser.tokenizer.GetNextToken()
+		if err != nil {
+			return err
+		}
+		if token != "." {
+			return parser.error("Could not find end of directive "+name, token, ".")
+		}
+	}
+
+	if isBase {
+		parser.base = iri
+	} else {
+		parser.prefixes[strings.TrimSuffix(prefix, ":")] = iri
+	}
+	return nil
+}
+
+// Converts a token to a term: relative IRIs are resolved against
+// the current base and prefixed names are expanded to absolute IRIs.
+// Prefixed names with an undeclared prefix and bare words (other
+// than the keywords handled by the tokenizer) are not valid Turtle.
+func (parser *TurtleParser) term(token string) (Term, error) {
+	switch {
+	case isIriToken(token):
+		return NewIri(ResolveIri(parser.base, token[1:len(token)-1])), nil
+	case strings.HasPrefix(token, "\""):
+		return parser.literal(token)
+	case strings.HasPrefix(token, "_:"):
+		return parser.blanks.Label(token), nil
+	case strings.HasPrefix(token, "?"):
+		// only in SPARQL (see Tokenizer.sparql)
+		return NewVariable(token), nil
+	}
+
+	index := strings.Index(token, ":")
+	if index == -1 {
+		return Term{}, parser.error("Unexpected token", token, "IRI", "prefixed name", "blank node", "literal")
+	}
+	namespace, ok := parser.prefixes[token[:index]]
+	if !ok {
+		return Term{}, parser.error("Undeclared prefix", token)
+	}
+	return NewIri(namespace + token[index+1:]), nil
+}
+
+// Returns the term for a predicate. The keyword "a" is
+// short for rdf:type.
+func (parser *TurtleParser) predicate(token string) (Term, error) {
+	if token == "a" {
+		return NewIri(RdfTypeUri), nil
+	}
+	return parser.term(token)
+}
+
+// Returns the literal for a literal token resolving (or expanding)
+// its datatype.
+func (parser *TurtleParser) literal(token string) (Term, error) {
+	value, language, datatype := literalParts(token)
+	switch {
+	case language != "":
+		return NewLangLiteral(value, language), nil
+	case datatype != "":
+		term, err := parser.term(datatype)
+		if err != nil {
+			return Term{}, err
+		}
+		return NewTypedLiteral(value, term.Value()), nil
+	}
+	return NewLiteral(value), nil
+}
 
-	token, err := parser.tokenizer.GetNextToken()
-	if token != "." {
-		return errors.New("Could not find end of directive (" + name + ")")
+func isIriToken(token string) bool {
+	return strings.HasPrefix(token, "<") && strings.HasSuffix(token, ">")
+}
+
+func (parser *TurtleParser) addTriples(triples ...Triple) error {
+	for _, triple := range triples {
+		if err := parser.emit(triple); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	if err == nil {
-		directive := Directive{name: name, value: value}
-		parser.directives = append(parser.directives, directive)
+// Returns the value for the subject of a set of triples. Subjects can
+// also be blank node property lists or collections, in which case the
+// triples inside of them are added as well.
+func (parser *TurtleParser) parseSubject(token string) (Term, error) {
+	switch token {
+	case "[":
+		return parser.parseBlankNodePropertyList()
+	case "(":
+		return parser.parseCollection()
+	case ".", ",", ";", "]", ")":
+		return Term{}, parser.error("Unexpected token", token, turtleSubjectTokens...)
 	}
+	return parser.term(token)
+}
+
+// Parses the predicates and objects for a subject up to the end
+// token ("." or "]".) The predicates are optional for blank node
+// property lists used as subjects, e.g. [ <p> <o> ] .
+func (parser *TurtleParser) parsePredicates(subject *SubjectNode, end string, optional bool) error {
+	_, err := parser.parsePredicatesUntil(subject, optional, end)
 	return err
 }
 
-func (parser *TurtleParser) parsePredicates(subject *SubjectNode) error {
-	var err error
-	var token string
+// Like parsePredicates but stops at any of the end tokens (e.g. "."
+// or "}" in SPARQL) and returns the one found.
+func (parser *TurtleParser) parsePredicatesUntil(subject *SubjectNode, optional bool, ends ...string) (string, error) {
+	isEnd := func(token string) bool {
+		for _, end := range ends {
+			if token == end {
+				return true
+			}
+		}
+		return false
+	}
 
-	for err == nil && parser.tokenizer.CanRead() {
-		token, err = parser.tokenizer.GetNextToken()
-		if err != nil || token == "." {
+	for {
+		token, err := parser.tokenizer.GetNextToken()
+		if err != nil {
+			return "", err
+		} else if token == "" {
+			return "", parser.error("Unexpected end of document", "", ends...)
+		} else if isEnd(token) && (optional || len(subject.predicates) > 0) {
 			// we are done
-			break
+			return token, nil
+		} else if token == ";" && len(subject.predicates) > 0 {
+			// repeated semicolons are allowed
+			continue
+		} else if isPunctuation(token) {
+			return "", parser.error("Unexpected token parsing predicates", token, turtlePredicateTokens...)
+		}
+
+		term, err := parser.predicate(token)
+		if err != nil {
+			return "", err
 		}
-		predicate := subject.AddPredicate(token)
+		predicate := subject.AddPredicate(term)
 		token, err = parser.parseObjects(predicate)
 		if err != nil {
-			break
-		} else if token == "." {
+			return "", err
+		} else if isEnd(token) {
 			// we are done, next triple will be for a different subject
-			break
-		} else if token == ";" {
-			// next triple will be for the same subject
-			continue
-		} else {
-			err = errors.New("Unexpected token parsing predicates (" + token + ")")
+			return token, nil
+		} else if token != ";" {
+			return "", parser.error("Unexpected token parsing predicates", token, append([]string{",", ";"}, ends...)...)
 		}
+		// next triple will be for the same subject
 	}
-	return err
 }
 
+// Parses the objects for a predicate and returns the token that
+// ended the list of objects (e.g. "." or ";").
 func (parser *TurtleParser) parseObjects(predicate *PredicateNode) (string, error) {
-	var err error
-	var token string
-	for parser.tokenizer.CanRead() {
-		token, err = parser.tokenizer.GetNextToken()
-		if err != nil || token == "." || token == ";" {
-			// we are done
-			break
+	expectObject := true
+	for {
+		token, err := parser.tokenizer.GetNextToken()
+		if err != nil {
+			return "", err
+		}
+
+		if expectObject {
+			if token == "" || (isPunctuation(token) && !parser.isObjectStart(token)) {
+				return "", parser.error("Expected an object", token, turtleObjectTokens...)
+			}
+			object, err := parser.parseObject(token)
+			if err != nil {
+				return "", err
+			}
+			predicate.AddObject(object)
+			expectObject = false
 		} else if token == "," {
 			// the next token will be for the same
 			// subject + predicate
-			continue
+			expectObject = true
 		} else {
-			// it's a object, add it to the predicate
-			predicate.AddObject(token)
+			return token, nil
+		}
+	}
+}
+
+func (parser *TurtleParser) parseObject(token string) (Term, error) {
+	switch token {
+	case "[":
+		return parser.parseBlankNodePropertyList()
+	case "(":
+		return parser.parseCollection()
+	case "{":
+		return parser.parseFormula()
+	}
+	return parser.term(token)
+}
+
+// Returns true for the punctuation that can start an object. N3
+// formulas ({ }) are only objects when parsing N3.
+func (parser *TurtleParser) isObjectStart(token string) bool {
+	return token == "[" || token == "(" || (token == "{" && parser.formulas != nil)
+}
+
+// Parses the triples of an N3 formula (the opening brace has been
+// read) and returns the blank node that stands for it.
+func (parser *TurtleParser) parseFormula() (Term, error) {
+	start := position{parser.tokenizer.tokenRow, parser.tokenizer.tokenCol}
+	triples, err := parser.parseBlock()
+	if err != nil {
+		return Term{}, err
+	}
+	term := parser.blanks.New()
+	parser.formulas[term] = formula{triples, start}
+	return term, nil
+}
+
+// Parses the triples inside { } (the opening brace has been read) as
+// in SPARQL patterns, N3 formulas, and LD Patch. The triples are
+// returned rather than added to the ones of the document.
+func (parser *TurtleParser) parseBlock() ([]Triple, error) {
+	triples := []Triple{}
+	emit := parser.emit
+	defer func() { parser.emit = emit }()
+	parser.emit = func(triple Triple) error {
+		triples = append(triples, triple)
+		return nil
+	}
+
+	for {
+		token, err := parser.tokenizer.GetNextToken()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case token == "}":
+			return triples, nil
+		case token == "":
+			return nil, parser.error("Unexpected end of document", "", "}")
+		case isKeyword(token):
+			// e.g. GRAPH, FILTER, or OPTIONAL in SPARQL
+			return nil, parser.error("Unsupported keyword", token, append(turtleSubjectTokens, "variable", "}")...)
+		}
+
+		value, err := parser.parseSubject(token)
+		if err != nil {
+			return nil, err
+		}
+		subject := NewSubjectNode(value)
+		end, err := parser.parsePredicatesUntil(&subject, token == "[", ".", "}")
+		if err != nil {
+			return nil, err
+		}
+		if err = parser.addTriples(subject.RenderTriples()...); err != nil {
+			return nil, err
+		}
+		if end == "}" {
+			return triples, nil
+		}
+	}
+}
+
+// Keywords are words that are not prefixed names (Turtle accepts
+// them as relative IRIs, SPARQL doesn't.)
+func isKeyword(token string) bool {
+	if token == "" || strings.Contains(token, ":") {
+		return false
+	}
+	char := token[0]
+	return (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z')
+}
+
+// Parses the predicates and objects inside [ ] for a new blank node
+// and returns the blank node.
+func (parser *TurtleParser) parseBlankNodePropertyList() (Term, error) {
+	subject := NewSubjectNode(parser.blanks.New())
+	err := parser.parsePredicates(&subject, "]", true)
+	if err != nil {
+		return Term{}, err
+	}
+	return subject.value, parser.addTriples(subject.RenderTriples()...)
+}
+
+// Parses the items inside ( ) and returns the head of the RDF
+// collection (rdf:nil for an empty collection.)
+func (parser *TurtleParser) parseCollection() (Term, error) {
+	items := []Term{}
+	for {
+		token, err := parser.tokenizer.GetNextToken()
+		if err != nil {
+			return Term{}, err
+		}
+		if token == ")" {
+			break
+		}
+		if token == "" || (isPunctuation(token) && !parser.isObjectStart(token)) {
+			return Term{}, parser.error("Unexpected token in collection", token, append(turtleObjectTokens, ")")...)
+		}
+		item, err := parser.parseObject(token)
+		if err != nil {
+			return Term{}, err
+		}
+		items = append(items, item)
+	}
+
+	head := NewIri(RdfNilUri)
+	nodes := make([]Term, len(items))
+	for i := range items {
+		nodes[i] = parser.blanks.New()
+	}
+	for i, item := range items {
+		rest := head
+		if i < len(items)-1 {
+			rest = nodes[i+1]
+		}
+		err := parser.addTriples(NewTriple(nodes[i], NewIri(RdfFirstUri), item),
+			NewTriple(nodes[i], NewIri(RdfRestUri), rest))
+		if err != nil {
+			return Term{}, err
 		}
 	}
-	return token, err
+	if len(nodes) > 0 {
+		head = nodes[0]
+	}
+	return head, nil
+}
+
+// Returns a ParseError for the last token read.
+func (parser *TurtleParser) error(message, token string, expected ...string) error {
+	return &ParseError{Message: message, Line: parser.tokenizer.tokenRow, Column: parser.tokenizer.tokenCol,
+		Token: token, Expected: expected}
+}
+
+func isPunctuation(token string) bool {
+	return len(token) == 1 && strings.Contains(".,;[](){}", token)
 }
@@ -2,8 +2,12 @@ package rdf
 
 import (
 	// "fmt"
+	"errors"
+	"io"
 	"io/ioutil"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestOneTriple(t *testing.T) {
@@ -94,7 +98,7 @@ func TestTriplesWithCommaAndSemicolon(t *testing.T) {
 	}
 
 	t0 := parser.Triples()[0].String()
-	if t0 != "<> a <http://www.w3.org/ns/ldp#RDFSource> ." {
+	if t0 != "<> <"+RdfTypeUri+"> <http://www.w3.org/ns/ldp#RDFSource> ." {
 		t.Errorf("Triple 1 is incorrect: %s", t0)
 	}
 
@@ -134,8 +138,284 @@ func TestBaseDirective(t *testing.T) {
 	}
 
 	for _, triple := range parser.Triples() {
-		if triple.subject != "<http://ourbaseuri>" {
+		if triple.subject != NewIri("http://ourbaseuri") {
 			t.Errorf("Base not replaced correctly for %s", triple)
 		}
 	}
 }
+
+func TestPrefixDirectives(t *testing.T) {
+	test := `@prefix dc: <http://purl.org/dc/terms/> .
+PREFIX : <http://x/>
+<s> dc:title "hello" ; :rel :other-thing.
+prefix dc: <http://other/>
+<s> dc:title "bye" , <o> .`
+	parser := NewTurtleParserWithBase(test, "http://base/doc")
+	err := parser.Parse()
+	if err != nil {
+		t.Fatalf("Error parsing prefixes: %s", err)
+	}
+
+	expected := []string{
+		`<http://base/s> <http://purl.org/dc/terms/title> "hello" .`,
+		`<http://base/s> <http://x/rel> <http://x/other-thing> .`,
+		`<http://base/s> <http://other/title> "bye" .`,
+		`<http://base/s> <http://other/title> <http://base/o> .`,
+	}
+	if len(parser.Triples()) != len(expected) {
+		t.Fatalf("Incorrect number of triples: %d", len(parser.Triples()))
+	}
+	for i, triple := range parser.Triples() {
+		if triple.String() != expected[i] {
+			t.Errorf("Triple %d is incorrect: %s", i, triple)
+		}
+	}
+}
+
+func TestBaseDirectives(t *testing.T) {
+	test := `<> <p> <a> .
+@base <http://x/one/> .
+<> <p> <a> .
+BASE <two/>
+@prefix rel: <../rel#> .
+<> rel:p <../a>, "1"^^<int> .`
+	parser := NewTurtleParserWithBase(test, "http://base/doc")
+	err := parser.Parse()
+	if err != nil {
+		t.Fatalf("Error parsing base: %s", err)
+	}
+
+	expected := []string{
+		`<http://base/doc> <http://base/p> <http://base/a> .`,
+		`<http://x/one/> <http://x/one/p> <http://x/one/a> .`,
+		`<http://x/one/two/> <http://x/one/rel#p> <http://x/one/a> .`,
+		`<http://x/one/two/> <http://x/one/rel#p> "1"^^<http://x/one/two/int> .`,
+	}
+	if len(parser.Triples()) != len(expected) {
+		t.Fatalf("Incorrect number of triples: %d", len(parser.Triples()))
+	}
+	for i, triple := range parser.Triples() {
+		if triple.String() != expected[i] {
+			t.Errorf("Triple %d is incorrect: %s", i, triple)
+		}
+	}
+}
+
+func TestInvalidDirectives(t *testing.T) {
+	tests := []string{
+		`@prefix dc <http://x/> .`,
+		`@prefix dc: "x" .`,
+		`@base <http://x/>`,
+		`@unknown <http://x/> .`,
+		`PREFIX dc: <http://x/> .`,
+	}
+	for _, test := range tests {
+		parser := NewTurtleParser(test)
+		if err := parser.Parse(); err == nil {
+			t.Errorf("Invalid directive not detected: %s", test)
+		}
+	}
+}
+
+func TestUndeclaredPrefixes(t *testing.T) {
+	tests := []string{
+		`<s> xx:p <o> .`,
+		`@prefix dc: <http://x/> . <s> dc:p xx:o .`,
+		`<s> <p> "1"^^xsd:integer .`,
+		`<s> p <o> .`,
+	}
+	for _, test := range tests {
+		parser := NewTurtleParser(test)
+		if _, ok := parser.Parse().(*ParseError); !ok {
+			t.Errorf("Undeclared prefix not detected: %s", test)
+		}
+	}
+}
+
+func TestBlankNodes(t *testing.T) {
+	test := `@prefix : <http://x/> .
+_:one :knows _:two .
+_:two :knows [ :name "anon" ; :age "3" ] , [] .
+[ :name "subject" ] :knows _:one .
+[ :name "alone" ] .`
+	parser := NewTurtleParser(test)
+	err := parser.Parse()
+	if err != nil {
+		t.Fatalf("Error parsing blank nodes: %s", err)
+	}
+
+	graph := NewGraph(parser.Triples()...)
+	if graph.Len() != 8 {
+		t.Fatalf("Incorrect number of triples: %d\n%s", graph.Len(), graph)
+	}
+
+	first := graph.Triples()[0]
+	one, two := first.Subject(), first.Object()
+	if !one.IsBlankNode() || !two.IsBlankNode() || one == NewBlankNode("one") || one == two {
+		t.Errorf("Blank node labels not scoped: %s", first)
+	}
+
+	anon, ok := graph.GetObject(two, NewIri("http://x/knows"))
+	if !ok || !anon.IsBlankNode() || !graph.HasTriple(anon, NewIri("http://x/age"), NewLiteral("3")) {
+		t.Errorf("Blank node property list not parsed: %s", graph)
+	}
+
+	subject, _ := findSubject(graph, NewIri("http://x/name"), NewLiteral("subject"))
+	if !graph.HasTriple(subject, NewIri("http://x/knows"), one) {
+		t.Errorf("Blank node property list not parsed as subject: %s", graph)
+	}
+
+	other := NewTurtleParser(`_:one <http://x/p> "other document" .`)
+	other.Parse()
+	if other.Triples()[0].Subject() == one {
+		t.Errorf("Blank node labels collide across documents")
+	}
+}
+
+func TestCollections(t *testing.T) {
+	test := `<s> <p> ( <a> "b" ( ) [ <q> <c> ] ) , () .
+( <x> ) <p> <o> .`
+	parser := NewTurtleParser(test)
+	err := parser.Parse()
+	if err != nil {
+		t.Fatalf("Error parsing collections: %s", err)
+	}
+
+	graph := NewGraph(parser.Triples()...)
+	s, p, empty := NewIri("s"), NewIri("p"), NewIri(RdfNilUri)
+	if !graph.HasTriple(s, p, empty) {
+		t.Errorf("Empty collection not parsed: %s", graph)
+	}
+
+	items := []Term{}
+	head, _ := graph.GetObject(s, p)
+	for head != empty {
+		item, ok := graph.GetObject(head, NewIri(RdfFirstUri))
+		if !ok {
+			t.Fatalf("Invalid collection: %s", graph)
+		}
+		items = append(items, item)
+		head, _ = graph.GetObject(head, NewIri(RdfRestUri))
+	}
+	if len(items) != 4 || items[0] != NewIri("a") || items[1] != NewLiteral("b") || items[2] != empty {
+		t.Errorf("Unexpected items in collection: %v", items)
+	}
+
+	if !graph.HasTriple(items[3], NewIri("q"), NewIri("c")) {
+		t.Errorf("Blank node in collection not parsed: %s", graph)
+	}
+
+	subject, _ := findSubject(graph, NewIri(RdfFirstUri), NewIri("x"))
+	if !graph.HasTriple(subject, p, NewIri("o")) {
+		t.Errorf("Collection not parsed as subject: %s", graph)
+	}
+
+	invalid := []string{`<s> <p> ( <a> .`, `<s> <p> [ <q> <c> .`, `<s> <p> [ <q> ] .`, `<s> <p> ] .`, `<s> <p> <o>`}
+	for _, test := range invalid {
+		parser := NewTurtleParser(test)
+		if err := parser.Parse(); err == nil {
+			t.Errorf("Invalid Turtle not detected: %s", test)
+		}
+	}
+}
+
+func findSubject(graph RdfGraph, predicate, object Term) (Term, bool) {
+	for _, triple := range graph.Triples() {
+		if triple.predicate == predicate && triple.object == object {
+			return triple.subject, true
+		}
+	}
+	return Term{}, false
+}
+
+func TestLiteralRoundTrip(t *testing.T) {
+	test := `@prefix xsd: <http://www.w3.org/2001/XMLSchema#> .
+<s> <p> 42, 3.14, 1e5, true, "x"^^xsd:string, "7"^^xsd:int, 'single', """long
+text""" .`
+	parser := NewTurtleParser(test)
+	if err := parser.Parse(); err != nil {
+		t.Fatalf("Error parsing literals: %s", err)
+	}
+
+	graph := NewGraph(parser.Triples()...)
+	xsd := "^^<http://www.w3.org/2001/XMLSchema#"
+	expected := []string{`"42"` + xsd + `integer>`, `"3.14"` + xsd + `decimal>`, `"1e5"` + xsd + `double>`,
+		`"true"` + xsd + `boolean>`, `"x"`, `"7"` + xsd + `int>`, `"single"`, `"long\ntext"`}
+	for _, object := range expected {
+		if !graph.HasTriple(NewIri("s"), NewIri("p"), termOf(object)) {
+			t.Errorf("Literal %s not found:\n%s", object, graph)
+		}
+	}
+
+	reparsed, err := StringToGraph(graph.String(), "")
+	if err != nil || reparsed.String() != graph.String() {
+		t.Errorf("Literals did not round-trip: %s\n%s", err, reparsed)
+	}
+}
+
+func TestTurtleReader(t *testing.T) {
+	text := "<s> <p> <o1>, <o2> .\n<s> <p> ( <o3> ) .\n<s> <p> <o4> ."
+	parser := NewTurtleReader(strings.NewReader(text), "http://x/")
+	triples := []Triple{}
+	stop := errors.New("stop")
+	err := parser.ParseFunc(func(triple Triple) error {
+		triples = append(triples, triple)
+		if triple.Object() == NewIri(RdfNilUri) {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || len(triples) != 4 || triples[0] != NewTriple(NewIri("http://x/s"), NewIri("http://x/p"), NewIri("http://x/o1")) {
+		t.Errorf("Unexpected triples (%s): %v", err, triples)
+	}
+
+	if len(parser.Triples()) != 0 {
+		t.Errorf("Triples kept by ParseFunc: %v", parser.Triples())
+	}
+
+	reader := io.MultiReader(strings.NewReader("<s> <p> <o> .\n<s> <p> "), iotest.ErrReader(errors.New("boom")))
+	parser = NewTurtleReader(reader, "")
+	if err := parser.Parse(); err == nil || err.Error() != "boom" {
+		t.Errorf("Read error not detected: %s", err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected ParseError
+	}{
+		{"<s> <p> <o> .\n<s> <p> <o> ;\n  , <o2> .",
+			ParseError{Line: 3, Column: 3, Token: ",", Expected: turtlePredicateTokens}},
+		{"<s> <p> <o>\n<s2> <p> <o> .",
+			ParseError{Line: 2, Column: 1, Token: "<s2>", Expected: []string{",", ";", "."}}},
+		{"@prefix x <http://x/> .",
+			ParseError{Line: 1, Column: 9, Token: "x", Expected: []string{"prefix:"}}},
+		{"<s> <p> .",
+			ParseError{Line: 1, Column: 9, Token: ".", Expected: turtleObjectTokens}},
+		{"<s> <p> <o> ; <p2>",
+			ParseError{Line: 1, Column: 19, Token: "", Expected: turtleObjectTokens}},
+		{"<s> <p> \"héllo\" ,\n\t<o2 x> .",
+			ParseError{Line: 2, Column: 5, Token: " "}},
+		{"<s> <p> \"hello .",
+			ParseError{Line: 1, Column: 17, Token: "", Expected: []string{"\""}}},
+		{"@prefix dc: <http://x/> .\n<s> dc:p\n  xx:o .",
+			ParseError{Line: 3, Column: 3, Token: "xx:o"}},
+		{"<s> title <o> .",
+			ParseError{Line: 1, Column: 5, Token: "title", Expected: []string{"IRI", "prefixed name", "blank node", "literal"}}},
+	}
+	for _, test := range tests {
+		parser := NewTurtleParser(test.text)
+		err := parser.Parse()
+		parseErr, ok := err.(*ParseError)
+		if !ok {
+			t.Errorf("No ParseError for %s: %v", test.text, err)
+			continue
+		}
+		if parseErr.Line != test.expected.Line || parseErr.Column != test.expected.Column ||
+			parseErr.Token != test.expected.Token ||
+			strings.Join(parseErr.Expected, " ") != strings.Join(test.expected.Expected, " ") {
+			t.Errorf("Unexpected error for %s: %s %v", test.text, err, parseErr.Expected)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package rdf
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// A TurtleWriter writes graphs in Turtle grouping the triples by
+// subject (with ;) and by predicate (with ,). IRIs are shortened
+// to prefixed names when there is a prefix for their namespace and,
+// if a base IRI is set, to IRIs relative to it. The output is written
+// as the triples are visited so large graphs are not built in memory
+// as a string.
+//
+// Sample usage:
+//
+//	writer := NewTurtleWriter(os.Stdout)
+//	writer.SetPrefix("ex", "http://example.org/")
+//	writer.SetBase("http://localhost/node1")
+//	err := writer.Write(graph)
+type TurtleWriter struct {
+	writer   *bufio.Writer
+	prefixes []turtlePrefix
+	base     string
+	err      error
+}
+
+type turtlePrefix struct {
+	prefix    string
+	namespace string
+}
+
+// Local names that can be written as-is in a prefixed name
+// (a subset of PN_LOCAL that does not need escaping.)
+var turtleLocalNameRegex = regexp.MustCompile(`^([A-Za-z0-9_]([A-Za-z0-9_.-]*[A-Za-z0-9_-])?)?$`)
+
+var turtleIntegerRegex = regexp.MustCompile(`^[+-]?[0-9]+$`)
+
+// NewTurtleWriter returns a writer with the prefixes for the
+// namespaces used by the server (rdf, ldp, dcterms, et cetera.)
+func NewTurtleWriter(writer io.Writer) *TurtleWriter {
+	turtleWriter := &TurtleWriter{writer: bufio.NewWriter(writer)}
+	for _, known := range knownPrefixes {
+		turtleWriter.SetPrefix(known.prefix, known.namespace)
+	}
+	return turtleWriter
+}
+
+// SetPrefix adds (or replaces) the namespace for a prefix. An
+// empty namespace removes the prefix.
+func (tw *TurtleWriter) SetPrefix(prefix, namespace string) {
+	for i, existing := range tw.prefixes {
+		if existing.prefix == prefix {
+			tw.prefixes = append(tw.prefixes[:i], tw.prefixes[i+1:]...)
+			break
+		}
+	}
+	if namespace != "" {
+		tw.prefixes = append(tw.prefixes, turtlePrefix{prefix, namespace})
+	}
+}
+
+// SetBase makes the writer use IRIs relative to the base (e.g. the
+// URI of the request) when possible. No @base is written since the
+// base of a document is the URI from which it was retrieved.
+func (tw *TurtleWriter) SetBase(base string) {
+	tw.base = base
+}
+
+// Write writes the prefixes and the triples of the graph.
+func (tw *TurtleWriter) Write(graph RdfGraph) error {
+	for _, prefix := range tw.prefixes {
+		tw.writeString("@prefix " + prefix.prefix + ": <" + prefix.namespace + "> .\n")
+	}
+
+	// Subjects are separated by a blank line (as are the prefixes.)
+	separate := len(tw.prefixes) > 0
+	written := map[Term]bool{}
+	for _, triple := range graph.Triples() {
+		if !written[triple.subject] {
+			written[triple.subject] = true
+			if separate {
+				tw.writeString("\n")
+			}
+			tw.writeSubject(graph.Match(triple.subject, Term{}, Term{}))
+			separate = true
+		}
+	}
+
+	if tw.err != nil {
+		return tw.err
+	}
+	return tw.writer.Flush()
+}
+
+// Writes the triples of a subject (in the order they were added.)
+// Predicates are written in the order in which they first appear.
+func (tw *TurtleWriter) writeSubject(triples []Triple) {
+	predicates := []Term{}
+	objects := map[Term][]Term{}
+	for _, triple := range triples {
+		if _, found := objects[triple.predicate]; !found {
+			predicates = append(predicates, triple.predicate)
+		}
+		objects[triple.predicate] = append(objects[triple.predicate], triple.object)
+	}
+
+	tw.writeString(tw.term(triples[0].subject) + "\n")
+	for i, predicate := range predicates {
+		if i > 0 {
+			tw.writeString(" ;\n")
+		}
+		if predicate == NewIri(RdfTypeUri) {
+			tw.writeString("    a")
+		} else {
+			tw.writeString("    " + tw.term(predicate))
+		}
+		for j, object := range objects[predicate] {
+			if j > 0 {
+				tw.writeString(",\n       ")
+			}
+			tw.writeString(" " + tw.term(object))
+		}
+	}
+	tw.writeString(" .\n")
+}
+
+func (tw *TurtleWriter) writeString(text string) {
+	if tw.err == nil {
+		_, tw.err = tw.writer.WriteString(text)
+	}
+}
+
+// Returns the Turtle for a term.
+func (tw *TurtleWriter) term(term Term) string {
+	switch term.Kind() {
+	case IriKind:
+		return tw.iri(term.Value())
+	case LiteralKind:
+		return tw.literal(term)
+	}
+	return term.String()
+}
+
+// Returns a prefixed name, a relative IRI, or the full IRI (in
+// that order of preference.)
+func (tw *TurtleWriter) iri(iri string) string {
+	best := turtlePrefix{}
+	for _, prefix := range tw.prefixes {
+		if strings.HasPrefix(iri, prefix.namespace) && len(prefix.namespace) > len(best.namespace) &&
+			turtleLocalNameRegex.MatchString(iri[len(prefix.namespace):]) {
+			best = prefix
+		}
+	}
+	if best.namespace != "" {
+		return best.prefix + ":" + iri[len(best.namespace):]
+	}
+	if tw.base != "" {
+		return "<" + RelativeIri(tw.base, iri) + ">"
+	}
+	return "<" + iri + ">"
+}
+
+func (tw *TurtleWriter) literal(term Term) string {
+	switch {
+	case term.Language() != "":
+		return term.String()
+	case term.Datatype() == xsdStringUri:
+		return Literal(term.Value())
+	case term.Datatype() == xsdNamespace+"integer" && turtleIntegerRegex.MatchString(term.Value()):
+		return term.Value()
+	case term.Datatype() == xsdNamespace+"boolean" && (term.Value() == "true" || term.Value() == "false"):
+		return term.Value()
+	}
+	return Literal(term.Value()) + "^^" + tw.iri(term.Datatype())
+}
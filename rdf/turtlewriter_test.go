@@ -0,0 +1,84 @@
+package rdf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTurtleWriter(t *testing.T) {
+	graph := serializerTestGraph(t)
+	var text strings.Builder
+	if err := NewTurtleWriter(&text).Write(graph); err != nil {
+		t.Fatalf("Error writing Turtle: %s", err)
+	}
+
+	expected := []string{
+		"@prefix ldp: <http://www.w3.org/ns/ldp#> .\n",
+		"<http://x/a>\n    a ldp:BasicContainer ;\n    ldp:contains <http://x/a/b> ;\n",
+		`    dcterms:title "hello \"world\""@en ;` + "\n",
+		"    <http://x/ns#count> 3 .\n",
+		"<http://x/a/b>\n    <http://x/ns#name> \"b & c\" .\n",
+	}
+	for _, snippet := range expected {
+		if !strings.Contains(text.String(), snippet) {
+			t.Errorf("Turtle snippet not found: %s\n%s", snippet, text.String())
+		}
+	}
+
+	// The output must parse back to the same graph
+	parsed, err := StringToGraph(text.String(), "")
+	if err != nil || parsed.Len() != graph.Len() {
+		t.Fatalf("Error parsing the Turtle written: %s\n%s", err, text.String())
+	}
+	for _, triple := range graph.Triples() {
+		if len(parsed.Match(triple.subject, triple.predicate, triple.object)) != 1 {
+			t.Errorf("Triple not found after round trip: %s", triple)
+		}
+	}
+}
+
+func TestTurtleWriterObjectLists(t *testing.T) {
+	s, p := NewIri("http://x/s"), NewIri("http://x/ns#p")
+	graph := NewGraph(
+		NewTriple(s, p, NewIri("http://x/o1")),
+		NewTriple(s, p, NewTypedLiteral("true", xsdNamespace+"boolean")),
+		NewTriple(s, p, NewTypedLiteral("x y", xsdNamespace+"integer")),
+		NewTriple(NewBlankNode("b1"), p, NewLangLiteral("hi", "en")))
+
+	var text strings.Builder
+	writer := NewTurtleWriter(&text)
+	writer.SetPrefix("ex", "http://x/ns#")
+	writer.SetPrefix("rdf", "")
+	if err := writer.Write(graph); err != nil {
+		t.Fatalf("Error writing Turtle: %s", err)
+	}
+
+	expected := "<http://x/s>\n    ex:p <http://x/o1>,\n        true,\n" +
+		"        \"x y\"^^xsd:integer .\n\n_:b1\n    ex:p \"hi\"@en .\n"
+	if !strings.HasSuffix(text.String(), expected) {
+		t.Errorf("Unexpected Turtle. Expected suffix:\n%s\nGot:\n%s", expected, text.String())
+	}
+	if !strings.Contains(text.String(), "@prefix ex: <http://x/ns#> .") ||
+		strings.Contains(text.String(), "@prefix rdf:") {
+		t.Errorf("Unexpected prefixes:\n%s", text.String())
+	}
+}
+
+func TestTurtleWriterRelativeIris(t *testing.T) {
+	graph := serializerTestGraph(t)
+	var text strings.Builder
+	writer := NewTurtleWriter(&text)
+	writer.SetBase("http://x/a")
+	if err := writer.Write(graph); err != nil {
+		t.Fatalf("Error writing Turtle: %s", err)
+	}
+
+	if !strings.Contains(text.String(), "<>\n    a ldp:BasicContainer ;\n    ldp:contains <a/b> ;\n") {
+		t.Errorf("Relative IRIs not found:\n%s", text.String())
+	}
+
+	parser := NewTurtleParserWithBase(text.String(), "http://x/a")
+	if err := parser.Parse(); err != nil || NewGraph(parser.Triples()...).Len() != graph.Len() {
+		t.Fatalf("Error parsing the Turtle written: %s\n%s", err, text.String())
+	}
+}
@@ -0,0 +1,291 @@
+package rdf
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// An Update deletes and inserts triples in a graph. The templates
+// (Delete and Insert) and the pattern (Where) can have variables:
+// the templates are instantiated for each solution of the pattern
+// (see Solve.) An empty pattern has one solution that binds no
+// variables, so templates without variables are used as-is.
+//
+// Template triples with variables that the solution does not bind
+// are skipped. Blank nodes in the Insert template are replaced with
+// new blank nodes for each solution when there is more than one.
+//
+// The conditions (Once, DeleteExisting, and InsertNew) are for the
+// patch formats that fail when the patch does not match the graph
+// (e.g. N3 Patch.) Variables set with Bind keep their value in the
+// updates that follow (e.g. LD Patch.)
+type Update struct {
+	Delete []Triple
+	Insert []Triple
+	Where  []Triple
+
+	Once           bool // the pattern must have exactly one solution
+	DeleteExisting bool // the triples to delete must be in the graph
+	InsertNew      bool // the triples to insert must not be in the graph
+	Bind           Term // variable of the pattern bound for the updates that follow
+
+	// finds more triples to delete and insert (e.g. LD Patch Cut)
+	compute func(graph RdfGraph, bindings Bindings) ([]Triple, []Triple, error)
+}
+
+// Errors for updates whose conditions are not met by the graph.
+var NoSolutionError = errors.New("The pattern of the patch does not match the graph")
+var ManySolutionsError = errors.New("The pattern of the patch matches the graph more than once")
+var TripleNotFoundError = errors.New("A triple to delete is not in the graph")
+var TripleExistsError = errors.New("A triple to insert is already in the graph")
+
+// Bindings are the values of the variables in a solution.
+type Bindings map[Term]Term
+
+const SparqlUpdateContentType = "application/sparql-update"
+
+// An UpdateParser returns the updates in the text of a patch
+// document (e.g. SPARQL Update, N3 Patch, or LD Patch.) Relative
+// IRIs must be resolved against the base IRI.
+type UpdateParser func(text, base string) ([]Update, error)
+
+type mediaTypeUpdateParser struct {
+	contentType string
+	parser      UpdateParser
+}
+
+var updateParsers = []mediaTypeUpdateParser{
+	{SparqlUpdateContentType, ParseSparqlUpdate},
+	{N3ContentType, ParseN3Patch},
+	{LdPatchContentType, ParseLdPatch},
+}
+
+// UpdateParserFor returns the parser for the media type (without
+// parameters) of a patch document.
+func UpdateParserFor(contentType string) (UpdateParser, bool) {
+	contentType = strings.ToLower(contentType)
+	for _, registered := range updateParsers {
+		if registered.contentType == contentType {
+			return registered.parser, true
+		}
+	}
+	return nil, false
+}
+
+// PatchContentTypes returns the media types accepted to patch a
+// graph: patch documents and the RDF serializations that we can
+// parse (whose triples are added to the graph.)
+func PatchContentTypes() []string {
+	contentTypes := []string{}
+	for _, registered := range updateParsers {
+		contentTypes = append(contentTypes, registered.contentType)
+	}
+	return append(contentTypes, ParserContentTypes()...)
+}
+
+// ApplyUpdates applies the updates (in order) to a copy of the
+// graph and returns the copy along with the triples that the updates
+// deleted or inserted. The graph itself is not changed so that the
+// changes can be validated before they are kept (i.e. the updates
+// are applied all together or not at all.) An error is returned if
+// the conditions of an update are not met.
+func ApplyUpdates(graph RdfGraph, updates []Update) (RdfGraph, RdfGraph, error) {
+	updated := NewGraph(graph.Triples()...)
+	var changes RdfGraph
+	bound := Bindings{}
+	for _, update := range updates {
+		deleted, inserted, err := update.changes(updated, bound)
+		if err != nil {
+			return RdfGraph{}, RdfGraph{}, err
+		}
+		for _, triple := range deleted {
+			updated.RemoveTriple(triple)
+			changes.AppendTriple(triple)
+		}
+		for _, triple := range inserted {
+			updated.AppendTriple(triple)
+			changes.AppendTriple(triple)
+		}
+	}
+	return updated, changes, nil
+}
+
+// Returns the triples that the update deletes from and inserts into
+// the graph (without changing it.) Deletes are found before inserts
+// so that an update can replace a value. The bound variables are the
+// ones set by the previous updates (see Update.Bind.)
+func (update Update) changes(graph RdfGraph, bound Bindings) ([]Triple, []Triple, error) {
+	initial := bound.copy()
+	if update.Bind.IsVariable() {
+		delete(initial, update.Bind)
+	}
+	solutions := graph.solve(update.Where, initial)
+	if update.Once {
+		solutions = distinctSolutions(solutions)
+		switch {
+		case len(solutions) == 0:
+			return nil, nil, NoSolutionError
+		case len(solutions) > 1:
+			return nil, nil, ManySolutionsError
+		}
+	}
+
+	deleted := []Triple{}
+	inserted := []Triple{}
+	blanks := newBlankNodes()
+	for _, bindings := range solutions {
+		deleted = append(deleted, bindings.instantiate(update.Delete)...)
+
+		labels := map[Term]Term{}
+		for _, triple := range bindings.instantiate(update.Insert) {
+			if len(solutions) > 1 {
+				// new blank nodes for each solution
+				terms := []*Term{&triple.subject, &triple.predicate, &triple.object}
+				for _, term := range terms {
+					if term.IsBlankNode() {
+						if _, ok := labels[*term]; !ok {
+							labels[*term] = blanks.New()
+						}
+						*term = labels[*term]
+					}
+				}
+			}
+			inserted = append(inserted, triple)
+		}
+
+		if update.compute != nil {
+			moreDeleted, moreInserted, err := update.compute(graph, bindings)
+			if err != nil {
+				return nil, nil, err
+			}
+			deleted = append(deleted, moreDeleted...)
+			inserted = append(inserted, moreInserted...)
+		}
+	}
+
+	for _, triple := range deleted {
+		if update.DeleteExisting && !graph.HasTriple(triple.subject, triple.predicate, triple.object) {
+			return nil, nil, TripleNotFoundError
+		}
+	}
+	for _, triple := range inserted {
+		if update.InsertNew && graph.HasTriple(triple.subject, triple.predicate, triple.object) {
+			return nil, nil, TripleExistsError
+		}
+	}
+	if update.Bind.IsVariable() && len(solutions) > 0 {
+		bound[update.Bind] = solutions[0][update.Bind]
+	}
+	return deleted, inserted, nil
+}
+
+// Solve returns the solutions of a basic graph pattern, i.e. the
+// bindings of its variables for which all the triples of the pattern
+// are in the graph.
+func (graph RdfGraph) Solve(pattern []Triple) []Bindings {
+	return graph.solve(pattern, Bindings{})
+}
+
+// Like Solve but the solutions extend the initial bindings.
+func (graph RdfGraph) solve(pattern []Triple, initial Bindings) []Bindings {
+	solutions := []Bindings{initial}
+	for _, triple := range pattern {
+		next := []Bindings{}
+		for _, bindings := range solutions {
+			subject := bindings.matchTerm(triple.subject)
+			predicate := bindings.matchTerm(triple.predicate)
+			object := bindings.matchTerm(triple.object)
+			for _, match := range graph.Match(subject, predicate, object) {
+				if extended, ok := bindings.extend(triple, match); ok {
+					next = append(next, extended)
+				}
+			}
+		}
+		solutions = next
+	}
+	return solutions
+}
+
+// Returns the value of a term for Match: the bound value of
+// variables (or the zero Term if not bound.)
+func (bindings Bindings) matchTerm(term Term) Term {
+	if term.IsVariable() {
+		return bindings[term]
+	}
+	return term
+}
+
+// Returns the bindings extended with the variables in the pattern
+// bound to the terms in the triple that matched it. Returns false
+// if a variable would have two values (e.g. ?x <p> ?x.)
+func (bindings Bindings) extend(pattern, match Triple) (Bindings, bool) {
+	extended := bindings.copy()
+	patternTerms := []Term{pattern.subject, pattern.predicate, pattern.object}
+	matchTerms := []Term{match.subject, match.predicate, match.object}
+	for i, term := range patternTerms {
+		if !term.IsVariable() {
+			continue
+		}
+		if value, ok := extended[term]; ok && value != matchTerms[i] {
+			return nil, false
+		}
+		extended[term] = matchTerms[i]
+	}
+	return extended, true
+}
+
+// Returns the triples of the template with the variables replaced
+// by their values. Triples with unbound variables are skipped.
+func (bindings Bindings) instantiate(template []Triple) []Triple {
+	triples := []Triple{}
+	for _, triple := range template {
+		terms := []*Term{&triple.subject, &triple.predicate, &triple.object}
+		bound := true
+		for _, term := range terms {
+			if term.IsVariable() {
+				*term, bound = bindings[*term]
+				if !bound {
+					break
+				}
+			}
+		}
+		if bound {
+			triples = append(triples, triple)
+		}
+	}
+	return triples
+}
+
+func (bindings Bindings) copy() Bindings {
+	copied := Bindings{}
+	for variable, value := range bindings {
+		copied[variable] = value
+	}
+	return copied
+}
+
+// Returns the solutions that differ in the value of a named variable.
+// Variables whose name starts with "_:" stand for blank nodes (or
+// the steps of an LD Patch path) and their values are not compared,
+// e.g. a pattern has one solution for ?x when two blank nodes link
+// to it.
+func distinctSolutions(solutions []Bindings) []Bindings {
+	distinct := []Bindings{}
+	keys := map[string]bool{}
+	for _, bindings := range solutions {
+		names := []string{}
+		for variable, value := range bindings {
+			if !strings.HasPrefix(variable.Value(), "_:") {
+				names = append(names, variable.String()+"="+value.String())
+			}
+		}
+		sort.Strings(names)
+		key := strings.Join(names, " ")
+		if !keys[key] {
+			keys[key] = true
+			distinct = append(distinct, bindings)
+		}
+	}
+	return distinct
+}
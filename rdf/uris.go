@@ -1,7 +1,12 @@
 package rdf
 
 const (
-	RdfTypeUri = "http://www.w3.org/1999/02/22-rdf-syntax-ns#type"
+	RdfTypeUri  = "http://www.w3.org/1999/02/22-rdf-syntax-ns#type"
+	RdfFirstUri = "http://www.w3.org/1999/02/22-rdf-syntax-ns#first"
+	RdfRestUri  = "http://www.w3.org/1999/02/22-rdf-syntax-ns#rest"
+	RdfNilUri   = "http://www.w3.org/1999/02/22-rdf-syntax-ns#nil"
+
+	RdfLangStringUri = "http://www.w3.org/1999/02/22-rdf-syntax-ns#langString"
 )
 
 const (
@@ -11,21 +16,27 @@ const (
 	LdpContainerUri               = "http://www.w3.org/ns/ldp#Container"
 	LdpBasicContainerUri          = "http://www.w3.org/ns/ldp#BasicContainer"
 	LdpDirectContainerUri         = "http://www.w3.org/ns/ldp#DirectContainer"
+	LdpIndirectContainerUri       = "http://www.w3.org/ns/ldp#IndirectContainer"
 	LdpContainsUri                = "http://www.w3.org/ns/ldp#contains"
 	LdpInsertedContentRelationUri = "http://www.w3.org/ns/ldp#insertedContentRelation"
 	LdpMemberSubjectUri           = "http://www.w3.org/ns/ldp#MemberSubject"
 	LdpMembershipResource         = "http://www.w3.org/ns/ldp#membershipResource"
 	LdpHasMemberRelation          = "http://www.w3.org/ns/ldp#hasMemberRelation"
+	LdpIsMemberOfRelation         = "http://www.w3.org/ns/ldp#isMemberOfRelation"
 	LdpConstrainedBy              = "http://www.w3.org/ns/ldp#constrainedBy"
+	LdpPreferContainment          = "http://www.w3.org/ns/ldp#PreferContainment"
+	LdpPreferMembership           = "http://www.w3.org/ns/ldp#PreferMembership"
+	LdpPreferMinimalContainer     = "http://www.w3.org/ns/ldp#PreferMinimalContainer"
 )
 
 const (
 	// HTTP header links
-	LdpResourceLink        = "<" + LdpResourceUri + ">; rel=\"type\""
-	LdpNonRdfSourceLink    = "<" + LdpNonRdfSourceUri + ">; rel=\"type\""
-	LdpContainerLink       = "<" + LdpContainerUri + ">; rel=\"type\""
-	LdpBasicContainerLink  = "<" + LdpBasicContainerUri + ">; rel=\"type\""
-	LdpDirectContainerLink = "<" + LdpDirectContainerUri + ">; rel=\"type\""
+	LdpResourceLink          = "<" + LdpResourceUri + ">; rel=\"type\""
+	LdpNonRdfSourceLink      = "<" + LdpNonRdfSourceUri + ">; rel=\"type\""
+	LdpContainerLink         = "<" + LdpContainerUri + ">; rel=\"type\""
+	LdpBasicContainerLink    = "<" + LdpBasicContainerUri + ">; rel=\"type\""
+	LdpDirectContainerLink   = "<" + LdpDirectContainerUri + ">; rel=\"type\""
+	LdpIndirectContainerLink = "<" + LdpIndirectContainerUri + ">; rel=\"type\""
 )
 
 const (
@@ -34,8 +45,28 @@ const (
 )
 
 const (
-	ServerETagUri        = "http://hectorcorrea.com/ldpserver/ns/etag"
-	ServerContentTypeUri = "http://hectorcorrea.com/ldpserver/ns/contentType"
+	// N3 Patch (https://solidproject.org/TR/protocol#n3-patch)
+	SolidInsertDeletePatchUri = "http://www.w3.org/ns/solid/terms#InsertDeletePatch"
+	SolidWhereUri             = "http://www.w3.org/ns/solid/terms#where"
+	SolidInsertsUri           = "http://www.w3.org/ns/solid/terms#inserts"
+	SolidDeletesUri           = "http://www.w3.org/ns/solid/terms#deletes"
+)
+
+const (
+	ServerETagUri         = "http://hectorcorrea.com/ldpserver/ns/etag"
+	ServerContentTypeUri  = "http://hectorcorrea.com/ldpserver/ns/contentType"
+	ServerLastModifiedUri = "http://hectorcorrea.com/ldpserver/ns/lastModified"
+	ServerDigestUri       = "http://hectorcorrea.com/ldpserver/ns/digest"
+	// The members that were added to the membershipResource when
+	// a node was added to an Indirect Container.
+	ServerInsertedMemberUri = "http://hectorcorrea.com/ldpserver/ns/insertedMember"
+
+	// Problem type (RFC 7807) for request bodies that cannot be parsed
+	ServerParseErrorUri = "http://hectorcorrea.com/ldpserver/ns/ParseError"
+
+	// Prefer header URIs for triples not covered by LDP
+	ServerPreferServerManaged     = "http://hectorcorrea.com/ldpserver/ns/PreferServerManaged"
+	ServerPreferInboundReferences = "http://hectorcorrea.com/ldpserver/ns/PreferInboundReferences"
 )
 const (
 	TurtleContentType = "text/turtle"
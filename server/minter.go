@@ -27,6 +27,19 @@ func CreateMinter(idFile string) chan string {
 	return nextId
 }
 
+// Same as CreateMinter but the last ID is only kept in memory.
+func CreateMemoryMinter() chan string {
+	nextId := make(chan string)
+	go func() {
+		lastId := 0
+		for {
+			lastId++
+			nextId <- strconv.Itoa(lastId)
+		}
+	}()
+	return nextId
+}
+
 // Uses a synchronous channel to force sequential process
 // of this code.
 func MintNextUri(slug string, minter chan string) string {
@@ -4,8 +4,9 @@ import (
 	"errors"
 	"io"
 	"ldpserver/ldp"
-	"ldpserver/textstore"
+	"ldpserver/storage"
 	"ldpserver/util"
+	"time"
 )
 
 // POST
@@ -17,11 +18,11 @@ func (server Server) CreateNonRdfSource(reader io.ReadCloser, parentPath, slug,
 
 	resource := server.createResource(path)
 	err = resource.Error()
-	if err != nil && err != textstore.AlreadyExistsError && err != textstore.CreateDeletedError {
+	if err != nil && err != storage.AlreadyExistsError && err != storage.CreateDeletedError {
 		return ldp.Node{}, resource.Error()
 	}
 
-	if err == textstore.AlreadyExistsError || err == textstore.CreateDeletedError {
+	if err == storage.AlreadyExistsError || err == storage.CreateDeletedError {
 		if slug == "" {
 			// We generated a duplicate node.
 			return ldp.Node{}, ldp.DuplicateNodeError
@@ -39,26 +40,32 @@ func (server Server) CreateNonRdfSource(reader io.ReadCloser, parentPath, slug,
 	}
 
 	if path != "/" {
-		err = server.addNodeToContainer(node, parentPath)
+		err = server.addNodeToContainer(&node, parentPath)
 	}
 
 	return node, err
 }
 
 // PUT
-func (server Server) ReplaceNonRdfSource(reader io.ReadCloser, path, etag, triples string) (ldp.Node, error) {
+func (server Server) ReplaceNonRdfSource(reader io.ReadCloser, path, etag, triples string, since time.Time) (ldp.Node, error) {
 	if isRootPath(path) {
 		return ldp.Node{}, errors.New("Cannot replace root node with an Non-RDF source")
 	}
 
 	resource := server.createResource(path)
-	if resource.Error() != nil && resource.Error() != textstore.AlreadyExistsError {
+	if resource.Error() == storage.CreateDeletedError {
+		return ldp.Node{}, ldp.NodeDeletedError
+	}
+
+	if resource.Error() != nil && resource.Error() != storage.AlreadyExistsError {
 		return ldp.Node{}, resource.Error()
 	}
 
-	if resource.Error() == textstore.AlreadyExistsError {
+	if resource.Error() == storage.AlreadyExistsError {
 		// Replace existing node
-		return ldp.ReplaceNonRdfNode(server.settings, reader, path, etag, triples)
+		server.writeLock.Lock()
+		defer server.writeLock.Unlock()
+		return ldp.ReplaceNonRdfNode(server.settings, reader, path, etag, triples, since)
 	}
 
 	// Create new node
@@ -68,6 +75,6 @@ func (server Server) ReplaceNonRdfSource(reader io.ReadCloser, path, etag, tripl
 	}
 
 	parentPath := util.ParentUriPath(path)
-	err = server.addNodeToContainer(node, parentPath)
+	err = server.addNodeToContainer(&node, parentPath)
 	return node, err
 }
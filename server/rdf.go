@@ -1,72 +1,110 @@
 package server
 
 import (
+	"io"
 	"ldpserver/ldp"
-	"ldpserver/textstore"
+	"ldpserver/storage"
+	"time"
 )
 
-// POST
-func (server Server) CreateRdfSource(triples string, parentPath string, slug string) (ldp.Node, error) {
+// POST. The triples can be in any of the media types supported by
+// rdf.ReadGraph (e.g. Turtle or JSON-LD)
+func (server Server) CreateRdfSource(reader io.Reader, contentType string, parentPath string, slug string) (ldp.Node, error) {
 	path, err := server.newPathFromSlug(parentPath, slug)
 	if err != nil {
 		return ldp.Node{}, err
 	}
 
+	if slug != "" && server.isPathUsed(path) {
+		// The user provided slug is duplicated.
+		// Let's try with one of our own.
+		path, err = server.newPathFromSlug(parentPath, "")
+		if err != nil {
+			return ldp.Node{}, err
+		}
+	}
+
+	// The triples are read once the path (and therefore the base
+	// URI) is known. Validate them before creating the resource so
+	// that we don't leave half-created nodes behind.
+	graph, err := ldp.ReadRdfSource(server.settings, reader, contentType, path)
+	if err != nil {
+		return ldp.Node{}, err
+	}
+
+	err = ldp.ValidateRdfSource(server.settings, graph, path)
+	if err != nil {
+		return ldp.Node{}, err
+	}
+
 	resource := server.createResource(path)
 	err = resource.Error()
-	if err != nil && err != textstore.AlreadyExistsError && err != textstore.CreateDeletedError {
-		return ldp.Node{}, resource.Error()
+	if err == storage.AlreadyExistsError || err == storage.CreateDeletedError {
+		// The path was taken after we checked it (or we
+		// generated a duplicate node.)
+		return ldp.Node{}, ldp.DuplicateNodeError
 	}
 
-	if err == textstore.AlreadyExistsError || err == textstore.CreateDeletedError {
-		if slug == "" {
-			// We generated a duplicate node.
-			return ldp.Node{}, ldp.DuplicateNodeError
-		}
-
-		// The user provided slug is duplicated.
-		// Let's try with one of our own.
-		return server.CreateRdfSource(triples, parentPath, "")
+	if err != nil {
+		return ldp.Node{}, err
 	}
 
 	// Create new node
-	node, err := ldp.NewRdfNode(server.settings, triples, path)
+	node, err := ldp.NewRdfNode(server.settings, graph, path)
 	if err != nil {
 		return ldp.Node{}, err
 	}
 
 	if path != "/" {
-		err = server.addNodeToContainer(node, parentPath)
+		err = server.addNodeToContainer(&node, parentPath)
 	}
 
 	return node, err
 }
 
 // PUT
-func (server Server) ReplaceRdfSource(triples string, parentPath string, slug string, etag string) (ldp.Node, error) {
+// The node is only replaced if it has not been modified after since
+// (a zero time skips this check.)
+func (server Server) ReplaceRdfSource(reader io.Reader, contentType string, parentPath string, slug string, etag string, since time.Time) (ldp.Node, error) {
 	path, err := server.newPathFromSlug(parentPath, slug)
 	if err != nil {
 		return ldp.Node{}, err
 	}
 
+	graph, err := ldp.ReadRdfSource(server.settings, reader, contentType, path)
+	if err != nil {
+		return ldp.Node{}, err
+	}
+
+	err = ldp.ValidateRdfSource(server.settings, graph, path)
+	if err != nil {
+		return ldp.Node{}, err
+	}
+
 	resource := server.createResource(path)
-	if resource.Error() != nil && resource.Error() != textstore.AlreadyExistsError {
+	if resource.Error() == storage.CreateDeletedError {
+		return ldp.Node{}, ldp.NodeDeletedError
+	}
+
+	if resource.Error() != nil && resource.Error() != storage.AlreadyExistsError {
 		return ldp.Node{}, resource.Error()
 	}
 
-	if resource.Error() == textstore.AlreadyExistsError {
+	if resource.Error() == storage.AlreadyExistsError {
 		// Replace existing node
-		return ldp.ReplaceRdfNode(server.settings, triples, path, etag)
+		server.writeLock.Lock()
+		defer server.writeLock.Unlock()
+		return ldp.ReplaceRdfNode(server.settings, graph, path, etag, since)
 	}
 
 	// Create new node
-	node, err := ldp.NewRdfNode(server.settings, triples, path)
+	node, err := ldp.NewRdfNode(server.settings, graph, path)
 	if err != nil {
 		return ldp.Node{}, err
 	}
 
 	if path != "/" {
-		err = server.addNodeToContainer(node, parentPath)
+		err = server.addNodeToContainer(&node, parentPath)
 	}
 
 	return node, err
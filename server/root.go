@@ -3,7 +3,9 @@ package server
 import (
 	"fmt"
 	"ldpserver/ldp"
+	"ldpserver/rdf"
 	"log"
+	"strings"
 )
 
 func (server Server) createRoot() {
@@ -16,10 +18,14 @@ func (server Server) createRoot() {
 		panic(fmt.Sprintf("Error reading root node: %s", err.Error()))
 	}
 
-	_, err = server.CreateRdfSource("", ".", ".")
+	_, err = server.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, ".", ".")
 	if err != nil {
 		panic(fmt.Sprintf("Could not create root node: %s", err.Error()))
 	}
 
-	log.Printf("Root node created on disk at : %s\n", server.settings.DataPath())
+	if server.settings.IsInMemory() {
+		log.Printf("Root node created in memory\n")
+	} else {
+		log.Printf("Root node created on disk at : %s\n", server.settings.DataPath())
+	}
 }
@@ -4,27 +4,50 @@ import (
 	"errors"
 	"fmt"
 	"ldpserver/ldp"
-	"ldpserver/textstore"
+	"ldpserver/storage"
 	"ldpserver/util"
-	// "log"
+	"log"
+	"sync"
+	"time"
 )
 
 const defaultSlug string = "node"
 
+var RootNodeDeleteError = errors.New("Cannot delete root node")
+
 type Server struct {
 	settings ldp.Settings
 	minter   chan string
-	// this should use an interface so it's not tied to "textStore"
-	nextResource chan textstore.Store
+	// Serializes the creation of new nodes so that two
+	// requests cannot create the same path.
+	createLock *sync.Mutex
+	// Serializes changes to existing nodes so that concurrent
+	// requests (e.g. two POSTs to the same container) don't
+	// overwrite each other's changes.
+	writeLock *sync.Mutex
 }
 
 func NewServer(rootUri string, dataPath string) Server {
-	settings := ldp.SettingsNew(rootUri, dataPath)
+	return NewServerWithSettings(ldp.SettingsNew(rootUri, dataPath))
+}
+
+// NewMemoryServer returns a server that keeps all its
+// nodes in memory and never touches the disk.
+func NewMemoryServer(rootUri string) Server {
+	return NewServerWithSettings(ldp.SettingsInMemory(rootUri))
+}
+
+func NewServerWithSettings(settings ldp.Settings) Server {
 	var server Server
 	server.settings = settings
-	server.createIdFile()
-	server.minter = CreateMinter(server.settings.IdFile())
-	server.nextResource = make(chan textstore.Store)
+	if settings.IsInMemory() {
+		server.minter = CreateMemoryMinter()
+	} else {
+		server.createIdFile()
+		server.minter = CreateMinter(server.settings.IdFile())
+	}
+	server.createLock = &sync.Mutex{}
+	server.writeLock = &sync.Mutex{}
 	server.createRoot()
 	return server
 }
@@ -37,41 +60,95 @@ func (server Server) GetHead(path string) (ldp.Node, error) {
 	return ldp.GetHead(server.settings, path)
 }
 
-func (server Server) PatchNode(path string, triples string) error {
+// The node is only patched (or deleted, see DeleteNode) if it has
+// not been modified after since. A zero time skips this check.
+func (server Server) PatchNode(path string, triples string, contentType string, since time.Time) error {
+	server.writeLock.Lock()
+	defer server.writeLock.Unlock()
 	node, err := ldp.GetNode(server.settings, path, ldp.PreferTriples{})
 	if err != nil {
 		return err
 	}
-	return node.Patch(triples)
+	if err = node.CheckUnmodifiedSince(since); err != nil {
+		return err
+	}
+	return node.Patch(triples, contentType)
 }
 
-func (server Server) DeleteNode(path string) error {
+// Deletes the node at the given path. Containers that have children
+// are only deleted (along with all their descendants) when recursive
+// is true, otherwise ContainerNotEmptyError is returned.
+func (server Server) DeleteNode(path string, recursive bool, since time.Time) error {
 	if isRootPath(path) {
-		return errors.New("Cannot delete root node")
+		return RootNodeDeleteError
 	}
 
+	server.writeLock.Lock()
+	defer server.writeLock.Unlock()
+
 	node, err := ldp.GetNode(server.settings, path, ldp.PreferTriples{})
 	if err != nil {
 		return err
 	}
 
+	if err = node.CheckUnmodifiedSince(since); err != nil {
+		return err
+	}
+
+	if len(node.ChildrenPaths()) > 0 && !recursive {
+		return ldp.ContainerNotEmptyError
+	}
+
 	parentPath := util.ParentUriPath(path)
 	parent, err := server.getContainer(parentPath)
 	if err != nil {
 		return err
 	}
 
-	// First remove the reference to the node to be deleted...
-	err = parent.RemoveContainsUri("<" + node.Uri() + ">")
+	// First delete the requested node (and its children)...
+	err = server.deleteTree(node)
 	if err != nil {
 		return err
 	}
 
-	// ...then delete the requested node
+	// ...then remove the references to it from its parent
+	// (and from the parent's membershipResource)
+	return parent.RemoveChild(node)
+}
+
+// Deletes a node and all its descendants, depth first.
+// Each deleted node leaves a tombstone behind.
+func (server Server) deleteTree(node ldp.Node) error {
+	for _, childPath := range node.ChildrenPaths() {
+		child, err := ldp.GetHead(server.settings, childPath)
+		if err == ldp.NodeNotFoundError || err == ldp.NodeDeletedError {
+			log.Printf("Skipping missing child %s of %s", childPath, node.Uri())
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		err = server.deleteTree(child)
+		if err != nil {
+			return err
+		}
+
+		// The membershipResource of this node might not be part
+		// of the tree being deleted.
+		err = node.RemoveMember(child)
+		if err != nil {
+			return err
+		}
+	}
 	return node.Delete()
 }
 
-func (server Server) addNodeToContainer(node ldp.Node, path string) error {
+// The node is updated in place since adding it to a Direct
+// Container with an isMemberOfRelation changes its graph.
+func (server Server) addNodeToContainer(node *ldp.Node, path string) error {
+	server.writeLock.Lock()
+	defer server.writeLock.Unlock()
 	container, err := server.getContainer(path)
 	if err != nil {
 		return err
@@ -96,16 +173,16 @@ func (server Server) newPathFromSlug(parentPath string, slug string) (string, er
 	return util.UriConcat(parentPath, slug), nil
 }
 
-func (server Server) createResource(path string) textstore.Store {
-	pathOnDisk := util.PathConcat(server.settings.DataPath(), path)
-	// Queue up the creation of a new resource
-	go func(pathOnDisk string) {
-		server.nextResource <- textstore.CreateStore(pathOnDisk)
-	}(pathOnDisk)
+func (server Server) createResource(path string) storage.Store {
+	server.createLock.Lock()
+	defer server.createLock.Unlock()
+	return server.settings.Backend().CreateStore(path)
+}
 
-	// Wait for the new resource to be available.
-	resource := <-server.nextResource
-	return resource
+// Returns true if there is (or was) a node at the path
+func (server Server) isPathUsed(path string) bool {
+	store := server.settings.Backend().NewStore(path)
+	return store.Exists() || store.IsDeleted()
 }
 
 func (server Server) getContainer(path string) (ldp.Node, error) {
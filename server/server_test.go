@@ -2,35 +2,35 @@ package server
 
 import (
 	"fmt"
-	"github.com/hectorcorrea/rdf"
 	"ldpserver/ldp"
+	"ldpserver/rdf"
 	"ldpserver/util"
 	"log"
-	"os"
-	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
-var dataPath string
 var theServer Server
 var rootUrl = "http://localhost:9001/"
 var emptySlug = ""
 
+// No If-Unmodified-Since precondition
+var unconditional time.Time
+
 func init() {
-	dataPath, _ := filepath.Abs(filepath.Dir(os.Args[0]))
-	theServer = NewServer(rootUrl, dataPath)
+	theServer = NewMemoryServer(rootUrl)
 }
 
 func TestBadSlug(t *testing.T) {
-	_, err := theServer.CreateRdfSource("", "/", "/invalid/")
+	_, err := theServer.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, "/", "/invalid/")
 	if err == nil {
 		t.Error("Failed to detect an invalid slug")
 	}
 }
 
 func TestCreateRdf(t *testing.T) {
-	_, err := theServer.CreateRdfSource("", "/", "slugA")
+	_, err := theServer.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, "/", "slugA")
 	if err != nil {
 		t.Errorf("Error creating RDF. Error: %s", err)
 	}
@@ -44,7 +44,7 @@ func TestCreateRdf(t *testing.T) {
 		t.Errorf("RDF source was created but not as RDF")
 	}
 
-	node2, err := theServer.CreateRdfSource("", "/", "slugA")
+	node2, err := theServer.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, "/", "slugA")
 	if err != nil {
 		t.Errorf("Error %s while attemping to create duplicate node", err)
 	}
@@ -55,8 +55,8 @@ func TestCreateRdf(t *testing.T) {
 }
 
 func TestReplaceRdf(t *testing.T) {
-	triples := "<> xx:version \"version1\" ."
-	node, err := theServer.ReplaceRdfSource(triples, "/", "rdf-test", "ignore-etag")
+	triples := "@prefix xx: <http://example.org/> .\n<> xx:version \"version1\" ."
+	node, err := theServer.ReplaceRdfSource(strings.NewReader(triples), rdf.TurtleContentType, "/", "rdf-test", "ignore-etag", unconditional)
 	log.Printf("1. %s", node.Content())
 	if err != nil {
 		t.Errorf("Error creating a new RDF node with replace: %s", err)
@@ -64,23 +64,23 @@ func TestReplaceRdf(t *testing.T) {
 
 	path := node.Path()[1:]
 	etag := node.Etag()
-	triples = "<> xx:version \"version2\" ."
-	node, err = theServer.ReplaceRdfSource(triples, "/", path, etag)
+	triples = "@prefix xx: <http://example.org/> .\n<> xx:version \"version2\" ."
+	node, err = theServer.ReplaceRdfSource(strings.NewReader(triples), rdf.TurtleContentType, "/", path, etag, unconditional)
 	log.Printf("2. %s", node.Content())
 	if err != nil {
 		t.Errorf("Error replacing RDF node: %s", err)
 	}
 
-	if !node.HasTriple("xx:version", "\"version2\"") {
+	if !node.HasTriple(rdf.NewIri("http://example.org/version"), rdf.NewLiteral("version2")) {
 		t.Errorf("Error replacing RDF node. Updated triple not found")
 	}
 
-	_, err = theServer.ReplaceRdfSource(triples, "/", path, "bad-etag")
+	_, err = theServer.ReplaceRdfSource(strings.NewReader(triples), rdf.TurtleContentType, "/", path, "bad-etag", unconditional)
 	if err != ldp.EtagMismatchError {
 		t.Errorf("Failed to detect etag mismatch: %s", err)
 	}
 
-	_, err = theServer.ReplaceRdfSource(triples, "/", path, "")
+	_, err = theServer.ReplaceRdfSource(strings.NewReader(triples), rdf.TurtleContentType, "/", path, "", unconditional)
 	if err != ldp.EtagMissingError {
 		t.Errorf("Failed to detect missing etag: %s", err)
 	}
@@ -88,20 +88,20 @@ func TestReplaceRdf(t *testing.T) {
 
 func TestCreateDirectContainer(t *testing.T) {
 	// Create a helper node
-	helperNode, err := theServer.CreateRdfSource("", "/", "other")
+	helperNode, err := theServer.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, "/", "other")
 
 	// Create the direct container (pointing to the helper node)
 	dcTriple1 := fmt.Sprintf("<> <%s> <%s> .\n", rdf.LdpMembershipResource, helperNode.Uri())
-	dcTriple2 := fmt.Sprintf("<> <%s> <hasXYZ> .\n", rdf.LdpHasMemberRelation)
+	dcTriple2 := fmt.Sprintf("<> <%s> <http://example.org/hasXYZ> .\n", rdf.LdpHasMemberRelation)
 	dcTriples := dcTriple1 + dcTriple2
-	dcNode, err := theServer.CreateRdfSource(dcTriples, "/", "dc")
+	dcNode, err := theServer.CreateRdfSource(strings.NewReader(dcTriples), rdf.TurtleContentType, "/", "dc")
 	if err != nil {
-		t.Errorf("Error creating direct container", err)
+		t.Errorf("Error creating direct container %s", err)
 	}
 
 	dcNode, err = theServer.GetNode(dcNode.Path(), ldp.PreferTriples{})
 	if err != nil {
-		t.Errorf("Error fetching direct container", err)
+		t.Errorf("Error fetching direct container %s", err)
 	}
 
 	if !dcNode.IsBasicContainer() {
@@ -112,24 +112,24 @@ func TestCreateDirectContainer(t *testing.T) {
 	}
 
 	// Add a child to the direct container
-	childNode, err := theServer.CreateRdfSource("", dcNode.Path(), "child")
+	childNode, err := theServer.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, dcNode.Path(), "child")
 	if err != nil {
 		t.Errorf("Error adding child to Direct Container %s", err)
 	}
 
 	// Reload our helper node and make sure the child is referenced on it.
 	helperNode, err = theServer.GetNode(helperNode.Path(), ldp.PreferTriples{})
-	if !helperNode.HasTriple("<hasXYZ>", "<"+childNode.Uri()+">") {
+	if !helperNode.HasTriple(rdf.NewIri("http://example.org/hasXYZ"), rdf.NewIri(childNode.Uri())) {
 		t.Error("Helper node did not get new triple when adding to a Direct Container")
 	}
 }
 
 func TestCreateChildRdf(t *testing.T) {
-	parentNode, _ := theServer.CreateRdfSource("", "/", emptySlug)
+	parentNode, _ := theServer.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, "/", emptySlug)
 
-	rdfNode, err := theServer.CreateRdfSource("", parentNode.Path(), emptySlug)
+	rdfNode, err := theServer.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, parentNode.Path(), emptySlug)
 	if err != nil {
-		t.Errorf("Error creating child RDF node under %s", err, parentNode.Uri())
+		t.Errorf("Error creating child RDF node under %s. Error: %s", parentNode.Uri(), err)
 	}
 
 	if !strings.HasPrefix(rdfNode.Uri(), parentNode.Uri()) || rdfNode.Uri() == parentNode.Uri() {
@@ -137,7 +137,7 @@ func TestCreateChildRdf(t *testing.T) {
 	}
 
 	invalidPath := parentNode.Path() + "/invalid"
-	invalidNode, err := theServer.CreateRdfSource("", invalidPath, emptySlug)
+	invalidNode, err := theServer.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, invalidPath, emptySlug)
 	if err == nil {
 		t.Errorf("A node was added to an invalid path %s %s", err, invalidNode.Uri())
 	}
@@ -152,15 +152,15 @@ func TestCreateChildRdf(t *testing.T) {
 		t.Errorf("Child URI %s does not seem to be under the parent URI %s", nonRdfNode.Uri(), parentNode.Uri())
 	}
 
-	_, err = theServer.CreateRdfSource("", nonRdfNode.Path(), emptySlug)
+	_, err = theServer.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, nonRdfNode.Path(), emptySlug)
 	if err == nil {
 		t.Errorf("A child was added to a non-RDF node! %s", nonRdfNode.Uri())
 	}
 }
 
 func TestCreateRdfWithTriples(t *testing.T) {
-	triples := "<> <b> <c> .\n<x> <y> <z> .\n"
-	node, err := theServer.CreateRdfSource(triples, "/", emptySlug)
+	triples := "<> <http://example.org/b> <http://example.org/c> .\n<http://example.org/x> <http://example.org/y> <http://example.org/z> .\n"
+	node, err := theServer.CreateRdfSource(strings.NewReader(triples), rdf.TurtleContentType, "/", emptySlug)
 	if err != nil || !node.IsRdf() {
 		t.Errorf("Error creating RDF")
 	}
@@ -170,12 +170,12 @@ func TestCreateRdfWithTriples(t *testing.T) {
 		t.Errorf("err %v, uri %s", err, node.Uri())
 	}
 
-	if !node.HasTriple("<b>", "<c>") {
+	if !node.HasTriple(rdf.NewIri("http://example.org/b"), rdf.NewIri("http://example.org/c")) {
 		t.Errorf("Blank node not handled correctly %s", node.Uri())
-		t.Errorf(node.DebugString())
+		t.Error(node.DebugString())
 	}
 
-	if node.HasTriple("x", "z") {
+	if node.HasTriple(rdf.NewIri("x"), rdf.NewIri("z")) {
 		t.Errorf("Unexpected tripled for new subject %s", node.Uri())
 	}
 }
@@ -213,13 +213,13 @@ func TestCreateNonRdf(t *testing.T) {
 func TestReplaceNonRdf(t *testing.T) {
 	path := "/non-rdf-test"
 	reader := util.FakeReaderCloser{Text: "HELLO"}
-	node, err := theServer.ReplaceNonRdfSource(reader, path, "ignored-etag", "")
+	node, err := theServer.ReplaceNonRdfSource(reader, path, "ignored-etag", "", unconditional)
 	if err != nil {
 		t.Errorf("Error creating a new non-RDF node with replace: %s", err)
 	}
 
 	reader2 := util.FakeReaderCloser{Text: "BYE"}
-	node, err = theServer.ReplaceNonRdfSource(reader2, path, node.Etag(), "")
+	node, err = theServer.ReplaceNonRdfSource(reader2, path, node.Etag(), "", unconditional)
 	if err != nil {
 		t.Errorf("Error replacing Non-RDF node: %s", err)
 	}
@@ -228,32 +228,32 @@ func TestReplaceNonRdf(t *testing.T) {
 		t.Errorf("Non-RDF content was not replaced. %s", node.Content())
 	}
 
-	_, err = theServer.ReplaceNonRdfSource(reader, path, "bad-etag", "")
+	_, err = theServer.ReplaceNonRdfSource(reader, path, "bad-etag", "", unconditional)
 	if err != ldp.EtagMismatchError {
 		t.Errorf("Failed to detect etag mismatch: %s", err)
 	}
 
-	_, err = theServer.ReplaceNonRdfSource(reader, path, "", "")
+	_, err = theServer.ReplaceNonRdfSource(reader, path, "", "", unconditional)
 	if err != ldp.EtagMissingError {
 		t.Errorf("Failed to detect missing etag: %s", err)
 	}
 }
 
 func TestPatchRdf(t *testing.T) {
-	triples := "<> <p1> <o1> .\n<> <p2> <o2> .\n"
-	node, _ := theServer.CreateRdfSource(triples, "/", emptySlug)
+	triples := "<> <http://example.org/p1> <http://example.org/o1> .\n<> <http://example.org/p2> <http://example.org/o2> .\n"
+	node, _ := theServer.CreateRdfSource(strings.NewReader(triples), rdf.TurtleContentType, "/", emptySlug)
 	node, _ = theServer.GetNode(node.Path(), ldp.PreferTriples{})
-	if !node.HasTriple("<p1>", "<o1>") || !node.HasTriple("<p2>", "<o2>") {
+	if !node.HasTriple(rdf.NewIri("http://example.org/p1"), rdf.NewIri("http://example.org/o1")) || !node.HasTriple(rdf.NewIri("http://example.org/p2"), rdf.NewIri("http://example.org/o2")) {
 		t.Errorf("Expected triple not found %s", node.Content())
 	}
 
-	newTriples := "<> <p3> <o3> .\n"
-	err := node.Patch(newTriples)
+	newTriples := "<> <http://example.org/p3> <http://example.org/o3> .\n"
+	err := node.Patch(newTriples, rdf.TurtleContentType)
 	if err != nil {
 		t.Errorf("Error during Patch %s", err)
-	} else if !node.HasTriple("<p1>", "<o1>") ||
-		!node.HasTriple("<p2>", "<o2>") ||
-		!node.HasTriple("<p3>", "<o3>") {
+	} else if !node.HasTriple(rdf.NewIri("http://example.org/p1"), rdf.NewIri("http://example.org/o1")) ||
+		!node.HasTriple(rdf.NewIri("http://example.org/p2"), rdf.NewIri("http://example.org/o2")) ||
+		!node.HasTriple(rdf.NewIri("http://example.org/p3"), rdf.NewIri("http://example.org/o3")) {
 		t.Errorf("Expected triple not after patch found %s", node.Content())
 	}
 }
@@ -266,7 +266,617 @@ func TestPatchNonRdf(t *testing.T) {
 		t.Errorf("Unexpected non-RDF content found %s", node.Content())
 	}
 
-	if err := node.Patch("whatever"); err == nil {
+	if err := node.Patch("whatever", rdf.TurtleContentType); err == nil {
 		t.Errorf("Shouldn't be able to patch non-RDF")
 	}
 }
+
+func TestPatchSparqlUpdate(t *testing.T) {
+	title := rdf.NewIri("http://purl.org/dc/terms/title")
+	node, _ := theServer.CreateRdfSource(strings.NewReader("<> <http://purl.org/dc/terms/title> \"old\" ."), rdf.TurtleContentType, "/", emptySlug)
+	update := `PREFIX dc: <http://purl.org/dc/terms/>
+DELETE { <> dc:title ?title } INSERT { <> dc:title "new" } WHERE { <> dc:title ?title }`
+	err := theServer.PatchNode(node.Path(), update, rdf.SparqlUpdateContentType, unconditional)
+	node, _ = theServer.GetNode(node.Path(), ldp.PreferTriples{})
+	if err != nil || node.HasTriple(title, rdf.NewLiteral("old")) || !node.HasTriple(title, rdf.NewLiteral("new")) {
+		t.Errorf("Title not replaced %s %s", err, node.Content())
+	}
+
+	// Nothing is changed if any of the operations is not allowed
+	update = `INSERT DATA { <> <http://purl.org/dc/terms/title> "newer" } ;
+DELETE WHERE { <> <http://www.w3.org/ns/ldp#contains> ?child }`
+	err = theServer.PatchNode("/", update, rdf.SparqlUpdateContentType, unconditional)
+	root, _ := theServer.GetNode("/", ldp.PreferTriples{})
+	if err != ldp.ServerManagedPropertyError || root.HasTriple(title, rdf.NewLiteral("newer")) {
+		t.Errorf("Server-managed property changed: %s", err)
+	}
+
+	err = theServer.PatchNode(node.Path(), "DELETE DATA { <> ?p ?o }", rdf.SparqlUpdateContentType, unconditional)
+	if _, ok := err.(*rdf.ParseError); !ok {
+		t.Errorf("Invalid SPARQL Update not detected: %s", err)
+	}
+}
+
+func TestPatchN3AndLdPatch(t *testing.T) {
+	title := rdf.NewIri("http://purl.org/dc/terms/title")
+	node, _ := theServer.CreateRdfSource(strings.NewReader("<> <http://purl.org/dc/terms/title> \"old\" ."), rdf.TurtleContentType, "/", emptySlug)
+	patch := `@prefix solid: <http://www.w3.org/ns/solid/terms#> .
+_:patch a solid:InsertDeletePatch ;
+    solid:where { ?node <http://purl.org/dc/terms/title> "old" } ;
+    solid:inserts { ?node <http://purl.org/dc/terms/title> "n3" } ;
+    solid:deletes { ?node <http://purl.org/dc/terms/title> "old" } .`
+	err := theServer.PatchNode(node.Path(), patch, rdf.N3ContentType, unconditional)
+	node, _ = theServer.GetNode(node.Path(), ldp.PreferTriples{})
+	if err != nil || node.HasTriple(title, rdf.NewLiteral("old")) || !node.HasTriple(title, rdf.NewLiteral("n3")) {
+		t.Errorf("Title not replaced with N3 Patch %s %s", err, node.Content())
+	}
+
+	// The where formula no longer matches
+	err = theServer.PatchNode(node.Path(), patch, rdf.N3ContentType, unconditional)
+	if err != rdf.NoSolutionError {
+		t.Errorf("N3 Patch conflict not detected: %s", err)
+	}
+
+	patch = `Delete { <> <http://purl.org/dc/terms/title> "n3" } .
+Add { <> <http://purl.org/dc/terms/title> "ldpatch" } .`
+	err = theServer.PatchNode(node.Path(), patch, rdf.LdPatchContentType, unconditional)
+	node, _ = theServer.GetNode(node.Path(), ldp.PreferTriples{})
+	if err != nil || node.HasTriple(title, rdf.NewLiteral("n3")) || !node.HasTriple(title, rdf.NewLiteral("ldpatch")) {
+		t.Errorf("Title not replaced with LD Patch %s %s", err, node.Content())
+	}
+
+	err = theServer.PatchNode("/", "Bind ?child <> / <http://www.w3.org/ns/ldp#contains> ! .", rdf.LdPatchContentType, unconditional)
+	if err != rdf.ManySolutionsError {
+		t.Errorf("LD Patch conflict not detected: %s", err)
+	}
+}
+
+func TestPatchInteractionModel(t *testing.T) {
+	node, _ := theServer.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, "/", emptySlug)
+	patches := map[string]string{
+		"INSERT DATA { <> a <http://www.w3.org/ns/ldp#DirectContainer> }":                                     rdf.SparqlUpdateContentType,
+		"DELETE DATA { <> a <http://www.w3.org/ns/ldp#BasicContainer> }":                                      rdf.SparqlUpdateContentType,
+		"Add { <> a <http://www.w3.org/ns/ldp#NonRDFSource> } .":                                              rdf.LdPatchContentType,
+		"<> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://www.w3.org/ns/ldp#IndirectContainer> .": rdf.TurtleContentType,
+	}
+	for patch, contentType := range patches {
+		err := theServer.PatchNode(node.Path(), patch, contentType, unconditional)
+		if err != ldp.ServerManagedPropertyError {
+			t.Errorf("Change to the interaction model not detected: %s %s", patch, err)
+		}
+	}
+
+	// The types of the node can be sent back on PUT
+	node, _ = theServer.GetNode(node.Path(), ldp.PreferTriples{})
+	triples := fmt.Sprintf("<> a <%s>, <%s> .", rdf.LdpRdfSourceUri, rdf.LdpBasicContainerUri)
+	path, slug := util.DirBasePath(node.Path())
+	if _, err := theServer.ReplaceRdfSource(strings.NewReader(triples), rdf.TurtleContentType, path, slug, node.Etag(), unconditional); err != nil {
+		t.Errorf("Error replacing node with its own types: %s", err)
+	}
+}
+
+func TestPatchServerManagedTriples(t *testing.T) {
+	node, _ := theServer.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, "/", emptySlug)
+	patches := []string{
+		fmt.Sprintf("INSERT DATA { <> <%s> \"text/plain\" }", rdf.ServerContentTypeUri),
+		fmt.Sprintf("DELETE { <> <%s> ?lm } INSERT { <> <%s> \"2001-01-01T00:00:00Z\" } WHERE { <> <%s> ?lm }",
+			rdf.ServerLastModifiedUri, rdf.ServerLastModifiedUri, rdf.ServerLastModifiedUri),
+	}
+	for _, patch := range patches {
+		err := theServer.PatchNode(node.Path(), patch, rdf.SparqlUpdateContentType, unconditional)
+		if err != ldp.ServerManagedPropertyError {
+			t.Errorf("Change to a server-managed triple not detected: %s %s", patch, err)
+		}
+	}
+
+	node, _ = theServer.GetNode(node.Path(), ldp.PreferTriples{})
+	if node.HasTriple(rdf.NewIri(rdf.ServerContentTypeUri), rdf.NewLiteral("text/plain")) {
+		t.Errorf("Content type added by PATCH: %s", node.Content())
+	}
+}
+
+func TestPatchContainerConfig(t *testing.T) {
+	helperNode, _ := theServer.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, "/", emptySlug)
+	container, _ := theServer.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, "/", emptySlug)
+	child, _ := theServer.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, container.Path(), emptySlug)
+
+	// Turn the Basic Container into a Direct Container
+	update := fmt.Sprintf("INSERT DATA { <> <%s> <%s> ; <%s> <http://example.org/hasXYZ> }",
+		rdf.LdpMembershipResource, helperNode.Uri(), rdf.LdpHasMemberRelation)
+	if err := theServer.PatchNode(container.Path(), update, rdf.SparqlUpdateContentType, unconditional); err != nil {
+		t.Fatalf("Error patching container configuration: %s", err)
+	}
+	helperNode, _ = theServer.GetNode(helperNode.Path(), ldp.PreferTriples{})
+	if !helperNode.HasTriple(rdf.NewIri("http://example.org/hasXYZ"), rdf.NewIri(child.Uri())) {
+		t.Errorf("Membership triple not added for existing child after PATCH %s", helperNode.Content())
+	}
+
+	update = fmt.Sprintf("INSERT DATA { <> <%s> <%s> }", rdf.LdpMembershipResource, container.Uri())
+	err := theServer.PatchNode(container.Path(), update, rdf.SparqlUpdateContentType, unconditional)
+	if err != ldp.DuplicateContainerPredicateError {
+		t.Errorf("Failed to detect duplicate membershipResource in PATCH: %s", err)
+	}
+
+	update = fmt.Sprintf("DELETE DATA { <> <%s> <%s> } ; INSERT DATA { <> <%s> <%s/does-not-exist> }",
+		rdf.LdpMembershipResource, helperNode.Uri(), rdf.LdpMembershipResource, rootUrl)
+	err = theServer.PatchNode(container.Path(), update, rdf.SparqlUpdateContentType, unconditional)
+	if err != ldp.MembershipResourceNotFoundError {
+		t.Errorf("Failed to detect missing membershipResource in PATCH: %s", err)
+	}
+}
+
+func TestEtagChangesWithContent(t *testing.T) {
+	node, _ := theServer.CreateRdfSource(strings.NewReader("<> <http://example.org/p> \"one\" ."), rdf.TurtleContentType, "/", emptySlug)
+	etag1 := node.Etag()
+
+	// Replace it right away (i.e. within the same second)
+	path, slug := util.DirBasePath(node.Path())
+	node, err := theServer.ReplaceRdfSource(strings.NewReader("<> <http://example.org/p> \"two\" ."), rdf.TurtleContentType, path, slug, etag1, unconditional)
+	if err != nil {
+		t.Fatalf("Error replacing RDF node: %s", err)
+	}
+
+	if node.Etag() == etag1 {
+		t.Errorf("Etag did not change after replacing the content: %s", etag1)
+	}
+
+	_, err = theServer.ReplaceRdfSource(strings.NewReader("<> <http://example.org/p> \"three\" ."), rdf.TurtleContentType, path, slug, etag1, unconditional)
+	if err != ldp.EtagMismatchError {
+		t.Errorf("Failed to detect a lost update: %s", err)
+	}
+
+	if node.LastModified().IsZero() {
+		t.Errorf("Last modified date not set")
+	}
+}
+
+func TestEtagNonRdf(t *testing.T) {
+	path := "/non-rdf-etag"
+	node, _ := theServer.ReplaceNonRdfSource(util.FakeReaderCloser{Text: "one"}, path, "", "", unconditional)
+	etag1 := node.Etag()
+
+	node, err := theServer.ReplaceNonRdfSource(util.FakeReaderCloser{Text: "two"}, path, etag1, "", unconditional)
+	if err != nil {
+		t.Fatalf("Error replacing non-RDF node: %s", err)
+	}
+
+	if node.Etag() == etag1 {
+		t.Errorf("Etag did not change after replacing the binary: %s", etag1)
+	}
+}
+
+func TestUnmodifiedSince(t *testing.T) {
+	node, _ := theServer.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, "/", emptySlug)
+	update := "INSERT DATA { <> <http://example.org/p> \"one\" }"
+
+	before := node.LastModified().Add(-time.Hour)
+	if err := theServer.PatchNode(node.Path(), update, rdf.SparqlUpdateContentType, before); err != ldp.NodeModifiedError {
+		t.Errorf("Failed to detect a node modified since %s: %s", before, err)
+	}
+	if err := theServer.DeleteNode(node.Path(), false, before); err != ldp.NodeModifiedError {
+		t.Errorf("Failed to detect a node modified before deleting it: %s", err)
+	}
+
+	after := node.LastModified().Add(time.Hour)
+	if err := theServer.PatchNode(node.Path(), update, rdf.SparqlUpdateContentType, after); err != nil {
+		t.Errorf("Error patching a node not modified since %s: %s", after, err)
+	}
+}
+
+func TestEtagPrefer(t *testing.T) {
+	node, _ := theServer.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, "/", emptySlug)
+	node, _ = theServer.GetNode(node.Path(), ldp.PreferTriples{MinimalContainer: true})
+	etag := node.RepresentationEtag()
+	if !strings.HasPrefix(etag, "W/") || etag == "W/"+node.Etag() {
+		t.Errorf("Unexpected ETag for a minimal container: %s", etag)
+	}
+}
+
+func TestEtagContainerChanges(t *testing.T) {
+	parent, _ := theServer.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, "/", emptySlug)
+	etag1 := parent.Etag()
+
+	child, err := theServer.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, parent.Path(), emptySlug)
+	if err != nil {
+		t.Fatalf("Error creating child: %s", err)
+	}
+
+	parent, _ = theServer.GetNode(parent.Path(), ldp.PreferTriples{})
+	etag2 := parent.Etag()
+	if etag2 == etag1 {
+		t.Errorf("Container etag did not change after adding a child")
+	}
+
+	if err := theServer.DeleteNode(child.Path(), false, unconditional); err != nil {
+		t.Fatalf("Error deleting child: %s", err)
+	}
+
+	parent, _ = theServer.GetNode(parent.Path(), ldp.PreferTriples{})
+	if parent.Etag() == etag2 {
+		t.Errorf("Container etag did not change after deleting a child")
+	}
+}
+
+func TestEtagMembershipResourceChanges(t *testing.T) {
+	helperNode, _ := theServer.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, "/", emptySlug)
+	etag1 := helperNode.Etag()
+
+	dcTriples := fmt.Sprintf("<> <%s> <%s> .\n<> <%s> <http://example.org/hasXYZ> .\n",
+		rdf.LdpMembershipResource, helperNode.Uri(), rdf.LdpHasMemberRelation)
+	dcNode, _ := theServer.CreateRdfSource(strings.NewReader(dcTriples), rdf.TurtleContentType, "/", emptySlug)
+	theServer.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, dcNode.Path(), emptySlug)
+
+	helperNode, _ = theServer.GetNode(helperNode.Path(), ldp.PreferTriples{})
+	if helperNode.Etag() == etag1 {
+		t.Errorf("Membership resource etag did not change after adding a member")
+	}
+}
+
+func TestConcurrentChildren(t *testing.T) {
+	parent, _ := theServer.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, "/", emptySlug)
+	done := make(chan bool)
+	for i := 0; i < 10; i++ {
+		go func() {
+			if _, err := theServer.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, parent.Path(), emptySlug); err != nil {
+				t.Errorf("Error creating child: %s", err)
+			}
+			done <- true
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		<-done
+	}
+
+	parent, _ = theServer.GetNode(parent.Path(), ldp.PreferTriples{})
+	count := strings.Count(parent.Content(), rdf.LdpContainsUri)
+	if count != 10 {
+		t.Errorf("Expected 10 children, found %d", count)
+	}
+}
+
+func TestDeleteContainer(t *testing.T) {
+	parent, _ := theServer.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, "/", emptySlug)
+	child, _ := theServer.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, parent.Path(), emptySlug)
+	grandChild, _ := theServer.CreateNonRdfSource(util.FakeReaderCloser{Text: "HELLO"}, child.Path(), emptySlug, "")
+
+	if err := theServer.DeleteNode(parent.Path(), false, unconditional); err != ldp.ContainerNotEmptyError {
+		t.Errorf("Failed to detect a non-empty container: %s", err)
+	}
+
+	if err := theServer.DeleteNode(parent.Path(), true, unconditional); err != nil {
+		t.Fatalf("Error deleting container recursively: %s", err)
+	}
+
+	for _, node := range []ldp.Node{parent, child, grandChild} {
+		_, err := theServer.GetNode(node.Path(), ldp.PreferTriples{})
+		if err != ldp.NodeDeletedError {
+			t.Errorf("Node %s was not deleted: %s", node.Path(), err)
+		}
+	}
+
+	root, _ := theServer.GetNode("/", ldp.PreferTriples{})
+	if root.HasTriple(rdf.NewIri(rdf.LdpContainsUri), rdf.NewIri(parent.Uri())) {
+		t.Errorf("Root node still contains the deleted node")
+	}
+
+	if err := theServer.DeleteNode("/", true, unconditional); err != RootNodeDeleteError {
+		t.Errorf("Failed to prevent deleting the root node: %s", err)
+	}
+}
+
+func TestDeleteDirectContainerMember(t *testing.T) {
+	helperNode, _ := theServer.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, "/", emptySlug)
+	dcTriples := fmt.Sprintf("<> <%s> <%s> .\n<> <%s> <http://example.org/hasXYZ> .\n",
+		rdf.LdpMembershipResource, helperNode.Uri(), rdf.LdpHasMemberRelation)
+	dcNode, _ := theServer.CreateRdfSource(strings.NewReader(dcTriples), rdf.TurtleContentType, "/", emptySlug)
+	child1, _ := theServer.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, dcNode.Path(), emptySlug)
+	child2, _ := theServer.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, dcNode.Path(), emptySlug)
+
+	if err := theServer.DeleteNode(child1.Path(), false, unconditional); err != nil {
+		t.Fatalf("Error deleting member: %s", err)
+	}
+
+	helperNode, _ = theServer.GetNode(helperNode.Path(), ldp.PreferTriples{})
+	if helperNode.HasTriple(rdf.NewIri("http://example.org/hasXYZ"), rdf.NewIri(child1.Uri())) {
+		t.Errorf("Membership triple was not removed for deleted member")
+	}
+	if !helperNode.HasTriple(rdf.NewIri("http://example.org/hasXYZ"), rdf.NewIri(child2.Uri())) {
+		t.Errorf("Membership triple removed for the wrong member")
+	}
+
+	// Deleting the whole Direct Container removes the
+	// membership triples of its children too.
+	if err := theServer.DeleteNode(dcNode.Path(), true, unconditional); err != nil {
+		t.Fatalf("Error deleting direct container: %s", err)
+	}
+
+	helperNode, _ = theServer.GetNode(helperNode.Path(), ldp.PreferTriples{})
+	if helperNode.HasTriple(rdf.NewIri("http://example.org/hasXYZ"), rdf.NewIri(child2.Uri())) {
+		t.Errorf("Membership triple was not removed after deleting the direct container")
+	}
+}
+
+func TestCreateIndirectContainer(t *testing.T) {
+	helperNode, _ := theServer.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, "/", emptySlug)
+	icTriples := fmt.Sprintf("<> <%s> <%s> .\n<> <%s> <http://example.org/hasTopic> .\n<> <%s> <http://example.org/primaryTopic> .\n",
+		rdf.LdpMembershipResource, helperNode.Uri(), rdf.LdpHasMemberRelation, rdf.LdpInsertedContentRelationUri)
+	icNode, err := theServer.CreateRdfSource(strings.NewReader(icTriples), rdf.TurtleContentType, "/", emptySlug)
+	if err != nil {
+		t.Fatalf("Error creating indirect container %s", err)
+	}
+
+	icNode, _ = theServer.GetNode(icNode.Path(), ldp.PreferTriples{})
+	if !icNode.IsIndirectContainer() || icNode.IsDirectContainer() {
+		t.Errorf("Indirect container not detected %s", icNode.Content())
+	}
+
+	childTriples := "<> <http://example.org/primaryTopic> <http://example.org/topic1> .\n"
+	child, err := theServer.CreateRdfSource(strings.NewReader(childTriples), rdf.TurtleContentType, icNode.Path(), emptySlug)
+	if err != nil {
+		t.Fatalf("Error adding child to indirect container %s", err)
+	}
+
+	helperNode, _ = theServer.GetNode(helperNode.Path(), ldp.PreferTriples{})
+	if !helperNode.HasTriple(rdf.NewIri("http://example.org/hasTopic"), rdf.NewIri("http://example.org/topic1")) {
+		t.Errorf("Membership triple not found in membership resource %s", helperNode.Content())
+	}
+	if helperNode.HasTriple(rdf.NewIri("http://example.org/hasTopic"), rdf.NewIri(child.Uri())) {
+		t.Errorf("Child was added as member instead of its inserted content")
+	}
+
+	theServer.DeleteNode(child.Path(), false, unconditional)
+	helperNode, _ = theServer.GetNode(helperNode.Path(), ldp.PreferTriples{})
+	if helperNode.HasTriple(rdf.NewIri("http://example.org/hasTopic"), rdf.NewIri("http://example.org/topic1")) {
+		t.Errorf("Membership triple not removed after deleting child")
+	}
+}
+
+func TestIndirectContainerRemoveMembers(t *testing.T) {
+	helperNode, _ := theServer.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, "/", emptySlug)
+	icTriples := fmt.Sprintf("<> <%s> <%s> .\n<> <%s> <http://example.org/hasTopic> .\n<> <%s> <http://example.org/primaryTopic> .\n",
+		rdf.LdpMembershipResource, helperNode.Uri(), rdf.LdpHasMemberRelation, rdf.LdpInsertedContentRelationUri)
+	icNode, _ := theServer.CreateRdfSource(strings.NewReader(icTriples), rdf.TurtleContentType, "/", emptySlug)
+
+	// Two children with the same topic and one whose topic changes
+	// after it was added to the container.
+	topic := "<> <http://example.org/primaryTopic> <http://example.org/%s> .\n"
+	child1, _ := theServer.CreateRdfSource(strings.NewReader(fmt.Sprintf(topic, "shared")), rdf.TurtleContentType, icNode.Path(), emptySlug)
+	child2, _ := theServer.CreateRdfSource(strings.NewReader(fmt.Sprintf(topic, "shared")), rdf.TurtleContentType, icNode.Path(), emptySlug)
+	child3, _ := theServer.CreateRdfSource(strings.NewReader(fmt.Sprintf(topic, "original")), rdf.TurtleContentType, icNode.Path(), emptySlug)
+
+	update := "DELETE DATA { <> <http://example.org/primaryTopic> <http://example.org/original> } ;\n" +
+		"INSERT DATA { <> <http://example.org/primaryTopic> <http://example.org/changed> }"
+	if err := theServer.PatchNode(child3.Path(), update, rdf.SparqlUpdateContentType, unconditional); err != nil {
+		t.Fatalf("Error updating child of indirect container: %s", err)
+	}
+
+	hasTopic := rdf.NewIri("http://example.org/hasTopic")
+	theServer.DeleteNode(child3.Path(), false, unconditional)
+	helperNode, _ = theServer.GetNode(helperNode.Path(), ldp.PreferTriples{})
+	if helperNode.HasTriple(hasTopic, rdf.NewIri("http://example.org/original")) {
+		t.Errorf("Original membership triple not removed after deleting updated child %s", helperNode.Content())
+	}
+
+	theServer.DeleteNode(child1.Path(), false, unconditional)
+	helperNode, _ = theServer.GetNode(helperNode.Path(), ldp.PreferTriples{})
+	if !helperNode.HasTriple(hasTopic, rdf.NewIri("http://example.org/shared")) {
+		t.Errorf("Membership triple removed while another child still produces it %s", helperNode.Content())
+	}
+
+	theServer.DeleteNode(child2.Path(), false, unconditional)
+	helperNode, _ = theServer.GetNode(helperNode.Path(), ldp.PreferTriples{})
+	if helperNode.HasTriple(hasTopic, rdf.NewIri("http://example.org/shared")) {
+		t.Errorf("Membership triple not removed after deleting the last child %s", helperNode.Content())
+	}
+}
+
+func TestDirectContainerIsMemberOf(t *testing.T) {
+	helperNode, _ := theServer.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, "/", emptySlug)
+	dcTriples := fmt.Sprintf("<> <%s> <%s> .\n<> <%s> <http://example.org/isPartOf> .\n",
+		rdf.LdpMembershipResource, helperNode.Uri(), rdf.LdpIsMemberOfRelation)
+	dcNode, err := theServer.CreateRdfSource(strings.NewReader(dcTriples), rdf.TurtleContentType, "/", emptySlug)
+	if err != nil {
+		t.Fatalf("Error creating direct container %s", err)
+	}
+
+	dcNode, _ = theServer.GetNode(dcNode.Path(), ldp.PreferTriples{})
+	if !dcNode.IsDirectContainer() {
+		t.Errorf("Direct container with isMemberOfRelation not detected %s", dcNode.Content())
+	}
+
+	child, err := theServer.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, dcNode.Path(), emptySlug)
+	if err != nil {
+		t.Fatalf("Error adding child to direct container %s", err)
+	}
+
+	if !child.HasTriple(rdf.NewIri("http://example.org/isPartOf"), rdf.NewIri(helperNode.Uri())) {
+		t.Errorf("Membership triple not returned on new child %s", child.Content())
+	}
+
+	child, _ = theServer.GetNode(child.Path(), ldp.PreferTriples{})
+	if !child.HasTriple(rdf.NewIri("http://example.org/isPartOf"), rdf.NewIri(helperNode.Uri())) {
+		t.Errorf("Membership triple not saved on child %s", child.Content())
+	}
+
+	helperNode, _ = theServer.GetNode(helperNode.Path(), ldp.PreferTriples{})
+	if strings.Contains(helperNode.Content(), "isPartOf") {
+		t.Errorf("Unexpected membership triple on membership resource %s", helperNode.Content())
+	}
+
+	if err := theServer.DeleteNode(child.Path(), false, unconditional); err != nil {
+		t.Errorf("Error deleting child %s", err)
+	}
+}
+
+func TestDirectContainerValidation(t *testing.T) {
+	missing := fmt.Sprintf("<> <%s> <%s/does-not-exist> .\n<> <%s> <http://example.org/hasXYZ> .\n",
+		rdf.LdpMembershipResource, rootUrl, rdf.LdpHasMemberRelation)
+	_, err := theServer.CreateRdfSource(strings.NewReader(missing), rdf.TurtleContentType, "/", emptySlug)
+	if err != ldp.MembershipResourceNotFoundError {
+		t.Errorf("Failed to detect missing membershipResource: %s", err)
+	}
+
+	external := fmt.Sprintf("<> <%s> <http://other.org/x> .\n<> <%s> <http://example.org/hasXYZ> .\n",
+		rdf.LdpMembershipResource, rdf.LdpHasMemberRelation)
+	_, err = theServer.CreateRdfSource(strings.NewReader(external), rdf.TurtleContentType, "/", emptySlug)
+	if err != ldp.MembershipResourceNotFoundError {
+		t.Errorf("Failed to detect external membershipResource: %s", err)
+	}
+
+	duplicate := fmt.Sprintf("<> <%s> <http://example.org/hasXYZ> .\n<> <%s> <http://example.org/hasABC> .\n",
+		rdf.LdpHasMemberRelation, rdf.LdpHasMemberRelation)
+	_, err = theServer.CreateRdfSource(strings.NewReader(duplicate), rdf.TurtleContentType, "/", emptySlug)
+	if err != ldp.DuplicateContainerPredicateError {
+		t.Errorf("Failed to detect duplicated hasMemberRelation: %s", err)
+	}
+
+	self := fmt.Sprintf("<> <%s> <> .\n<> <%s> <http://example.org/hasXYZ> .\n",
+		rdf.LdpMembershipResource, rdf.LdpHasMemberRelation)
+	if _, err = theServer.CreateRdfSource(strings.NewReader(self), rdf.TurtleContentType, "/", emptySlug); err != nil {
+		t.Errorf("Error creating self-referencing direct container: %s", err)
+	}
+}
+
+func TestDirectContainerCycle(t *testing.T) {
+	helperNode, _ := theServer.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, "/", emptySlug)
+	dcTriples := fmt.Sprintf("<> <%s> <%s> .\n<> <%s> <http://example.org/hasXYZ> .\n",
+		rdf.LdpMembershipResource, helperNode.Uri(), rdf.LdpHasMemberRelation)
+	dc1, err := theServer.CreateRdfSource(strings.NewReader(dcTriples), rdf.TurtleContentType, "/", emptySlug)
+	if err != nil {
+		t.Fatalf("Error creating direct container %s", err)
+	}
+
+	dcTriples = fmt.Sprintf("<> <%s> <%s> .\n<> <%s> <http://example.org/hasXYZ> .\n",
+		rdf.LdpMembershipResource, dc1.Uri(), rdf.LdpHasMemberRelation)
+	dc2, err := theServer.CreateRdfSource(strings.NewReader(dcTriples), rdf.TurtleContentType, "/", emptySlug)
+	if err != nil {
+		t.Fatalf("Error creating second direct container %s", err)
+	}
+
+	// Point the first container to the second one.
+	dcTriples = fmt.Sprintf("<> <%s> <%s> .\n<> <%s> <http://example.org/hasXYZ> .\n",
+		rdf.LdpMembershipResource, dc2.Uri(), rdf.LdpHasMemberRelation)
+	_, err = theServer.ReplaceRdfSource(strings.NewReader(dcTriples), rdf.TurtleContentType, "/", dc1.Path()[1:], dc1.Etag(), unconditional)
+	if err != ldp.MembershipCycleError {
+		t.Errorf("Failed to detect membership cycle: %s", err)
+	}
+}
+
+func TestDirectContainerBackfill(t *testing.T) {
+	helper1, _ := theServer.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, "/", emptySlug)
+	helper2, _ := theServer.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, "/", emptySlug)
+	dcTriples := fmt.Sprintf("<> <%s> <%s> .\n<> <%s> <http://example.org/hasXYZ> .\n",
+		rdf.LdpMembershipResource, helper1.Uri(), rdf.LdpHasMemberRelation)
+	dcNode, err := theServer.CreateRdfSource(strings.NewReader(dcTriples), rdf.TurtleContentType, "/", emptySlug)
+	if err != nil {
+		t.Fatalf("Error creating direct container %s", err)
+	}
+
+	child, _ := theServer.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, dcNode.Path(), emptySlug)
+	dcNode, _ = theServer.GetNode(dcNode.Path(), ldp.PreferTriples{})
+
+	// Move the membership triples to the second helper node.
+	dcTriples = fmt.Sprintf("<> <%s> <%s> .\n<> <%s> <http://example.org/hasXYZ> .\n",
+		rdf.LdpMembershipResource, helper2.Uri(), rdf.LdpHasMemberRelation)
+	dcNode, err = theServer.ReplaceRdfSource(strings.NewReader(dcTriples), rdf.TurtleContentType, "/", dcNode.Path()[1:], dcNode.Etag(), unconditional)
+	if err != nil {
+		t.Fatalf("Error replacing direct container %s", err)
+	}
+
+	if !dcNode.HasTriple(rdf.NewIri(rdf.LdpContainsUri), rdf.NewIri(child.Uri())) {
+		t.Errorf("Containment triple lost on replace %s", dcNode.Content())
+	}
+
+	helper1, _ = theServer.GetNode(helper1.Path(), ldp.PreferTriples{})
+	if helper1.HasTriple(rdf.NewIri("http://example.org/hasXYZ"), rdf.NewIri(child.Uri())) {
+		t.Errorf("Membership triple not removed from old membershipResource %s", helper1.Content())
+	}
+
+	helper2, _ = theServer.GetNode(helper2.Path(), ldp.PreferTriples{})
+	if !helper2.HasTriple(rdf.NewIri("http://example.org/hasXYZ"), rdf.NewIri(child.Uri())) {
+		t.Errorf("Membership triple not added to new membershipResource %s", helper2.Content())
+	}
+}
+
+func TestPreferHeader(t *testing.T) {
+	header := fmt.Sprintf("return=representation; include=\"%s %s\"; omit=\"%s\"",
+		rdf.LdpPreferMembership, rdf.ServerPreferInboundReferences, rdf.LdpPreferContainment)
+	pref := ldp.PreferTriplesFromHeader([]string{header})
+	expected := ldp.PreferTriples{Membership: true, InboundReferences: true, OmitContainment: true}
+	if pref != expected {
+		t.Errorf("Prefer header not parsed: %+v", pref)
+	}
+
+	pref = ldp.PreferTriplesFromHeader([]string{"return=minimal"})
+	if !pref.Minimal || pref.Applied() != "return=minimal" {
+		t.Errorf("Prefer return=minimal not parsed: %+v", pref)
+	}
+
+	pref = ldp.PreferTriplesFromHeader([]string{"respond-async", "return=representation; include=\"http://x/unknown\""})
+	if pref != (ldp.PreferTriples{}) || pref.Applied() != "" {
+		t.Errorf("Unexpected preferences parsed: %+v", pref)
+	}
+}
+
+func TestPreferTriples(t *testing.T) {
+	parent, _ := theServer.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, "/", emptySlug)
+	child, _ := theServer.CreateRdfSource(strings.NewReader(""), rdf.TurtleContentType, parent.Path(), emptySlug)
+	other, _ := theServer.CreateRdfSource(strings.NewReader(fmt.Sprintf("<> <http://example.org/seeAlso> <%s> .", child.Uri())), rdf.TurtleContentType, "/", emptySlug)
+
+	pref := ldp.PreferTriples{OmitContainment: true, OmitServerManaged: true}
+	parent, _ = theServer.GetNode(parent.Path(), pref)
+	content := parent.ContentPref(pref)
+	if strings.Contains(content, rdf.LdpContainsUri) || strings.Contains(content, rdf.ServerETagUri) {
+		t.Errorf("Omitted triples returned %s", content)
+	}
+
+	if applied := parent.Headers()["Preference-Applied"]; len(applied) != 1 || applied[0] != "return=representation" {
+		t.Errorf("Unexpected Preference-Applied header %v", applied)
+	}
+
+	// Membership triples only apply to Direct and Indirect Containers
+	pref = ldp.PreferTriples{Membership: true}
+	parent, _ = theServer.GetNode(parent.Path(), pref)
+	if applied, ok := parent.Headers()["Preference-Applied"]; ok {
+		t.Errorf("Unexpected Preference-Applied header for a Basic Container %v", applied)
+	}
+	if parent.RepresentationEtag() != parent.Etag() {
+		t.Errorf("ETag changed by a preference that was not applied %s", parent.RepresentationEtag())
+	}
+
+	pref = ldp.PreferTriples{InboundReferences: true}
+	child, _ = theServer.GetNode(child.Path(), pref)
+	content = child.ContentPref(pref)
+	if !strings.Contains(content, "<"+parent.Uri()+"> <"+rdf.LdpContainsUri+"> <"+child.Uri()+">") {
+		t.Errorf("Inbound containment triple not returned %s", content)
+	}
+	if !strings.Contains(content, "<"+other.Uri()+"> <http://example.org/seeAlso> <"+child.Uri()+">") {
+		t.Errorf("Inbound reference not returned %s", content)
+	}
+}
+
+func TestCreateRdfJsonLd(t *testing.T) {
+	jsonLd := `{"@id": "", "http://purl.org/dc/terms/title": "hello", "http://x/seeAlso": {"@id": "http://x/a"}}`
+	node, err := theServer.CreateRdfSource(strings.NewReader(jsonLd), rdf.JsonLdContentType, "/", emptySlug)
+	if err != nil {
+		t.Fatalf("Error creating RDF node from JSON-LD: %s", err)
+	}
+
+	node, _ = theServer.GetNode(node.Path(), ldp.PreferTriples{})
+	if !node.IsRdf() || !node.HasTriple(rdf.NewIri("http://purl.org/dc/terms/title"), rdf.NewLiteral("hello")) ||
+		!node.HasTriple(rdf.NewIri("http://x/seeAlso"), rdf.NewIri("http://x/a")) {
+		t.Errorf("Triples from JSON-LD not found %s", node.Content())
+	}
+
+	nTriples := "<> <http://x/p> \"v\" .\n"
+	err = theServer.PatchNode(node.Path(), nTriples, rdf.NTriplesContentType, unconditional)
+	node, _ = theServer.GetNode(node.Path(), ldp.PreferTriples{})
+	if err != nil || !node.HasTriple(rdf.NewIri("http://x/p"), rdf.NewLiteral("v")) {
+		t.Errorf("Triples from N-Triples not found %s %s", err, node.Content())
+	}
+
+	_, err = theServer.CreateRdfSource(strings.NewReader(`{"@id": "", `), rdf.JsonLdContentType, "/", emptySlug)
+	if err == nil {
+		t.Errorf("Invalid JSON-LD not detected")
+	}
+}
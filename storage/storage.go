@@ -0,0 +1,42 @@
+// Package storage defines the interfaces that a storage backend
+// must implement so that nodes can be persisted. The textstore
+// package is the default implementation (one folder per node.)
+package storage
+
+import (
+	"errors"
+	"io"
+)
+
+var AlreadyExistsError = errors.New("Already exists")
+var CreateDeletedError = errors.New("Attempting to create a store that has been previously deleted")
+
+// Store handles the persistence of a single node: its metadata
+// (the node's triples), its binary content (only for non-RDF
+// sources), and a tombstone once the node has been deleted.
+type Store interface {
+	// Error returns the error (if any) that occurred when the
+	// store was created via Backend.CreateStore.
+	Error() error
+	Exists() bool
+	IsDeleted() bool
+	Delete() error
+	SaveMetaFile(content string) error
+	AppendToMetaFile(content string) error
+	ReadMetaFile() (string, error)
+	SaveDataFile(reader io.Reader) error
+	// ReadDataFile returns a reader to the binary content.
+	// The caller is responsible for closing it.
+	ReadDataFile() (io.ReadSeekCloser, error)
+}
+
+// Backend gives access to the Store of each node. Nodes are
+// identified by their path relative to the root (e.g. /node1)
+type Backend interface {
+	// NewStore returns the store for an existing path.
+	NewStore(path string) Store
+	// CreateStore creates the store for a new path. Errors are
+	// reported via Store.Error(), for example AlreadyExistsError
+	// or CreateDeletedError.
+	CreateStore(path string) Store
+}
@@ -1,25 +1,42 @@
 package textstore
 
 import (
-	"errors"
 	"io"
 	"ldpserver/fileio"
+	"ldpserver/storage"
 	"ldpserver/util"
 	"os"
 )
 
-var AlreadyExistsError = errors.New("Already exists")
-var CreateDeletedError = errors.New("Attempting to create a store that has been previously deleted")
+var AlreadyExistsError = storage.AlreadyExistsError
+var CreateDeletedError = storage.CreateDeletedError
 
 const metaFile string = "meta.rdf"
 const dataFile string = "data.bin"
 const deletedMarkFile string = "deleted"
 
+// Backend stores each node on its own folder under dataPath.
+type Backend struct {
+	dataPath string
+}
+
 type Store struct {
 	folder string
 	err    error
 }
 
+func NewBackend(dataPath string) Backend {
+	return Backend{dataPath: dataPath}
+}
+
+func (backend Backend) NewStore(path string) storage.Store {
+	return NewStore(util.PathConcat(backend.dataPath, path))
+}
+
+func (backend Backend) CreateStore(path string) storage.Store {
+	return CreateStore(util.PathConcat(backend.dataPath, path))
+}
+
 func NewStore(folder string) Store {
 	return Store{folder: folder}
 }
@@ -29,7 +46,7 @@ func CreateStore(folder string) Store {
 	switch {
 	case store.Exists():
 		store.err = AlreadyExistsError
-	case store.isDeleted():
+	case store.IsDeleted():
 		store.err = CreateDeletedError
 	default:
 		store.err = store.SaveMetaFile("")
@@ -75,14 +92,17 @@ func (store Store) AppendToMetaFile(content string) error {
 	return fileio.AppendToFile(fullFilename, content)
 }
 
-func (store Store) SaveDataFile(reader io.ReadCloser) error {
+func (store Store) SaveDataFile(reader io.Reader) error {
 	fullFilename := util.PathConcat(store.folder, dataFile)
 	out, err := os.Create(fullFilename)
 	if err != nil {
 		return err
 	}
-	defer out.Close()
-	io.Copy(out, reader)
+	_, err = io.Copy(out, reader)
+	if err != nil {
+		out.Close()
+		return err
+	}
 	return out.Close()
 }
 
@@ -92,13 +112,12 @@ func (store Store) ReadMetaFile() (string, error) {
 	return fileio.ReadFile(fullFilename)
 }
 
-// Should this return a reader?
-func (store Store) ReadDataFile() (string, error) {
+func (store Store) ReadDataFile() (io.ReadSeekCloser, error) {
 	fullFilename := util.PathConcat(store.folder, dataFile)
-	return fileio.ReadFile(fullFilename)
+	return os.Open(fullFilename)
 }
 
-func (store Store) isDeleted() bool {
+func (store Store) IsDeleted() bool {
 	deletedFile := util.PathConcat(store.folder, deletedMarkFile)
 	return fileio.FileExists(deletedFile)
 }
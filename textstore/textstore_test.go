@@ -1,6 +1,7 @@
 package textstore
 
 import (
+	"ldpserver/fileio"
 	"ldpserver/util"
 	"os"
 	"path/filepath"
@@ -29,13 +30,15 @@ func TestTextStore(t *testing.T) {
 		t.Errorf("Error %s saving text to data file at %s", err, dataPath)
 	}
 
-	text, err := store.ReadDataFile()
+	reader2, err := store.ReadDataFile()
 	if err != nil {
-		t.Errorf("Error %s reading text from data file at %s", err, dataPath)
+		t.Fatalf("Error %s reading text from data file at %s", err, dataPath)
 	}
 
+	text, err := fileio.ReaderToString(reader2)
+	reader2.Close()
 	if text != "hello" {
-		t.Errorf("Unexpected text %s found when reading store at %s", err, dataPath)
+		t.Errorf("Unexpected text %s found when reading store at %s. Error: %s", text, dataPath, err)
 	}
 
 	store = CreateStore(dataPath)
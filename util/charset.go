@@ -0,0 +1,162 @@
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var UnsupportedCharsetError = errors.New("Unsupported charset")
+
+const byteOrderMark = '\uFEFF'
+
+// Characters 0x80 to 0x9F in windows-1252. The rest of the
+// characters are the same as in ISO-8859-1. Undefined characters
+// are mapped to the Unicode replacement character.
+var windows1252 = [32]rune{
+	'€', utf8.RuneError, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', utf8.RuneError, 'Ž', utf8.RuneError,
+	utf8.RuneError, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', utf8.RuneError, 'ž', 'Ÿ',
+}
+
+// DecodeCharset returns the text in data decoded from the given
+// charset (e.g. the charset parameter of a Content-Type header.)
+// See NewCharsetReader for the charsets supported.
+func DecodeCharset(data []byte, charset string) (string, error) {
+	reader, err := NewCharsetReader(bytes.NewReader(data), charset)
+	if err != nil {
+		return "", err
+	}
+	text, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return "", err
+	}
+	return string(text), nil
+}
+
+// NewCharsetReader returns a reader that decodes the text read from
+// reader from the given charset into UTF-8 as it is read. UTF-8 is
+// assumed if the charset is empty. Only UTF-8, UTF-16, US-ASCII,
+// ISO-8859-1, and windows-1252 are supported, any other charset
+// results in UnsupportedCharsetError. A leading byte order mark
+// is removed.
+func NewCharsetReader(reader io.Reader, charset string) (io.Reader, error) {
+	source := bufio.NewReader(reader)
+	var decode func(*bufio.Reader) (rune, error)
+	switch strings.ToLower(strings.TrimSpace(charset)) {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		decode = readUtf8
+	case "iso-8859-1", "iso8859-1", "latin1", "l1":
+		decode = singleByteDecoder(false)
+	case "windows-1252", "cp1252":
+		decode = singleByteDecoder(true)
+	case "utf-16":
+		// Big endian unless there is a byte order mark
+		bigEndian := true
+		if bom, _ := source.Peek(2); len(bom) == 2 && bom[0] == 0xFF && bom[1] == 0xFE {
+			bigEndian = false
+			source.Discard(2)
+		} else if len(bom) == 2 && bom[0] == 0xFE && bom[1] == 0xFF {
+			source.Discard(2)
+		}
+		decode = utf16Decoder(bigEndian)
+	case "utf-16be":
+		decode = utf16Decoder(true)
+	case "utf-16le":
+		decode = utf16Decoder(false)
+	default:
+		return nil, UnsupportedCharsetError
+	}
+	return &charsetReader{source: source, decode: decode}, nil
+}
+
+type charsetReader struct {
+	source  *bufio.Reader
+	decode  func(*bufio.Reader) (rune, error)
+	started bool
+	pending []byte // decoded text not returned yet
+	err     error
+}
+
+func (reader *charsetReader) Read(data []byte) (int, error) {
+	for len(reader.pending) < len(data) && reader.err == nil {
+		char, err := reader.decode(reader.source)
+		if err != nil {
+			reader.err = err
+			break
+		}
+		if !reader.started {
+			reader.started = true
+			if char == byteOrderMark {
+				continue
+			}
+		}
+		reader.pending = utf8.AppendRune(reader.pending, char)
+	}
+
+	n := copy(data, reader.pending)
+	reader.pending = reader.pending[n:]
+	if n == 0 {
+		return 0, reader.err
+	}
+	return n, nil
+}
+
+func readUtf8(source *bufio.Reader) (rune, error) {
+	char, size, err := source.ReadRune()
+	if err == nil && char == utf8.RuneError && size == 1 {
+		return 0, errors.New("Invalid UTF-8 text")
+	}
+	return char, err
+}
+
+func singleByteDecoder(isWindows1252 bool) func(*bufio.Reader) (rune, error) {
+	return func(source *bufio.Reader) (rune, error) {
+		char, err := source.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if isWindows1252 && char >= 0x80 && char <= 0x9F {
+			return windows1252[char-0x80], nil
+		}
+		return rune(char), nil
+	}
+}
+
+// Surrogates that are not part of a valid pair are decoded as
+// the Unicode replacement character.
+func utf16Decoder(bigEndian bool) func(*bufio.Reader) (rune, error) {
+	unit := func(data []byte) rune {
+		if bigEndian {
+			return rune(data[0])<<8 | rune(data[1])
+		}
+		return rune(data[1])<<8 | rune(data[0])
+	}
+
+	return func(source *bufio.Reader) (rune, error) {
+		var data [2]byte
+		_, err := io.ReadFull(source, data[:])
+		if err == io.ErrUnexpectedEOF {
+			return 0, errors.New("Invalid UTF-16 text")
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		char := unit(data[:])
+		if !utf16.IsSurrogate(char) {
+			return char, nil
+		}
+		if next, _ := source.Peek(2); len(next) == 2 {
+			if pair := utf16.DecodeRune(char, unit(next)); pair != utf8.RuneError {
+				source.Discard(2)
+				return pair, nil
+			}
+		}
+		return utf8.RuneError, nil
+	}
+}
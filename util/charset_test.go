@@ -0,0 +1,61 @@
+package util
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestDecodeCharset(t *testing.T) {
+	if text, err := DecodeCharset([]byte("caf\xc3\xa9"), ""); err != nil || text != "café" {
+		t.Errorf("UTF-8 not decoded: %s %s", text, err)
+	}
+
+	if _, err := DecodeCharset([]byte("caf\xe9"), "utf-8"); err == nil {
+		t.Errorf("Invalid UTF-8 not detected")
+	}
+
+	if text, err := DecodeCharset([]byte("caf\xe9"), "ISO-8859-1"); err != nil || text != "café" {
+		t.Errorf("ISO-8859-1 not decoded: %s %s", text, err)
+	}
+
+	if text, err := DecodeCharset([]byte("\x80 caf\xe9"), "windows-1252"); err != nil || text != "€ café" {
+		t.Errorf("windows-1252 not decoded: %s %s", text, err)
+	}
+
+	if text, err := DecodeCharset([]byte("\xff\xfec\x00a\x00f\x00\xe9\x00"), "utf-16"); err != nil || text != "café" {
+		t.Errorf("UTF-16 not decoded: %s %s", text, err)
+	}
+
+	if text, err := DecodeCharset([]byte("\x00c\x00a\x00f\x00\xe9"), "utf-16be"); err != nil || text != "café" {
+		t.Errorf("UTF-16BE not decoded: %s %s", text, err)
+	}
+
+	if _, err := DecodeCharset([]byte("hello"), "koi8-r"); err != UnsupportedCharsetError {
+		t.Errorf("Unsupported charset not detected: %s", err)
+	}
+}
+
+func TestCharsetReader(t *testing.T) {
+	// U+1F600 is encoded as a surrogate pair in UTF-16
+	utf16 := "\xff\xfeh\x00i\x00 \x00\x3d\xd8\x00\xde"
+	reader, err := NewCharsetReader(iotest.OneByteReader(strings.NewReader(utf16)), "UTF-16")
+	if err != nil {
+		t.Fatalf("Error creating UTF-16 reader: %s", err)
+	}
+	text, err := ioutil.ReadAll(iotest.OneByteReader(reader))
+	if err != nil || string(text) != "hi \U0001F600" {
+		t.Errorf("UTF-16 not decoded one byte at a time: %s %s", text, err)
+	}
+
+	reader, _ = NewCharsetReader(strings.NewReader("\xef\xbb\xbfcaf\xc3\xa9"), "utf-8")
+	if text, err := ioutil.ReadAll(reader); err != nil || string(text) != "café" {
+		t.Errorf("UTF-8 byte order mark not removed: %s %s", text, err)
+	}
+
+	reader, _ = NewCharsetReader(strings.NewReader("ok\xe9"), "")
+	if text, err := ioutil.ReadAll(reader); err == nil || string(text) != "ok" {
+		t.Errorf("Invalid UTF-8 not detected after valid text: %s %s", text, err)
+	}
+}
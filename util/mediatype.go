@@ -0,0 +1,92 @@
+package util
+
+import (
+	"mime"
+	"strconv"
+	"strings"
+)
+
+type mediaRange struct {
+	mediaType string
+	params    map[string]string
+	q         float64
+}
+
+// NegotiateMediaType selects the best of the offered media types
+// for the values of the Accept header of a request, taking into
+// account q-values and wildcards (https://tools.ietf.org/html/rfc7231#section-5.3.2)
+// Offers should be given in the order preferred by the server.
+// Returns the selected media type, the parameters (other than q)
+// of the media range that matched it, and false if none of the
+// offers is acceptable.
+func NegotiateMediaType(accept []string, offers []string) (string, map[string]string, bool) {
+	ranges := parseAccept(accept)
+	if len(ranges) == 0 && len(offers) > 0 {
+		return offers[0], map[string]string{}, true
+	}
+
+	best, bestQ, bestSpecificity := -1, 0.0, 0
+	var bestParams map[string]string
+	for i, offer := range offers {
+		q, specificity, params := 0.0, 0, map[string]string{}
+		for _, accepted := range ranges {
+			s := matchMediaRange(accepted.mediaType, offer)
+			if s > specificity {
+				q, specificity, params = accepted.q, s, accepted.params
+			}
+		}
+		if q > bestQ || (q == bestQ && q > 0 && specificity > bestSpecificity) {
+			best, bestQ, bestSpecificity, bestParams = i, q, specificity, params
+		}
+	}
+
+	if best == -1 {
+		return "", nil, false
+	}
+	return offers[best], bestParams, true
+}
+
+func parseAccept(headers []string) []mediaRange {
+	ranges := []mediaRange{}
+	for _, header := range headers {
+		for _, item := range splitQuoted(header, ',') {
+			if item == "" {
+				continue
+			}
+			if item == "*" {
+				// Sent by some old clients
+				item = "*/*"
+			}
+			mediaType, params, err := mime.ParseMediaType(item)
+			if err != nil {
+				continue
+			}
+			q := 1.0
+			if value, ok := params["q"]; ok {
+				q, err = strconv.ParseFloat(value, 64)
+				if err != nil || q < 0 || q > 1 {
+					continue
+				}
+				delete(params, "q")
+			}
+			ranges = append(ranges, mediaRange{mediaType: mediaType, params: params, q: q})
+		}
+	}
+	return ranges
+}
+
+// Returns how specific the match of the media range to the
+// media type is (3 for type/subtype, 2 for type/*, 1 for */*)
+// or 0 if they don't match.
+func matchMediaRange(mediaRange, mediaType string) int {
+	switch {
+	case mediaRange == mediaType:
+		return 3
+	case mediaRange == "*/*":
+		return 1
+	case strings.HasSuffix(mediaRange, "/*") &&
+		strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*")):
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,43 @@
+package util
+
+import "testing"
+
+var testOffers = []string{"text/turtle", "application/ld+json", "application/n-triples"}
+
+func TestNegotiateMediaType(t *testing.T) {
+	if mediaType, _, ok := NegotiateMediaType(nil, testOffers); !ok || mediaType != "text/turtle" {
+		t.Errorf("Default media type not selected: %s", mediaType)
+	}
+
+	accept := []string{"text/turtle;q=0.5, application/ld+json"}
+	if mediaType, _, _ := NegotiateMediaType(accept, testOffers); mediaType != "application/ld+json" {
+		t.Errorf("Media type with highest q not selected: %s", mediaType)
+	}
+
+	accept = []string{"*/*;q=0.1", "application/n-triples;q=0.8"}
+	if mediaType, _, _ := NegotiateMediaType(accept, testOffers); mediaType != "application/n-triples" {
+		t.Errorf("Media type with highest q not selected: %s", mediaType)
+	}
+
+	accept = []string{"application/*, text/html"}
+	if mediaType, _, _ := NegotiateMediaType(accept, testOffers); mediaType != "application/ld+json" {
+		t.Errorf("Wildcard media type not selected: %s", mediaType)
+	}
+
+	accept = []string{"*/*, text/turtle;q=0"}
+	if mediaType, _, _ := NegotiateMediaType(accept, testOffers); mediaType != "application/ld+json" {
+		t.Errorf("Media type with q=0 selected: %s", mediaType)
+	}
+}
+
+func TestNegotiateMediaTypeParams(t *testing.T) {
+	accept := []string{`application/ld+json; profile="http://www.w3.org/ns/json-ld#expanded"`}
+	mediaType, params, ok := NegotiateMediaType(accept, testOffers)
+	if !ok || mediaType != "application/ld+json" || params["profile"] != "http://www.w3.org/ns/json-ld#expanded" {
+		t.Errorf("Unexpected media type or parameters: %s %v", mediaType, params)
+	}
+
+	if _, _, ok := NegotiateMediaType([]string{"text/html, image/*"}, testOffers); ok {
+		t.Errorf("Unacceptable media type selected")
+	}
+}
@@ -0,0 +1,108 @@
+package util
+
+import (
+	"strings"
+)
+
+// Preference is a single preference from a Prefer header
+// (https://tools.ietf.org/html/rfc7240) e.g.
+//
+//	return=representation; include="http://x/a http://x/b"
+//
+// is parsed as Name "return", Value "representation" and a
+// parameter "include" with value "http://x/a http://x/b".
+// Names are lowercased and values are unquoted.
+type Preference struct {
+	Name   string
+	Value  string
+	Params map[string]string
+}
+
+// ParsePrefer parses the values of one or more Prefer headers.
+// Per RFC 7240 only the first instance of a preference is
+// considered if it is specified more than once.
+func ParsePrefer(headers []string) map[string]Preference {
+	prefs := map[string]Preference{}
+	for _, header := range headers {
+		for _, item := range splitQuoted(header, ',') {
+			pref, ok := parsePreference(item)
+			if !ok {
+				continue
+			}
+			if _, found := prefs[pref.Name]; !found {
+				prefs[pref.Name] = pref
+			}
+		}
+	}
+	return prefs
+}
+
+// Param returns the value of the given parameter and whether
+// the parameter was present.
+func (pref Preference) Param(name string) (string, bool) {
+	value, ok := pref.Params[strings.ToLower(name)]
+	return value, ok
+}
+
+func parsePreference(text string) (Preference, bool) {
+	pref := Preference{Params: map[string]string{}}
+	for i, part := range splitQuoted(text, ';') {
+		name, value := parseNameValue(part)
+		if i == 0 {
+			if name == "" {
+				return pref, false
+			}
+			pref.Name = name
+			pref.Value = value
+		} else if name != "" {
+			if _, found := pref.Params[name]; !found {
+				pref.Params[name] = value
+			}
+		}
+	}
+	return pref, true
+}
+
+func parseNameValue(text string) (string, string) {
+	name := text
+	value := ""
+	if index := strings.Index(text, "="); index != -1 {
+		name = text[:index]
+		value = unquote(strings.TrimSpace(text[index+1:]))
+	}
+	return strings.ToLower(strings.TrimSpace(name)), value
+}
+
+// Splits the text on the given separator except when the
+// separator is inside a quoted string.
+func splitQuoted(text string, separator byte) []string {
+	parts := []string{}
+	inQuotes := false
+	start := 0
+	for i := 0; i < len(text); i++ {
+		switch {
+		case text[i] == '\\' && inQuotes:
+			i++
+		case text[i] == '"':
+			inQuotes = !inQuotes
+		case text[i] == separator && !inQuotes:
+			parts = append(parts, strings.TrimSpace(text[start:i]))
+			start = i + 1
+		}
+	}
+	return append(parts, strings.TrimSpace(text[start:]))
+}
+
+func unquote(text string) string {
+	if len(text) < 2 || text[0] != '"' || text[len(text)-1] != '"' {
+		return text
+	}
+	var value strings.Builder
+	for i := 1; i < len(text)-1; i++ {
+		if text[i] == '\\' && i+1 < len(text)-1 {
+			i++
+		}
+		value.WriteByte(text[i])
+	}
+	return value.String()
+}
@@ -0,0 +1,51 @@
+package util
+
+import "testing"
+
+func TestParsePrefer(t *testing.T) {
+	headers := []string{
+		`return=representation; include="http://x/a http://x/b" ; omit="http://x/c"`,
+		`Respond-Async, wait=10, return=minimal`,
+	}
+	prefs := ParsePrefer(headers)
+
+	pref, ok := prefs["return"]
+	if !ok || pref.Value != "representation" {
+		t.Errorf("Return preference not parsed: %v", prefs)
+	}
+
+	if include, _ := pref.Param("include"); include != "http://x/a http://x/b" {
+		t.Errorf("Include parameter not parsed: %s", include)
+	}
+
+	if omit, _ := pref.Param("OMIT"); omit != "http://x/c" {
+		t.Errorf("Omit parameter not parsed: %s", omit)
+	}
+
+	if _, ok := prefs["respond-async"]; !ok {
+		t.Errorf("Preference without value not parsed: %v", prefs)
+	}
+
+	if wait := prefs["wait"]; wait.Value != "10" {
+		t.Errorf("Wait preference not parsed: %v", wait)
+	}
+}
+
+func TestParsePreferQuoted(t *testing.T) {
+	prefs := ParsePrefer([]string{`foo="a, b; c=\"d\"";bar, handling=lenient`})
+	if foo := prefs["foo"]; foo.Value != `a, b; c="d"` {
+		t.Errorf("Quoted value not parsed: %v", foo)
+	}
+
+	if _, ok := prefs["foo"].Param("bar"); !ok {
+		t.Errorf("Parameter without value not parsed: %v", prefs["foo"])
+	}
+
+	if handling := prefs["handling"]; handling.Value != "lenient" {
+		t.Errorf("Preference after quoted value not parsed: %v", prefs)
+	}
+
+	if len(ParsePrefer([]string{"", " , ;x"})) != 0 {
+		t.Errorf("Empty preferences not ignored")
+	}
+}
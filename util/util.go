@@ -34,6 +34,11 @@ func StripSlash(path string) string {
 	return path
 }
 
+// Returns true if uri is under rootUri (e.g. http://x/a/b is under http://x/a)
+func IsSubUri(rootUri, uri string) bool {
+	return strings.HasPrefix(uri, StripSlash(rootUri)+"/")
+}
+
 func PathFromUri(rootUri, uri string) string {
 	if strings.HasPrefix(uri, rootUri) {
 		return uri[len(rootUri):]
@@ -104,6 +104,17 @@ func TestPathFromUri(t *testing.T) {
 	}
 }
 
+func TestIsSubUri(t *testing.T) {
+	rootUri := "http://localhost:9001"
+	if !IsSubUri(rootUri, "http://localhost:9001/a/b") || !IsSubUri(rootUri+"/", "http://localhost:9001/a") {
+		t.Errorf("IsSubUri failed to detect sub URI")
+	}
+
+	if IsSubUri(rootUri, "http://localhost:90011/a") || IsSubUri(rootUri, rootUri) {
+		t.Errorf("IsSubUri detected an invalid sub URI")
+	}
+}
+
 func TestDirBasePath(t *testing.T) {
 	testA := []string{"a/b/c", "a/b", "c"}
 	testB := []string{"a/b/c/", "a/b", "c"}
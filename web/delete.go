@@ -1,16 +1,34 @@
 package web
 
 import (
-  "net/http"
+	"ldpserver/ldp"
+	"ldpserver/server"
+	"log"
+	"net/http"
 )
 
 func handleDelete(resp http.ResponseWriter, req *http.Request) {
-  path := safePath(req.URL.Path)
-  err := theServer.DeleteNode(path)
-  if err != nil {
-    handleCommonErrors(resp, req, err)
-    return
-  }
-
-  resp.WriteHeader(http.StatusOK)
+	path := safePath(req.URL.Path)
+	log.Printf("Deleting %s", path)
+	recursive := isPreferRecursiveDelete(req.Header)
+	err := theServer.DeleteNode(path, recursive, requestUnmodifiedSince(req.Header))
+	switch err {
+	case nil:
+		if recursive {
+			resp.Header().Set("Preference-Applied", "delete=recursive")
+		}
+		resp.WriteHeader(http.StatusOK)
+	case ldp.NodeModifiedError:
+		handleNodeModified(resp, req)
+	case ldp.ContainerNotEmptyError:
+		msg := "Container is not empty. Use the header \"Prefer: delete=recursive\" to delete it and all its children."
+		logReqError(req, msg, http.StatusConflict)
+		http.Error(resp, msg, http.StatusConflict)
+	case server.RootNodeDeleteError:
+		resp.Header().Set("Allow", "GET, HEAD, POST, PUT, PATCH")
+		logReqError(req, err.Error(), http.StatusMethodNotAllowed)
+		http.Error(resp, err.Error(), http.StatusMethodNotAllowed)
+	default:
+		handleCommonErrors(resp, req, err)
+	}
 }
@@ -3,8 +3,12 @@ package web
 import (
 	"fmt"
 	"ldpserver/ldp"
+	"ldpserver/rdf"
+	"ldpserver/util"
 	"log"
+	"mime"
 	"net/http"
+	"strings"
 )
 
 func handleGet(includeBody bool, resp http.ResponseWriter, req *http.Request) {
@@ -16,9 +20,7 @@ func handleGet(includeBody bool, resp http.ResponseWriter, req *http.Request) {
 
 	if includeBody {
 		log.Printf("GET request %s", path)
-		pref = ldp.PreferTriples{
-			Membership:       isPreferMembership(req.Header),
-			MinimalContainer: isPreferMinimalContainer(req.Header)}
+		pref = ldp.PreferTriplesFromHeader(req.Header["Prefer"])
 		node, err = theServer.GetNode(path, pref)
 	} else {
 		log.Printf("HEAD request %s", path)
@@ -30,19 +32,112 @@ func handleGet(includeBody bool, resp http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	if etag := requestIfNoneMatch(req.Header); etag != "" {
-		if etag == node.Etag() {
-			resp.WriteHeader(http.StatusNotModified)
-			return
+	if !node.IsRdf() {
+		if isNotModified(req, node, node.RepresentationEtag()) {
+			handleNotModified(resp, node.RepresentationEtag())
+		} else if isNonRdfMetadataOnlyRequest(req) {
+			setResponseHeadersMetadataOnly(resp, node)
+			fmt.Fprint(resp, node.Metadata())
+		} else {
+			handleGetBinary(resp, req, node)
 		}
+		return
+	}
+
+	contentType, params, ok := util.NegotiateMediaType(req.Header["Accept"], rdf.SerializationContentTypes)
+	if !ok {
+		msg := "None of the requested media types is supported. Supported media types: " +
+			strings.Join(rdf.SerializationContentTypes, ", ")
+		logReqError(req, msg, http.StatusNotAcceptable)
+		http.Error(resp, msg, http.StatusNotAcceptable)
+		return
+	}
+
+	etag := rdfRepresentationEtag(node, contentType, params)
+	if isNotModified(req, node, etag) {
+		handleNotModified(resp, etag)
+		return
+	}
+
+	graph := node.GraphPref(pref)
+	if contentType == rdf.TurtleContentType {
+		handleGetTurtle(resp, req, node, graph, etag)
+		return
+	}
+
+	content, err := graph.Serialize(contentType, jsonLdProfile(params))
+	if err != nil {
+		handleCommonErrors(resp, req, err)
+		return
+	}
+
+	setResponseHeaders(resp, node)
+	resp.Header().Set("Etag", etag)
+	if contentType == rdf.JsonLdContentType {
+		contentType = mime.FormatMediaType(contentType, map[string]string{"profile": jsonLdProfile(params)})
 	}
+	resp.Header().Set("Content-Type", contentType)
+	fmt.Fprint(resp, content)
+}
+
+// Streams the Turtle for the graph rather than building it in
+// memory (containers can have many thousands of triples.) Errors
+// writing the response can only be logged since the headers have
+// already been sent.
+func handleGetTurtle(resp http.ResponseWriter, req *http.Request, node ldp.Node, graph rdf.RdfGraph, etag string) {
+	setResponseHeaders(resp, node)
+	resp.Header().Set("Etag", etag)
+	resp.Header().Set("Content-Type", rdf.TurtleContentType)
+
+	writer := rdf.NewTurtleWriter(resp)
+	if relativeIris {
+		writer.SetBase(requestUri(req))
+	}
+	if err := writer.Write(graph); err != nil {
+		log.Printf("Error writing Turtle for %s. %s", req.URL.Path, err)
+	}
+}
+
+func handleNotModified(resp http.ResponseWriter, etag string) {
+	resp.Header().Set("Etag", etag)
+	resp.WriteHeader(http.StatusNotModified)
+}
 
-	if !node.IsRdf() && isNonRdfMetadataOnlyRequest(req) {
-		setResponseHeadersMetadataOnly(resp, node)
-		fmt.Fprint(resp, node.Metadata())
+// Each serialization (and JSON-LD profile) of a node gets its own
+// ETag so that validators are not shared across formats.
+func rdfRepresentationEtag(node ldp.Node, contentType string, params map[string]string) string {
+	profile := ""
+	if contentType == rdf.JsonLdContentType {
+		profile = jsonLdProfile(params)
+	}
+	return node.SerializationEtag(contentType, profile)
+}
+
+// Returns the JSON-LD profile (expanded or compacted) requested in
+// the profile parameter of the Accept header. The parameter can
+// include several URIs separated by spaces.
+func jsonLdProfile(params map[string]string) string {
+	for _, profile := range strings.Fields(params["profile"]) {
+		if profile == rdf.JsonLdExpandedProfile || profile == rdf.JsonLdCompactedProfile {
+			return profile
+		}
+	}
+	return rdf.JsonLdCompactedProfile
+}
+
+// Streams the content of a non-RDF source rather than loading it
+// into memory. http.ServeContent takes care of Range and If-Range
+// requests (including multipart/byteranges and 416 responses),
+// Content-Length, and Accept-Ranges. It also skips the body on
+// HEAD requests.
+func handleGetBinary(resp http.ResponseWriter, req *http.Request, node ldp.Node) {
+	reader, err := node.Binary()
+	if err != nil {
+		handleCommonErrors(resp, req, err)
 		return
 	}
+	defer reader.Close()
 
 	setResponseHeaders(resp, node)
-	fmt.Fprint(resp, node.ContentPref(pref))
+	http.ServeContent(resp, req, "", node.LastModified(), reader)
 }
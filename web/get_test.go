@@ -0,0 +1,131 @@
+package web
+
+import (
+	"io/ioutil"
+	"ldpserver/server"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var binaryContent = "0123456789abcdefghij"
+
+func init() {
+	theServer = server.NewMemoryServer("http://localhost:9001/")
+}
+
+func doRequest(method, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	resp := httptest.NewRecorder()
+	homePage(resp, req)
+	return resp
+}
+
+func createBinary(t *testing.T, slug string) string {
+	reader := ioutil.NopCloser(strings.NewReader(binaryContent))
+	node, err := theServer.CreateNonRdfSource(reader, "/", slug, "")
+	if err != nil {
+		t.Fatalf("Error creating binary: %s", err)
+	}
+	return node.Path()
+}
+
+func TestGetBinaryRange(t *testing.T) {
+	path := createBinary(t, "range-single")
+	resp := doRequest("GET", path, map[string]string{"Range": "bytes=2-5"})
+	if resp.Code != http.StatusPartialContent {
+		t.Fatalf("Unexpected status for a single range: %d", resp.Code)
+	}
+	if resp.Body.String() != "2345" {
+		t.Errorf("Unexpected content for a single range: %s", resp.Body.String())
+	}
+	if resp.Header().Get("Content-Range") != "bytes 2-5/20" {
+		t.Errorf("Unexpected Content-Range: %s", resp.Header().Get("Content-Range"))
+	}
+}
+
+func TestGetBinaryMultipleRanges(t *testing.T) {
+	path := createBinary(t, "range-multiple")
+	resp := doRequest("GET", path, map[string]string{"Range": "bytes=0-1,10-12"})
+	if resp.Code != http.StatusPartialContent {
+		t.Fatalf("Unexpected status for multiple ranges: %d", resp.Code)
+	}
+	contentType := resp.Header().Get("Content-Type")
+	if !strings.HasPrefix(contentType, "multipart/byteranges; boundary=") {
+		t.Errorf("Unexpected Content-Type for multiple ranges: %s", contentType)
+	}
+	body := resp.Body.String()
+	if !strings.Contains(body, "Content-Range: bytes 0-1/20") || !strings.Contains(body, "Content-Range: bytes 10-12/20") {
+		t.Errorf("Ranges not found in multipart response: %s", body)
+	}
+	if !strings.Contains(body, "\r\n01\r\n") || !strings.Contains(body, "\r\nabc\r\n") {
+		t.Errorf("Range content not found in multipart response: %s", body)
+	}
+}
+
+func TestGetBinaryUnsatisfiableRange(t *testing.T) {
+	path := createBinary(t, "range-unsatisfiable")
+	resp := doRequest("GET", path, map[string]string{"Range": "bytes=50-60"})
+	if resp.Code != http.StatusRequestedRangeNotSatisfiable {
+		t.Fatalf("Unexpected status for an unsatisfiable range: %d", resp.Code)
+	}
+	if resp.Header().Get("Content-Range") != "bytes */20" {
+		t.Errorf("Unexpected Content-Range: %s", resp.Header().Get("Content-Range"))
+	}
+}
+
+func TestGetBinaryIfRange(t *testing.T) {
+	path := createBinary(t, "range-if-range")
+	etag := doRequest("HEAD", path, nil).Header().Get("Etag")
+	if etag == "" {
+		t.Fatalf("No ETag for binary")
+	}
+
+	resp := doRequest("GET", path, map[string]string{"Range": "bytes=0-3", "If-Range": etag})
+	if resp.Code != http.StatusPartialContent || resp.Body.String() != "0123" {
+		t.Errorf("Range not honored for a matching If-Range: %d %s", resp.Code, resp.Body.String())
+	}
+
+	resp = doRequest("GET", path, map[string]string{"Range": "bytes=0-3", "If-Range": `"stale"`})
+	if resp.Code != http.StatusOK || resp.Body.String() != binaryContent {
+		t.Errorf("Full content not returned for a stale If-Range: %d %s", resp.Code, resp.Body.String())
+	}
+}
+
+func TestGetRdfEtagPerFormat(t *testing.T) {
+	node, err := theServer.CreateRdfSource(strings.NewReader("<> <http://example.org/p> \"one\" ."), "text/turtle", "/", "etag-formats")
+	if err != nil {
+		t.Fatalf("Error creating RDF source: %s", err)
+	}
+
+	accepts := []string{"text/turtle", "application/n-triples",
+		"application/ld+json", `application/ld+json; profile="http://www.w3.org/ns/json-ld#expanded"`}
+	etags := map[string]string{}
+	for _, accept := range accepts {
+		etag := doRequest("GET", node.Path(), map[string]string{"Accept": accept}).Header().Get("Etag")
+		if other, ok := etags[etag]; ok {
+			t.Errorf("Same ETag %s for %s and %s", etag, other, accept)
+		}
+		etags[etag] = accept
+	}
+
+	for etag, accept := range etags {
+		resp := doRequest("GET", node.Path(), map[string]string{"Accept": accept, "If-None-Match": etag})
+		if resp.Code != http.StatusNotModified {
+			t.Errorf("Unexpected status for %s with its own ETag: %d", accept, resp.Code)
+		}
+		for _, other := range accepts {
+			if other == accept {
+				continue
+			}
+			resp = doRequest("GET", node.Path(), map[string]string{"Accept": other, "If-None-Match": etag})
+			if resp.Code != http.StatusOK {
+				t.Errorf("Unexpected status for %s with the ETag of %s: %d", other, accept, resp.Code)
+			}
+		}
+	}
+}
@@ -1,11 +1,17 @@
 package web
 
 import (
-	"github.com/hectorcorrea/rdf"
+	"encoding/json"
+	"io"
+	"io/ioutil"
 	"ldpserver/ldp"
+	"ldpserver/rdf"
+	"ldpserver/util"
 	"log"
+	"mime"
 	"net/http"
 	"strings"
+	"time"
 )
 
 func handleCommonErrors(resp http.ResponseWriter, req *http.Request, err error) {
@@ -19,20 +25,56 @@ func handleCommonErrors(resp http.ResponseWriter, req *http.Request, err error)
 		return
 	}
 
+	if err == ldp.NodeDeletedError {
+		log.Printf("Gone %s", req.URL.Path)
+		http.Error(resp, "Resource has been deleted", http.StatusGone)
+		return
+	}
+
+	if parseErr, ok := err.(*rdf.ParseError); ok {
+		handleParseError(resp, req, parseErr)
+		return
+	}
+
 	log.Printf("Error %s", err)
 	http.Error(resp, "Error processing request", http.StatusInternalServerError)
 }
 
-func isRdfRequest(header http.Header) bool {
-	contentType := requestContentType(header)
-	if contentType == "" {
-		return true
+// Returns the media type (lowercase and without parameters) and the
+// parameters of the Content-Type of a request. Requests without a
+// Content-Type are considered Turtle.
+func requestMediaType(header http.Header) (string, map[string]string, error) {
+	value := headerValue(header, "Content-Type")
+	if value == "" {
+		return rdf.TurtleContentType, map[string]string{}, nil
+	}
+	return mime.ParseMediaType(value)
+}
+
+// Returns a reader that decodes the body of an RDF request from the
+// charset in the Content-Type as it is read. Returns
+// rdf.UnsupportedContentTypeError for RDF serializations that we
+// cannot parse.
+func requestRdfBody(req *http.Request, mediaType string, params map[string]string) (io.Reader, error) {
+	if _, ok := rdf.ParserFor(mediaType); !ok {
+		return nil, rdf.UnsupportedContentTypeError
 	}
-	return strings.HasPrefix(contentType, rdf.TurtleContentType)
+	return util.NewCharsetReader(req.Body, params["charset"])
 }
 
-func isNonRdfRequest(header http.Header) bool {
-	return !isRdfRequest(header)
+// Reads the body of a request decoding it from the charset
+// in the Content-Type.
+func requestBody(req *http.Request, params map[string]string) (string, error) {
+	data, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return "", err
+	}
+	return util.DecodeCharset(data, params["charset"])
+}
+
+// Returns the URI of the request (without the query string)
+func requestUri(req *http.Request) string {
+	return "http://" + req.Host + req.URL.Path
 }
 
 func safePath(rawPath string) string {
@@ -42,6 +84,23 @@ func safePath(rawPath string) string {
 	return rawPath + "/"
 }
 
+// Sends a 415 with a header (e.g. Accept-Post) that lists the
+// media types that we can parse.
+func handleUnsupportedMediaType(resp http.ResponseWriter, req *http.Request, header string, msg string) {
+	contentTypes := rdf.ParserContentTypes()
+	if header == "Accept-Patch" {
+		contentTypes = rdf.PatchContentTypes()
+	}
+	resp.Header().Set(header, strings.Join(contentTypes, ", "))
+	logReqError(req, msg, http.StatusUnsupportedMediaType)
+	http.Error(resp, msg, http.StatusUnsupportedMediaType)
+}
+
+func addConstrainedByLink(resp http.ResponseWriter, req *http.Request) {
+	constrainedBy := "<" + req.URL.Path + ">; rel=\"" + rdf.LdpConstrainedBy + "\""
+	resp.Header().Add("Link", constrainedBy)
+}
+
 func setResponseHeaders(resp http.ResponseWriter, node ldp.Node) {
 	for key, header := range node.Headers() {
 		for _, value := range header {
@@ -61,15 +120,6 @@ func requestSlug(header http.Header) string {
 	return headerValue(header, "Slug")
 }
 
-func requestContentType(header http.Header) string {
-	value := headerValue(header, "Content-Type")
-	// TODO: remove this horrible hack
-	if strings.HasSuffix(value, "; charset=ISO-8859-1") {
-		return strings.Replace(value, "; charset=ISO-8859-1", "", 1)
-	}
-	return value
-}
-
 func requestIfNoneMatch(header http.Header) string {
 	return headerValue(header, "If-None-Match")
 }
@@ -78,32 +128,79 @@ func requestIfMatch(header http.Header) string {
 	return headerValue(header, "If-Match")
 }
 
-func isPreferMembership(header http.Header) bool {
-	// TODO: A more strict parsing of the header to make sure is in the form
-	// return=representation; include="http://www.w3.org/ns/ldp#PreferMembership"
-	// and handle more than one include URI and the existance of omit URIs.
-	prefHeader := headerValue(header, "Prefer")
-	isInclude := strings.Contains(prefHeader, "include=")
-	if isInclude && strings.Contains(prefHeader, "http://www.w3.org/ns/ldp#PreferMembership") {
-		return true
+func requestTime(header http.Header, name string) (time.Time, bool) {
+	value := headerValue(header, name)
+	if value == "" {
+		return time.Time{}, false
 	}
-	return false
+	t, err := http.ParseTime(value)
+	if err != nil {
+		log.Printf("Ignoring invalid %s header (%s)", name, value)
+		return time.Time{}, false
+	}
+	return t, true
 }
 
-func isPreferMinimalContainer(header http.Header) bool {
-	// TODO: ditto
-	prefHeader := headerValue(header, "Prefer")
-	isInclude := strings.Contains(prefHeader, "include=")
-	if isInclude && strings.Contains(prefHeader, "http://www.w3.org/ns/ldp#PreferMinimalContainer") {
+// Uses the weak comparison (i.e. ignores the W/ prefix) as
+// required for If-None-Match. The header can be a list of
+// ETags or "*".
+func isEtagInList(etag, list string) bool {
+	if strings.TrimSpace(list) == "*" {
 		return true
 	}
-	isOmit := strings.Contains(prefHeader, "omit=")
-	if isOmit && strings.Contains(prefHeader, "http://www.w3.org/ns/ldp#PreferContainment") {
-		return true
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, value := range strings.Split(list, ",") {
+		value = strings.TrimPrefix(strings.TrimSpace(value), "W/")
+		if value == etag {
+			return true
+		}
 	}
 	return false
 }
 
+// Evaluates If-None-Match (against the ETag of the selected
+// representation) and If-Modified-Since (RFC 7232) for
+// GET and HEAD requests. If-Modified-Since is ignored when
+// If-None-Match is present.
+func isNotModified(req *http.Request, node ldp.Node, etag string) bool {
+	if etags := requestIfNoneMatch(req.Header); etags != "" {
+		return isEtagInList(etag, etags)
+	}
+
+	since, ok := requestTime(req.Header, "If-Modified-Since")
+	modified := node.LastModified()
+	if !ok || modified.IsZero() {
+		return false
+	}
+	// HTTP dates have a resolution of one second.
+	return !modified.Truncate(time.Second).After(since)
+}
+
+// Returns the If-Unmodified-Since date (RFC 7232) for requests that
+// change a node or the zero time if there is none. The header is
+// ignored if If-Match is present. The date is checked by the server
+// while it holds the lock for the write (see NodeModifiedError.)
+func requestUnmodifiedSince(header http.Header) time.Time {
+	if requestIfMatch(header) != "" {
+		return time.Time{}
+	}
+	since, _ := requestTime(header, "If-Unmodified-Since")
+	return since
+}
+
+func handleNodeModified(resp http.ResponseWriter, req *http.Request) {
+	errorMsg := "Resource has been modified since " + headerValue(req.Header, "If-Unmodified-Since")
+	logReqError(req, errorMsg, http.StatusPreconditionFailed)
+	http.Error(resp, errorMsg, http.StatusPreconditionFailed)
+}
+
+// Clients must opt-in to delete non-empty containers with
+// the header: Prefer: delete=recursive
+func isPreferRecursiveDelete(header http.Header) bool {
+	pref, ok := util.ParsePrefer(header["Prefer"])["delete"]
+	return ok && strings.ToLower(pref.Value) == "recursive"
+}
+
 func headerValue(header http.Header, name string) string {
 	for _, value := range header[name] {
 		return value
@@ -115,14 +212,12 @@ func isNonRdfMetadataOnlyRequest(req *http.Request) bool {
 	return req.URL.Query().Get("metadata") == "yes"
 }
 
-func defaultNonRdfTriples(header http.Header) string {
-	triples := ""
-	contentType := requestContentType(header)
-	if contentType != "" {
-		triples = "<> <" + rdf.ServerContentTypeUri + "> \"" + contentType + "\" ."
-	}
+// Returns the triples to save the Content-Type (including its
+// parameters) of a non-RDF source.
+func defaultNonRdfTriples(mediaType string, params map[string]string) string {
+	contentType := mime.FormatMediaType(mediaType, params)
 	// TODO: We should also try to read the file name from the header (if available)
-	return triples
+	return "<> <" + rdf.ServerContentTypeUri + "> " + rdf.Literal(contentType) + " ."
 }
 
 func logHeaders(req *http.Request) {
@@ -134,6 +229,41 @@ func logHeaders(req *http.Request) {
 	}
 }
 
+// A problem details document (https://tools.ietf.org/html/rfc7807)
+// for a request body that could not be parsed.
+type parseProblem struct {
+	Type     string   `json:"type"`
+	Title    string   `json:"title"`
+	Status   int      `json:"status"`
+	Detail   string   `json:"detail"`
+	Instance string   `json:"instance"`
+	Line     int      `json:"line,omitempty"`
+	Column   int      `json:"column,omitempty"`
+	Token    string   `json:"token,omitempty"`
+	Expected []string `json:"expected,omitempty"`
+}
+
+// Sends a 400 with the location of the error in the body of the
+// request as an application/problem+json document.
+func handleParseError(resp http.ResponseWriter, req *http.Request, err *rdf.ParseError) {
+	problem := parseProblem{
+		Type:     rdf.ServerParseErrorUri,
+		Title:    "Invalid RDF in request body",
+		Status:   http.StatusBadRequest,
+		Detail:   err.Error(),
+		Instance: req.URL.Path,
+		Line:     err.Line,
+		Column:   err.Column,
+		Token:    err.Token,
+		Expected: err.Expected,
+	}
+	logReqError(req, err.Error(), http.StatusBadRequest)
+	resp.Header().Set("Content-Type", "application/problem+json")
+	resp.Header().Set("X-Content-Type-Options", "nosniff")
+	resp.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(resp).Encode(problem)
+}
+
 func logReqError(req *http.Request, message string, code int) {
 	log.Printf("Error %d on %s %s: %s", code, req.Method, req.URL.Path, message)
 }
@@ -2,23 +2,39 @@ package web
 
 import (
 	"fmt"
-	"ldpserver/fileio"
+	"ldpserver/ldp"
+	"ldpserver/rdf"
+	"ldpserver/util"
 	"log"
 	"net/http"
 )
 
 func handlePatch(resp http.ResponseWriter, req *http.Request) {
-	if !isRdfRequest(req.Header) {
-		errorMsg := fmt.Sprintf("Invalid Content-Type (%s) received", requestContentType(req.Header))
+	mediaType, params, err := requestMediaType(req.Header)
+	if err != nil {
+		errorMsg := fmt.Sprintf("Invalid Content-Type (%s) received", headerValue(req.Header, "Content-Type"))
 		logReqError(req, errorMsg, http.StatusBadRequest)
 		http.Error(resp, errorMsg, http.StatusBadRequest)
 		return
 	}
 
+	_, isRdf := rdf.ParserFor(mediaType)
+	_, isPatch := rdf.UpdateParserFor(mediaType)
+	if !isRdf && !isPatch {
+		errorMsg := fmt.Sprintf("Unsupported Content-Type (%s) received", mediaType)
+		handleUnsupportedMediaType(resp, req, "Accept-Patch", errorMsg)
+		return
+	}
+
 	path := safePath(req.URL.Path)
 	log.Printf("Patching %s", path)
 
-	triples, err := fileio.ReaderToString(req.Body)
+	triples, err := requestBody(req, params)
+	if err == util.UnsupportedCharsetError {
+		errorMsg := fmt.Sprintf("Unsupported charset (%s) received", params["charset"])
+		handleUnsupportedMediaType(resp, req, "Accept-Patch", errorMsg)
+		return
+	}
 	if err != nil {
 		errorMsg := fmt.Sprintf("Invalid body received. Error: %s", err.Error())
 		logReqError(req, errorMsg, http.StatusBadRequest)
@@ -26,7 +42,29 @@ func handlePatch(resp http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	err = theServer.PatchNode(path, triples)
+	err = theServer.PatchNode(path, triples, mediaType, requestUnmodifiedSince(req.Header))
+	switch err {
+	case ldp.NodeModifiedError:
+		handleNodeModified(resp, req)
+		return
+	case ldp.ServerManagedPropertyError:
+		errorMsg := "Cannot overwrite server-managed property"
+		addConstrainedByLink(resp, req)
+		logReqError(req, errorMsg, http.StatusConflict)
+		http.Error(resp, errorMsg, http.StatusConflict)
+		return
+	case ldp.MembershipResourceNotFoundError, ldp.DuplicateContainerPredicateError, ldp.MembershipCycleError:
+		addConstrainedByLink(resp, req)
+		logReqError(req, err.Error(), http.StatusConflict)
+		http.Error(resp, err.Error(), http.StatusConflict)
+		return
+	case rdf.NoSolutionError, rdf.ManySolutionsError, rdf.TripleNotFoundError, rdf.TripleExistsError,
+		rdf.CutError, rdf.InvalidListError:
+		// the patch does not apply to the current state of the resource
+		logReqError(req, err.Error(), http.StatusConflict)
+		http.Error(resp, err.Error(), http.StatusConflict)
+		return
+	}
 	if err != nil {
 		handleCommonErrors(resp, req, err)
 		return
@@ -1,8 +1,8 @@
 package web
 
 import (
-	"ldpserver/fileio"
 	"ldpserver/ldp"
+	"ldpserver/rdf"
 	"log"
 	"net/http"
 )
@@ -20,16 +20,21 @@ func handlePost(resp http.ResponseWriter, req *http.Request) {
 }
 
 func doPost(resp http.ResponseWriter, req *http.Request, path string, slug string) (ldp.Node, error) {
-	if isNonRdfRequest(req.Header) {
+	mediaType, params, err := requestMediaType(req.Header)
+	if err != nil {
+		return ldp.Node{}, err
+	}
+
+	if !rdf.IsRdfContentType(mediaType) {
 		log.Printf("Creating Non-RDF Source at %s", path)
-		triples := defaultNonRdfTriples(req.Header)
+		triples := defaultNonRdfTriples(mediaType, params)
 		return theServer.CreateNonRdfSource(req.Body, path, slug, triples)
 	}
 
 	log.Printf("Creating RDF Source %s at %s", slug, path)
-	triples, err := fileio.ReaderToString(req.Body)
+	body, err := requestRdfBody(req, mediaType, params)
 	if err != nil {
 		return ldp.Node{}, err
 	}
-	return theServer.CreateRdfSource(triples, path, slug)
+	return theServer.CreateRdfSource(body, mediaType, path, slug)
 }
@@ -2,8 +2,9 @@ package web
 
 import (
 	"fmt"
-	"github.com/hectorcorrea/rdf"
 	"ldpserver/ldp"
+	"ldpserver/rdf"
+	"ldpserver/util"
 	"log"
 	"net/http"
 )
@@ -23,10 +24,23 @@ func handlePostPutError(resp http.ResponseWriter, req *http.Request, err error)
 	msg := err.Error()
 	code := http.StatusBadRequest
 
+	if parseErr, ok := err.(*rdf.ParseError); ok {
+		handleParseError(resp, req, parseErr)
+		return
+	}
+
+	if err == ldp.NodeModifiedError {
+		handleNodeModified(resp, req)
+		return
+	}
+
 	switch err {
 	case ldp.NodeNotFoundError:
 		msg = "Parent container [" + path + "] not found."
 		code = http.StatusNotFound
+	case ldp.NodeDeletedError:
+		msg = "Resource [" + path + "] has been deleted."
+		code = http.StatusGone
 	case ldp.DuplicateNodeError:
 		msg = fmt.Sprintf("Resource already exists. Path: %s Slug: %s", path, slug)
 		code = http.StatusConflict
@@ -39,8 +53,13 @@ func handlePostPutError(resp http.ResponseWriter, req *http.Request, err error)
 	case ldp.ServerManagedPropertyError:
 		msg = fmt.Sprintf("Cannot overwrite server-managed property")
 		code = http.StatusConflict
-		constrainedBy := "<" + req.URL.Path + ">; rel=\"" + rdf.LdpConstrainedBy + "\""
-		resp.Header().Add("Link", constrainedBy)
+		addConstrainedByLink(resp, req)
+	case ldp.MembershipResourceNotFoundError, ldp.DuplicateContainerPredicateError, ldp.MembershipCycleError:
+		code = http.StatusConflict
+		addConstrainedByLink(resp, req)
+	case rdf.UnsupportedContentTypeError, util.UnsupportedCharsetError:
+		handleUnsupportedMediaType(resp, req, "Accept-Post", msg)
+		return
 	}
 
 	logReqError(req, msg, code)
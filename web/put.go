@@ -2,8 +2,8 @@ package web
 
 import (
 	"errors"
-	"ldpserver/fileio"
 	"ldpserver/ldp"
+	"ldpserver/rdf"
 	"ldpserver/util"
 	"log"
 	"net/http"
@@ -25,19 +25,24 @@ func doPut(resp http.ResponseWriter, req *http.Request) (ldp.Node, error) {
 	}
 
 	etag := requestIfMatch(req.Header)
+	since := requestUnmodifiedSince(req.Header)
+	mediaType, params, err := requestMediaType(req.Header)
+	if err != nil {
+		return ldp.Node{}, err
+	}
 
-	if isNonRdfRequest(req.Header) {
+	if !rdf.IsRdfContentType(mediaType) {
 		path := req.URL.Path
 		log.Printf("Creating Non-RDF Source at %s", path)
-		triples := defaultNonRdfTriples(req.Header)
-		return theServer.ReplaceNonRdfSource(req.Body, path, etag, triples)
+		triples := defaultNonRdfTriples(mediaType, params)
+		return theServer.ReplaceNonRdfSource(req.Body, path, etag, triples, since)
 	}
 
 	path, slug := util.DirBasePath(safePath(req.URL.Path))
 	log.Printf("Creating RDF Source %s at %s", slug, path)
-	triples, err := fileio.ReaderToString(req.Body)
+	body, err := requestRdfBody(req, mediaType, params)
 	if err != nil {
-		return ldp.Node{}, errors.New("Invalid request body received")
+		return ldp.Node{}, err
 	}
-	return theServer.ReplaceRdfSource(triples, path, slug, etag)
+	return theServer.ReplaceRdfSource(body, mediaType, path, slug, etag, since)
 }
@@ -8,10 +8,22 @@ import (
 
 var theServer server.Server
 
-func Start(address, dataPath string) {
-	theServer = server.NewServer("http://"+address, dataPath)
+// Use IRIs relative to the request URI in Turtle responses
+var relativeIris bool
+
+func Start(address, dataPath string, inMemory, relative bool) {
+	relativeIris = relative
+	if inMemory {
+		theServer = server.NewMemoryServer("http://" + address)
+	} else {
+		theServer = server.NewServer("http://"+address, dataPath)
+	}
 	log.Printf("Listening for requests at %s\n", "http://"+address)
-	log.Printf("Data folder: %s\n", dataPath)
+	if inMemory {
+		log.Printf("Data is kept in memory only\n")
+	} else {
+		log.Printf("Data folder: %s\n", dataPath)
+	}
 	http.HandleFunc("/", homePage)
 	err := http.ListenAndServe(address, nil)
 	if err != nil {
@@ -33,7 +45,7 @@ func homePage(resp http.ResponseWriter, req *http.Request) {
 		handlePatch(resp, req)
 	} else if req.Method == "OPTIONS" {
 		handleOptions(resp, req)
-	} else if req.Method == "xxDELETE" {
+	} else if req.Method == "DELETE" {
 		handleDelete(resp, req)
 	} else {
 		log.Printf("Unknown request type %s", req.Method)